@@ -4,19 +4,33 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/mustafakemalcelik/sitetakip/internal/account"
+	"github.com/mustafakemalcelik/sitetakip/internal/assessment"
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
 	"github.com/mustafakemalcelik/sitetakip/internal/auth"
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
 	"github.com/mustafakemalcelik/sitetakip/internal/dues"
 	"github.com/mustafakemalcelik/sitetakip/internal/expense"
+	"github.com/mustafakemalcelik/sitetakip/internal/importer"
 	"github.com/mustafakemalcelik/sitetakip/internal/notification"
+	"github.com/mustafakemalcelik/sitetakip/internal/numbering"
 	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/internal/paymentplan"
+	"github.com/mustafakemalcelik/sitetakip/internal/portfolio"
+	"github.com/mustafakemalcelik/sitetakip/internal/recurring"
 	"github.com/mustafakemalcelik/sitetakip/internal/report"
 	"github.com/mustafakemalcelik/sitetakip/internal/resident"
+	"github.com/mustafakemalcelik/sitetakip/internal/search"
 	"github.com/mustafakemalcelik/sitetakip/internal/unit"
+	"github.com/mustafakemalcelik/sitetakip/internal/vendors"
 	"github.com/mustafakemalcelik/sitetakip/pkg/database"
 	"github.com/mustafakemalcelik/sitetakip/pkg/logger"
 	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+	"github.com/mustafakemalcelik/sitetakip/pkg/scheduler"
+	"github.com/mustafakemalcelik/sitetakip/pkg/storage"
 
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
@@ -46,6 +60,12 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// File storage for receipts and payment proofs
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
 	// Initialize router
 	r := chi.NewRouter()
 
@@ -61,6 +81,11 @@ func main() {
 	})
 
 	// Initialize modules
+	notifService := notification.NewService()
+
+	attachmentRepo := attachment.NewRepository(db)
+	attachmentService := attachment.NewService(attachmentRepo, store)
+
 	authRepo := auth.NewRepository(db)
 	authService := auth.NewService(authRepo)
 	authHandler := auth.NewHandler(authService)
@@ -69,6 +94,10 @@ func main() {
 	orgService := organization.NewService(orgRepo)
 	orgHandler := organization.NewHandler(orgService)
 
+	blockRepo := block.NewRepository(db)
+	blockService := block.NewService(blockRepo)
+	blockHandler := block.NewHandler(blockService)
+
 	unitRepo := unit.NewRepository(db)
 	unitService := unit.NewService(unitRepo)
 	unitHandler := unit.NewHandler(unitService)
@@ -77,37 +106,111 @@ func main() {
 	residentService := resident.NewService(residentRepo)
 	residentHandler := resident.NewHandler(residentService)
 
+	importService := importer.NewService(db, unitService, residentService)
+	importHandler := importer.NewHandler(importService)
+
+	accountRepo := account.NewRepository(db)
+	accountService := account.NewService(accountRepo)
+	accountHandler := account.NewHandler(accountService)
+
+	assessmentRepo := assessment.NewRepository(db)
+	assessmentService := assessment.NewService(assessmentRepo)
+	assessmentHandler := assessment.NewHandler(assessmentService)
+
 	duesRepo := dues.NewRepository(db)
-	duesService := dues.NewService(duesRepo)
+	duesService := dues.NewService(duesRepo, attachmentService, notifService)
 	duesHandler := dues.NewHandler(duesService)
 
+	vendorRepo := vendors.NewRepository(db)
+	vendorService := vendors.NewService(vendorRepo)
+	vendorHandler := vendors.NewHandler(vendorService)
+
 	expenseRepo := expense.NewRepository(db)
-	expenseService := expense.NewService(expenseRepo)
+	expenseService := expense.NewService(expenseRepo, attachmentService)
 	expenseHandler := expense.NewHandler(expenseService)
 
-	notifService := notification.NewService()
-	_ = notifService // will be used by dues service later
+	recurringRepo := recurring.NewRepository(db)
+	recurringService := recurring.NewService(recurringRepo, notifService)
+	recurringHandler := recurring.NewHandler(recurringService)
+
+	paymentPlanRepo := paymentplan.NewRepository(db)
+	paymentPlanService := paymentplan.NewService(paymentPlanRepo, notifService)
+	paymentPlanHandler := paymentplan.NewHandler(paymentPlanService)
+
+	numberingRepo := numbering.NewRepository(db)
+	numberingService := numbering.NewService(numberingRepo)
+	numberingHandler := numbering.NewHandler(numberingService)
 
 	reportService := report.NewService(db)
 	reportHandler := report.NewHandler(reportService)
 
+	portfolioService := portfolio.NewService(db, reportService, duesService)
+	portfolioHandler := portfolio.NewHandler(portfolioService)
+
+	searchService := search.NewService(db)
+	searchHandler := search.NewHandler(searchService)
+
 	// Register routes
 	r.Route("/api/v1", func(r chi.Router) {
 		auth.RegisterRoutes(r, authHandler)
 
+		// Signed download links for locally stored files
+		if local, ok := store.(*storage.Local); ok {
+			r.Get("/files/*", local.Handler())
+		}
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.Auth(authService))
 
 			organization.RegisterRoutes(r, orgHandler)
+			block.RegisterRoutes(r, blockHandler)
 			unit.RegisterRoutes(r, unitHandler)
 			resident.RegisterRoutes(r, residentHandler)
+			importer.RegisterRoutes(r, importHandler)
 			dues.RegisterRoutes(r, duesHandler)
+			vendors.RegisterRoutes(r, vendorHandler)
 			expense.RegisterRoutes(r, expenseHandler)
+			account.RegisterRoutes(r, accountHandler)
+			assessment.RegisterRoutes(r, assessmentHandler)
+			paymentplan.RegisterRoutes(r, paymentPlanHandler)
+			recurring.RegisterRoutes(r, recurringHandler)
+			numbering.RegisterRoutes(r, numberingHandler)
 			report.RegisterRoutes(r, reportHandler)
+			portfolio.RegisterRoutes(r, portfolioHandler)
+			search.RegisterRoutes(r, searchHandler)
 		})
 	})
 
+	// Background jobs
+	sched := scheduler.New()
+	sched.Every("mark_overdue_dues", time.Hour, func() error {
+		_, err := duesService.MarkOverdue()
+		return err
+	})
+	sched.Every("generate_recurring_expenses", time.Hour, func() error {
+		_, err := recurringService.GenerateDue()
+		return err
+	})
+	sched.Every("renew_contracts", 24*time.Hour, func() error {
+		_, err := recurringService.RenewContracts()
+		return err
+	})
+	sched.Every("contract_renewal_reminders", 24*time.Hour, func() error {
+		_, err := recurringService.SendRenewalReminders()
+		return err
+	})
+	sched.Every("end_occupancies", time.Hour, func() error {
+		_, err := unitService.EndOccupancies()
+		return err
+	})
+	sched.Every("check_payment_plans", 24*time.Hour, func() error {
+		_, err := paymentPlanService.CheckPlans()
+		return err
+	})
+	sched.Start()
+	defer sched.Stop()
+
 	logger.Info("server_start", map[string]string{"port": port})
 	if err := http.ListenAndServe(":"+port, r); err != nil {
 		log.Fatalf("Server failed: %v", err)
@@ -16,7 +16,7 @@ export default function DashboardPage() {
   const [orgs, setOrgs] = useState<Organization[]>([]);
   const [loading, setLoading] = useState(true);
   const [showForm, setShowForm] = useState(false);
-  const [form, setForm] = useState({ name: "", address: "", total_units: 0, monthly_due_amount: 0 });
+  const [form, setForm] = useState({ name: "", address: "", monthly_due_amount: 0 });
   const [saving, setSaving] = useState(false);
 
   const loadOrgs = async () => {
@@ -38,7 +38,7 @@ export default function DashboardPage() {
     try {
       await api.createOrganization(form);
       setShowForm(false);
-      setForm({ name: "", address: "", total_units: 0, monthly_due_amount: 0 });
+      setForm({ name: "", address: "", monthly_due_amount: 0 });
       loadOrgs();
     } catch {
       // ignore
@@ -88,16 +88,6 @@ export default function DashboardPage() {
                 placeholder="Mersin, Mezitli"
               />
             </div>
-            <div>
-              <label className="block text-sm font-medium text-slate-700 mb-1">Daire Sayisi</label>
-              <input
-                type="number"
-                value={form.total_units || ""}
-                onChange={(e) => setForm({ ...form, total_units: parseInt(e.target.value) || 0 })}
-                className="w-full px-3 py-2 border border-slate-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-blue-500"
-                placeholder="20"
-              />
-            </div>
             <div>
               <label className="block text-sm font-medium text-slate-700 mb-1">Aylik Aidat (TL)</label>
               <input
@@ -9,9 +9,14 @@ interface APIResponse<T> {
     per_page: number;
     total: number;
     total_pages: number;
+    next_cursor?: string;
+    has_more: boolean;
   };
 }
 
+// Largest page the API serves (listing.MaxLimit).
+const PAGE_SIZE = 500;
+
 class ApiClient {
   private token: string | null = null;
 
@@ -63,6 +68,24 @@ class ApiClient {
     return data;
   }
 
+  // Lists are paged on the server; follow next_cursor until the last page
+  // so callers get every row.
+  private async requestAll<T>(path: string): Promise<APIResponse<T[]>> {
+    const sep = path.includes("?") ? "&" : "?";
+    const items: T[] = [];
+    let cursor = "";
+    for (;;) {
+      const page = await this.request<T[]>(
+        `${path}${sep}limit=${PAGE_SIZE}${cursor ? `&cursor=${encodeURIComponent(cursor)}` : ""}`
+      );
+      items.push(...(page.data || []));
+      if (!page.meta?.has_more || !page.meta.next_cursor) {
+        return { ...page, data: items };
+      }
+      cursor = page.meta.next_cursor;
+    }
+  }
+
   // Auth
   async login(email: string, password: string) {
     const res = await this.request<{
@@ -104,13 +127,12 @@ class ApiClient {
 
   // Organizations
   async getOrganizations() {
-    return this.request("/organizations");
+    return this.requestAll("/organizations");
   }
 
   async createOrganization(data: {
     name: string;
     address: string;
-    total_units: number;
     monthly_due_amount: number;
   }) {
     return this.request("/organizations", {
@@ -125,7 +147,7 @@ class ApiClient {
 
   // Units
   async getUnits(orgId: string) {
-    return this.request(`/organizations/${orgId}/units`);
+    return this.requestAll(`/organizations/${orgId}/units`);
   }
 
   async createUnit(orgId: string, data: { unit_number: string; floor: number }) {
@@ -137,7 +159,7 @@ class ApiClient {
 
   // Residents
   async getResidents(orgId: string) {
-    return this.request(`/organizations/${orgId}/residents`);
+    return this.requestAll(`/organizations/${orgId}/residents`);
   }
 
   async createResident(data: {
@@ -160,7 +182,7 @@ class ApiClient {
     if (params?.month) query.set("month", String(params.month));
 
     const qs = query.toString();
-    return this.request(`/organizations/${orgId}/dues${qs ? `?${qs}` : ""}`);
+    return this.requestAll(`/organizations/${orgId}/dues${qs ? `?${qs}` : ""}`);
   }
 
   async createDue(orgId: string, data: { unit_id: string; amount: number; due_date: string }) {
@@ -185,7 +207,7 @@ class ApiClient {
   }
 
   async getOverdueDues(orgId: string) {
-    return this.request(`/organizations/${orgId}/dues/overdue`);
+    return this.requestAll(`/organizations/${orgId}/dues/overdue`);
   }
 
   // Expenses
@@ -195,7 +217,7 @@ class ApiClient {
     if (params?.month) query.set("month", String(params.month));
 
     const qs = query.toString();
-    return this.request(`/organizations/${orgId}/expenses${qs ? `?${qs}` : ""}`);
+    return this.requestAll(`/organizations/${orgId}/expenses${qs ? `?${qs}` : ""}`);
   }
 
   async createExpense(orgId: string, data: {
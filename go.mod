@@ -3,8 +3,9 @@ module github.com/mustafakemalcelik/sitetakip
 go 1.24.0
 
 require (
-	github.com/go-chi/chi/v5 v5.2.5 // indirect
-	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
-	github.com/lib/pq v1.11.2 // indirect
-	golang.org/x/crypto v0.48.0 // indirect
+	github.com/go-chi/chi/v5 v5.2.5
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/lib/pq v1.11.2
+	golang.org/x/crypto v0.48.0
 )
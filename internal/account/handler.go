@@ -0,0 +1,157 @@
+package account
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	a, err := h.service.Create(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	accounts, res, err := h.service.ListByOrganization(orgID, p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, accounts, p.Meta(res, accounts))
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	a, err := h.service.GetByID(id)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a)
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	a, err := h.service.Update(id, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a)
+}
+
+func (h *Handler) Entries(w http.ResponseWriter, r *http.Request) {
+	filter := EntryFilter{AccountID: chi.URLParam(r, "id")}
+	if v := r.URL.Query().Get("from"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid from date, use YYYY-MM-DD")
+			return
+		}
+		filter.From = &d
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid to date, use YYYY-MM-DD")
+			return
+		}
+		filter.To = &d
+	}
+
+	entries, err := h.service.ListEntries(filter)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.service.Transfer(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, result)
+}
+
+func (h *Handler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req ReconcileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	rec, err := h.service.Reconcile(orgID, id, userID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, rec)
+}
+
+func (h *Handler) Reconciliations(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	recs, err := h.service.ListReconciliations(id)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, recs)
+}
@@ -0,0 +1,102 @@
+package account
+
+import "time"
+
+const (
+	TypeCash    = "cash"
+	TypeBank    = "bank"
+	TypeReserve = "reserve"
+)
+
+const (
+	KindPayment    = "payment"
+	KindExpense    = "expense"
+	KindTransfer   = "transfer"
+	KindAdjustment = "adjustment"
+)
+
+type Account struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"` // cash, bank, reserve
+	BankName       string    `json:"bank_name,omitempty"`
+	IBAN           string    `json:"iban,omitempty"`
+	OpeningBalance float64   `json:"opening_balance"`
+	OpeningDate    time.Time `json:"opening_date"`
+	IsDefault      bool      `json:"is_default"`
+	Archived       bool      `json:"archived"`
+	Balance        float64   `json:"balance"` // opening balance plus all entries
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	BankName       string  `json:"bank_name,omitempty"`
+	IBAN           string  `json:"iban,omitempty"`
+	OpeningBalance float64 `json:"opening_balance"`
+	OpeningDate    string  `json:"opening_date,omitempty"` // YYYY-MM-DD, defaults to today
+	IsDefault      bool    `json:"is_default"`
+}
+
+type UpdateRequest struct {
+	Name      *string `json:"name,omitempty"`
+	BankName  *string `json:"bank_name,omitempty"`
+	IBAN      *string `json:"iban,omitempty"`
+	IsDefault *bool   `json:"is_default,omitempty"`
+	Archived  *bool   `json:"archived,omitempty"`
+}
+
+// Entry is one line of the account journal. Amount is positive for money
+// coming in and negative for money going out.
+type Entry struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	AccountID      string    `json:"account_id"`
+	Date           time.Time `json:"date"`
+	Amount         float64   `json:"amount"`
+	Kind           string    `json:"kind"` // payment, expense, transfer, adjustment
+	SourceType     string    `json:"source_type,omitempty"`
+	SourceID       *string   `json:"source_id,omitempty"`
+	TransferID     *string   `json:"transfer_id,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type TransferRequest struct {
+	FromAccountID string  `json:"from_account_id"`
+	ToAccountID   string  `json:"to_account_id"`
+	Amount        float64 `json:"amount"`
+	Date          string  `json:"date"` // YYYY-MM-DD
+	Description   string  `json:"description,omitempty"`
+}
+
+type Reconciliation struct {
+	ID                string    `json:"id"`
+	AccountID         string    `json:"account_id"`
+	StatementDate     time.Time `json:"statement_date"`
+	StatementBalance  float64   `json:"statement_balance"`
+	BookBalance       float64   `json:"book_balance"`
+	Difference        float64   `json:"difference"` // statement minus book
+	AdjustmentEntryID *string   `json:"adjustment_entry_id,omitempty"`
+	Note              string    `json:"note,omitempty"`
+	CreatedBy         *string   `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type ReconcileRequest struct {
+	StatementDate    string  `json:"statement_date"` // YYYY-MM-DD
+	StatementBalance float64 `json:"statement_balance"`
+	Note             string  `json:"note,omitempty"`
+	// Adjust posts the difference as an adjustment entry so the books match
+	// the statement (bank fees, interest).
+	Adjust bool `json:"adjust"`
+}
+
+type EntryFilter struct {
+	AccountID string
+	From      *time.Time
+	To        *time.Time
+}
@@ -0,0 +1,341 @@
+package account
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectAccount = `SELECT a.id, a.organization_id, a.name, a.type, a.bank_name, a.iban,
+		a.opening_balance, a.opening_date, a.is_default, a.archived,
+		a.opening_balance + COALESCE((SELECT SUM(amount) FROM account_entries WHERE account_id = a.id), 0) as balance,
+		a.created_at, a.updated_at
+		FROM accounts a`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccount(row scanner, a *Account) error {
+	return row.Scan(
+		&a.ID, &a.OrganizationID, &a.Name, &a.Type, &a.BankName, &a.IBAN,
+		&a.OpeningBalance, &a.OpeningDate, &a.IsDefault, &a.Archived, &a.Balance,
+		&a.CreatedAt, &a.UpdatedAt,
+	)
+}
+
+func (r *Repository) Create(a *Account) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if a.IsDefault {
+		if err := clearDefault(tx, a.OrganizationID, a.Type); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO accounts (organization_id, name, type, bank_name, iban, opening_balance, opening_date, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query,
+		a.OrganizationID, a.Name, a.Type, a.BankName, a.IBAN, a.OpeningBalance, a.OpeningDate, a.IsDefault,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	a.Balance = a.OpeningBalance
+	return tx.Commit()
+}
+
+func (r *Repository) GetByID(id string) (*Account, error) {
+	a := &Account{}
+	if err := scanAccount(r.db.QueryRow(selectAccount+" WHERE a.id = $1", id), a); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("account not found")
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"name":       "a.name",
+		"type":       "a.type",
+		"created_at": "a.created_at",
+	},
+	Default: "name",
+	ID:      "a.id",
+	Search:  []string{"a.name", "a.bank_name", "a.iban"},
+}
+
+func (r *Repository) ListByOrganization(orgID string, p *listing.Params) ([]Account, listing.Result, error) {
+	query, args := p.Filter(selectAccount+" WHERE a.organization_id = $1", []interface{}{orgID})
+	accounts := []Account{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var a Account
+		if err := scanAccount(rows, &a); err != nil {
+			return err
+		}
+		accounts = append(accounts, a)
+		return nil
+	})
+	return accounts, res, err
+}
+
+func (r *Repository) Update(a *Account) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if a.IsDefault {
+		if err := clearDefault(tx, a.OrganizationID, a.Type); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE accounts SET name=$1, bank_name=$2, iban=$3, is_default=$4, archived=$5, updated_at=NOW()
+		WHERE id=$6 RETURNING updated_at`
+	if err := tx.QueryRow(query, a.Name, a.BankName, a.IBAN, a.IsDefault, a.Archived, a.ID).Scan(&a.UpdatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func clearDefault(tx *sql.Tx, orgID, accountType string) error {
+	_, err := tx.Exec(`UPDATE accounts SET is_default = FALSE, updated_at = NOW()
+		WHERE organization_id = $1 AND type = $2 AND is_default`, orgID, accountType)
+	return err
+}
+
+func (r *Repository) ListEntries(filter EntryFilter) ([]Entry, error) {
+	query := `SELECT id, organization_id, account_id, date, amount, kind, source_type, source_id, transfer_id,
+		description, created_at
+		FROM account_entries WHERE account_id = $1`
+
+	args := []interface{}{filter.AccountID}
+	argIdx := 2
+
+	if filter.From != nil {
+		query += fmt.Sprintf(" AND date >= $%d", argIdx)
+		args = append(args, *filter.From)
+		argIdx++
+	}
+	if filter.To != nil {
+		query += fmt.Sprintf(" AND date <= $%d", argIdx)
+		args = append(args, *filter.To)
+		argIdx++
+	}
+
+	query += " ORDER BY date, created_at"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(
+			&e.ID, &e.OrganizationID, &e.AccountID, &e.Date, &e.Amount, &e.Kind,
+			&e.SourceType, &e.SourceID, &e.TransferID, &e.Description, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// BalanceAsOf returns the account balance at the end of the given day.
+func (r *Repository) BalanceAsOf(accountID string, day time.Time) (float64, error) {
+	var balance float64
+	err := r.db.QueryRow(`SELECT a.opening_balance + COALESCE(
+			(SELECT SUM(amount) FROM account_entries WHERE account_id = a.id AND date <= $2), 0)
+		FROM accounts a WHERE a.id = $1`, accountID, day).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("account not found")
+	}
+	return balance, err
+}
+
+// Transfer moves money between two accounts of the same organization as a
+// pair of entries sharing a transfer_id.
+func (r *Repository) Transfer(orgID string, req TransferRequest, date time.Time) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM accounts WHERE organization_id = $1 AND id IN ($2, $3) AND NOT archived`,
+		orgID, req.FromAccountID, req.ToAccountID).Scan(&count); err != nil {
+		return "", err
+	}
+	if count != 2 {
+		return "", fmt.Errorf("both accounts must exist in this organization and not be archived")
+	}
+
+	var transferID string
+	if err := tx.QueryRow("SELECT uuid_generate_v4()").Scan(&transferID); err != nil {
+		return "", err
+	}
+
+	insert := `INSERT INTO account_entries (organization_id, account_id, date, amount, kind, transfer_id, description)
+		VALUES ($1, $2, $3, $4, 'transfer', $5, $6)`
+	if _, err := tx.Exec(insert, orgID, req.FromAccountID, date, -req.Amount, transferID, req.Description); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(insert, orgID, req.ToAccountID, date, req.Amount, transferID, req.Description); err != nil {
+		return "", err
+	}
+	return transferID, tx.Commit()
+}
+
+// Reconcile records a statement balance against the book balance and, if
+// requested, posts the difference as an adjustment entry.
+func (r *Repository) Reconcile(rec *Reconciliation, orgID string, adjust bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the account so the book balance cannot change underneath us.
+	if _, err := tx.Exec("SELECT id FROM accounts WHERE id = $1 FOR UPDATE", rec.AccountID); err != nil {
+		return err
+	}
+	err = tx.QueryRow(`SELECT a.opening_balance + COALESCE(
+			(SELECT SUM(amount) FROM account_entries WHERE account_id = a.id AND date <= $2), 0)
+		FROM accounts a WHERE a.id = $1`, rec.AccountID, rec.StatementDate).Scan(&rec.BookBalance)
+	if err != nil {
+		return err
+	}
+	rec.Difference = rec.StatementBalance - rec.BookBalance
+
+	if adjust && rec.Difference != 0 {
+		var entryID string
+		err := tx.QueryRow(`INSERT INTO account_entries (organization_id, account_id, date, amount, kind, description)
+			VALUES ($1, $2, $3, $4, 'adjustment', $5) RETURNING id`,
+			orgID, rec.AccountID, rec.StatementDate, rec.Difference, "Mutabakat düzeltmesi: "+rec.Note,
+		).Scan(&entryID)
+		if err != nil {
+			return err
+		}
+		rec.AdjustmentEntryID = &entryID
+	}
+
+	err = tx.QueryRow(`INSERT INTO account_reconciliations (account_id, statement_date, statement_balance,
+			book_balance, difference, adjustment_entry_id, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		rec.AccountID, rec.StatementDate, rec.StatementBalance, rec.BookBalance, rec.Difference,
+		rec.AdjustmentEntryID, rec.Note, rec.CreatedBy,
+	).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) ListReconciliations(accountID string) ([]Reconciliation, error) {
+	rows, err := r.db.Query(`SELECT id, account_id, statement_date, statement_balance, book_balance, difference,
+			adjustment_entry_id, note, created_by, created_at
+		FROM account_reconciliations WHERE account_id = $1 ORDER BY statement_date DESC, created_at DESC`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recs []Reconciliation
+	for rows.Next() {
+		var rec Reconciliation
+		if err := rows.Scan(
+			&rec.ID, &rec.AccountID, &rec.StatementDate, &rec.StatementBalance, &rec.BookBalance, &rec.Difference,
+			&rec.AdjustmentEntryID, &rec.Note, &rec.CreatedBy, &rec.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// Post records a journal entry inside the caller's transaction, so the
+// payment or expense and its posting are committed together. When
+// e.AccountID is empty the organization's default account is used: the
+// cash box for cash, the bank account for everything else. A default
+// account is created if the organization has none of that type yet.
+func Post(tx *sql.Tx, e *Entry, method string) error {
+	if e.AccountID == "" {
+		accountType := TypeBank
+		if method == "cash" {
+			accountType = TypeCash
+		}
+		id, err := defaultAccount(tx, e.OrganizationID, accountType)
+		if err != nil {
+			return err
+		}
+		e.AccountID = id
+	} else {
+		var ok bool
+		if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1 AND organization_id = $2 AND NOT archived)`,
+			e.AccountID, e.OrganizationID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("account not found")
+		}
+	}
+
+	return tx.QueryRow(`INSERT INTO account_entries (organization_id, account_id, date, amount, kind, source_type, source_id, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`,
+		e.OrganizationID, e.AccountID, e.Date, e.Amount, e.Kind, e.SourceType, e.SourceID, e.Description,
+	).Scan(&e.ID, &e.CreatedAt)
+}
+
+// Unpost removes the journal entries of a source document, e.g. when a
+// paid expense is deleted.
+func Unpost(tx *sql.Tx, sourceType, sourceID string) error {
+	_, err := tx.Exec("DELETE FROM account_entries WHERE source_type = $1 AND source_id = $2", sourceType, sourceID)
+	return err
+}
+
+func defaultAccount(tx *sql.Tx, orgID, accountType string) (string, error) {
+	var id string
+	err := tx.QueryRow(`SELECT id FROM accounts WHERE organization_id = $1 AND type = $2 AND NOT archived
+		ORDER BY is_default DESC, created_at LIMIT 1`, orgID, accountType).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	name := "Banka"
+	if accountType == TypeCash {
+		name = "Kasa"
+	}
+	err = tx.QueryRow(`INSERT INTO accounts (organization_id, name, type, is_default) VALUES ($1, $2, $3, TRUE)
+		RETURNING id`, orgID, name, accountType).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,16 @@
+package account
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/accounts", func(r chi.Router) {
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Post("/transfers", h.Transfer)
+		r.Get("/{id}", h.Get)
+		r.Put("/{id}", h.Update)
+		r.Get("/{id}/entries", h.Entries)
+		r.Post("/{id}/reconciliations", h.Reconcile)
+		r.Get("/{id}/reconciliations", h.Reconciliations)
+	})
+}
@@ -0,0 +1,152 @@
+package account
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(orgID string, req CreateRequest) (*Account, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.Type != TypeCash && req.Type != TypeBank && req.Type != TypeReserve {
+		return nil, fmt.Errorf("type must be cash, bank or reserve")
+	}
+
+	openingDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.OpeningDate != "" {
+		d, err := time.Parse("2006-01-02", req.OpeningDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opening_date format, use YYYY-MM-DD")
+		}
+		openingDate = d
+	}
+
+	a := &Account{
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Type:           req.Type,
+		BankName:       req.BankName,
+		IBAN:           req.IBAN,
+		OpeningBalance: req.OpeningBalance,
+		OpeningDate:    openingDate,
+		IsDefault:      req.IsDefault,
+	}
+
+	if err := s.repo.Create(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) GetByID(id string) (*Account, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *Service) ListByOrganization(orgID string, p *listing.Params) ([]Account, listing.Result, error) {
+	return s.repo.ListByOrganization(orgID, p)
+}
+
+func (s *Service) Update(id string, req UpdateRequest) (*Account, error) {
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		a.Name = *req.Name
+	}
+	if req.BankName != nil {
+		a.BankName = *req.BankName
+	}
+	if req.IBAN != nil {
+		a.IBAN = *req.IBAN
+	}
+	if req.IsDefault != nil {
+		a.IsDefault = *req.IsDefault
+	}
+	if req.Archived != nil {
+		a.Archived = *req.Archived
+	}
+	if a.Archived && a.IsDefault {
+		return nil, fmt.Errorf("the default account cannot be archived, choose another default first")
+	}
+
+	if err := s.repo.Update(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) ListEntries(filter EntryFilter) ([]Entry, error) {
+	return s.repo.ListEntries(filter)
+}
+
+func (s *Service) Transfer(orgID string, req TransferRequest) (map[string]string, error) {
+	if req.FromAccountID == "" || req.ToAccountID == "" || req.Amount <= 0 || req.Date == "" {
+		return nil, fmt.Errorf("from_account_id, to_account_id, amount, and date are required")
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return nil, fmt.Errorf("cannot transfer to the same account")
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format, use YYYY-MM-DD")
+	}
+
+	transferID, err := s.repo.Transfer(orgID, req, date)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"transfer_id": transferID}, nil
+}
+
+// Reconcile compares an entered statement balance with the books for the
+// period ending on the statement date.
+func (s *Service) Reconcile(orgID, accountID, userID string, req ReconcileRequest) (*Reconciliation, error) {
+	if req.StatementDate == "" {
+		return nil, fmt.Errorf("statement_date is required")
+	}
+	date, err := time.Parse("2006-01-02", req.StatementDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid statement_date format, use YYYY-MM-DD")
+	}
+
+	a, err := s.repo.GetByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if a.OrganizationID != orgID {
+		return nil, fmt.Errorf("account not found")
+	}
+
+	rec := &Reconciliation{
+		AccountID:        accountID,
+		StatementDate:    date,
+		StatementBalance: req.StatementBalance,
+		Note:             req.Note,
+	}
+	if userID != "" {
+		rec.CreatedBy = &userID
+	}
+
+	if err := s.repo.Reconcile(rec, orgID, req.Adjust); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *Service) ListReconciliations(accountID string) ([]Reconciliation, error) {
+	return s.repo.ListReconciliations(accountID)
+}
@@ -0,0 +1,124 @@
+package assessment
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	a, err := h.service.Create(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	list, res, err := h.service.ListByOrganization(orgID, p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, list, p.Meta(res, list))
+}
+
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	a, err := h.service.GetByID(id)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, a)
+}
+
+func (h *Handler) ListInstallments(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	list, err := h.service.ListInstallments(id, r.URL.Query().Get("unit_id"))
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) Progress(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p, err := h.service.GetProgress(id)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) Payoff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	unitID := chi.URLParam(r, "unitId")
+	var req PayoffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	paid, err := h.service.Payoff(id, unitID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Installments paid off",
+		"amount":  paid,
+	})
+}
+
+func (h *Handler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	unitID := chi.URLParam(r, "unitId")
+	var req RescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, err := h.service.Reschedule(id, unitID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, list)
+}
@@ -0,0 +1,82 @@
+package assessment
+
+import "time"
+
+// Allocation keys decide how an assessment's total is split across units.
+const (
+	KeyEqual     = "equal"
+	KeyArea      = "area"       // by net m²
+	KeyLandShare = "land_share" // by arsa payı
+)
+
+// MaxInstallments bounds how many monthly installments a plan can have.
+const MaxInstallments = 120
+
+// Assessment is a one-off charge (ek bütçe) such as a roof renovation,
+// split across units and collected in monthly installments.
+type Assessment struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	BlockID        *string   `json:"block_id,omitempty"` // split across this block's units only
+	Title          string    `json:"title"`
+	Description    string    `json:"description,omitempty"`
+	TotalAmount    float64   `json:"total_amount"`
+	AllocationKey  string    `json:"allocation_key"` // equal, area, land_share
+	Installments   int       `json:"installments"`
+	FirstDueDate   time.Time `json:"first_due_date"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	BlockID       string  `json:"block_id,omitempty"` // e.g. an elevator renewal charged to one block
+	Title         string  `json:"title"`
+	Description   string  `json:"description,omitempty"`
+	TotalAmount   float64 `json:"total_amount"`
+	AllocationKey string  `json:"allocation_key,omitempty"` // defaults to equal
+	Installments  int     `json:"installments"`
+	FirstDueDate  string  `json:"first_due_date"` // YYYY-MM-DD
+}
+
+// Installment is one generated due of an assessment.
+type Installment struct {
+	DueID         string     `json:"due_id"`
+	UnitID        string     `json:"unit_id"`
+	UnitNumber    string     `json:"unit_number"`
+	InstallmentNo int        `json:"installment_no"`
+	Amount        float64    `json:"amount"`
+	DueDate       time.Time  `json:"due_date"`
+	Status        string     `json:"status"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+}
+
+type PayoffRequest struct {
+	PaymentMethod string `json:"payment_method"`
+	AccountID     string `json:"account_id,omitempty"`
+}
+
+// RescheduleRequest spreads a unit's unpaid balance over a new plan.
+type RescheduleRequest struct {
+	Installments int    `json:"installments"`
+	FirstDueDate string `json:"first_due_date"` // YYYY-MM-DD
+}
+
+type UnitProgress struct {
+	UnitID      string  `json:"unit_id"`
+	UnitNumber  string  `json:"unit_number"`
+	Share       float64 `json:"share"`
+	Collected   float64 `json:"collected"`
+	Outstanding float64 `json:"outstanding"`
+	Overdue     float64 `json:"overdue"`
+	PaidCount   int     `json:"paid_count"`
+	TotalCount  int     `json:"total_count"`
+}
+
+type Progress struct {
+	Assessment  *Assessment    `json:"assessment"`
+	Collected   float64        `json:"collected"`
+	Outstanding float64        `json:"outstanding"`
+	Overdue     float64        `json:"overdue"`
+	Percent     float64        `json:"percent"`
+	Units       []UnitProgress `json:"units"`
+}
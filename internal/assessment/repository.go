@@ -0,0 +1,308 @@
+package assessment
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectAssessment = `SELECT id, organization_id, block_id, title, description, total_amount, allocation_key,
+		installments, first_due_date, created_at, updated_at
+		FROM assessments`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAssessment(row scanner, a *Assessment) error {
+	return row.Scan(
+		&a.ID, &a.OrganizationID, &a.BlockID, &a.Title, &a.Description, &a.TotalAmount, &a.AllocationKey,
+		&a.Installments, &a.FirstDueDate, &a.CreatedAt, &a.UpdatedAt,
+	)
+}
+
+// unitWeight is a unit together with its allocation key values.
+type unitWeight struct {
+	ID        string
+	Area      float64
+	LandShare float64
+}
+
+// listUnitWeights returns the units an assessment is split across: the
+// whole site, or one block of the organization when blockID is set.
+func (r *Repository) listUnitWeights(orgID, blockID string) ([]unitWeight, error) {
+	if blockID != "" {
+		if err := block.Belongs(r.db, blockID, orgID); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := r.db.Query(`SELECT id, area, land_share FROM units
+		WHERE organization_id = $1 AND ($2 = '' OR block_id::text = $2)
+		ORDER BY block_id, unit_number`, orgID, blockID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var units []unitWeight
+	for rows.Next() {
+		var u unitWeight
+		if err := rows.Scan(&u.ID, &u.Area, &u.LandShare); err != nil {
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	return units, rows.Err()
+}
+
+// Create stores the assessment and its installment dues in one transaction.
+// shares maps unit IDs to the unit's portion of the total in kuruş.
+func (r *Repository) Create(a *Assessment, units []string, shares []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO assessments (organization_id, block_id, title, description, total_amount, allocation_key,
+			installments, first_due_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(query,
+		a.OrganizationID, a.BlockID, a.Title, a.Description, a.TotalAmount, a.AllocationKey,
+		a.Installments, a.FirstDueDate,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i, unitID := range units {
+		if err := insertInstallments(tx, a, unitID, shares[i], 1, a.Installments, a.FirstDueDate); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertInstallments splits amount (kuruş) into n monthly dues numbered from
+// startNo onwards, billed to the right payer with open credits applied.
+func insertInstallments(tx *sql.Tx, a *Assessment, unitID string, amount int64, startNo, n int, first time.Time) error {
+	query := `
+		INSERT INTO dues (organization_id, unit_id, type, amount, due_date, status, description,
+			assessment_id, installment_no)
+		VALUES ($1, $2, $3, $4, $5, 'pending', $6, $7, $8)
+		RETURNING id`
+
+	last := startNo + n - 1
+	ids := make([]string, 0, n)
+	for i, part := range dues.SplitKurus(amount, n) {
+		no := startNo + i
+		desc := fmt.Sprintf("%s (%d/%d)", a.Title, no, last)
+		var id string
+		if err := tx.QueryRow(query,
+			a.OrganizationID, unitID, dues.TypeSpecialAssessment, float64(part)/100,
+			dues.AddMonths(first, i), desc, a.ID, no,
+		).Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	return dues.OnIssued(tx, ids)
+}
+
+func (r *Repository) GetByID(id string) (*Assessment, error) {
+	a := &Assessment{}
+	if err := scanAssessment(r.db.QueryRow(selectAssessment+" WHERE id = $1", id), a); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("assessment not found")
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"first_due_date": "first_due_date",
+		"title":          "title",
+		"total_amount":   "total_amount",
+		"created_at":     "created_at",
+	},
+	Default: "-first_due_date",
+	ID:      "id",
+	Search:  []string{"title", "description"},
+	Date:    "first_due_date",
+}
+
+func (r *Repository) ListByOrganization(orgID string, p *listing.Params) ([]Assessment, listing.Result, error) {
+	query, args := p.Filter(selectAssessment+" WHERE organization_id = $1", []interface{}{orgID})
+	list := []Assessment{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var a Assessment
+		if err := scanAssessment(rows, &a); err != nil {
+			return err
+		}
+		list = append(list, a)
+		return nil
+	})
+	return list, res, err
+}
+
+// ListInstallments returns the dues of an assessment, optionally limited to
+// one unit.
+func (r *Repository) ListInstallments(assessmentID, unitID string) ([]Installment, error) {
+	query := `SELECT d.id, d.unit_id, COALESCE(u.unit_number, ''), d.installment_no, d.amount,
+		d.due_date, d.status, d.paid_at
+		FROM dues d
+		LEFT JOIN units u ON d.unit_id = u.id
+		WHERE d.assessment_id = $1 AND ($2 = '' OR d.unit_id::text = $2) AND d.status <> 'restructured'
+		ORDER BY u.unit_number, d.installment_no`
+
+	rows, err := r.db.Query(query, assessmentID, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Installment
+	for rows.Next() {
+		var i Installment
+		if err := rows.Scan(&i.DueID, &i.UnitID, &i.UnitNumber, &i.InstallmentNo, &i.Amount,
+			&i.DueDate, &i.Status, &i.PaidAt); err != nil {
+			return nil, err
+		}
+		list = append(list, i)
+	}
+	return list, nil
+}
+
+// lockUnpaid locks a unit's unpaid installments and returns their IDs, total
+// in kuruş and the highest paid installment number.
+func lockUnpaid(tx *sql.Tx, assessmentID, unitID string) ([]string, int64, int, error) {
+	rows, err := tx.Query(`SELECT id, amount - adjustment_total FROM dues
+		WHERE assessment_id = $1 AND unit_id = $2 AND status IN ('pending', 'overdue')
+		ORDER BY installment_no FOR UPDATE`, assessmentID, unitID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	var total int64
+	for rows.Next() {
+		var id string
+		var amount float64
+		if err := rows.Scan(&id, &amount); err != nil {
+			return nil, 0, 0, err
+		}
+		ids = append(ids, id)
+		total += dues.ToKurus(amount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var lastPaid int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(installment_no), 0) FROM dues
+		WHERE assessment_id = $1 AND unit_id = $2 AND status = 'paid'`, assessmentID, unitID).Scan(&lastPaid)
+	return ids, total, lastPaid, err
+}
+
+// Payoff settles all remaining installments of a unit at once and returns
+// the amount paid.
+func (r *Repository) Payoff(assessmentID, unitID, method, accountID string) (float64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids, total, _, err := lockUnpaid(tx, assessmentID, unitID)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("no unpaid installments for this unit")
+	}
+	for _, id := range ids {
+		if err := dues.MarkPaidTx(tx, id, method, accountID); err != nil {
+			return 0, err
+		}
+	}
+	return float64(total) / 100, tx.Commit()
+}
+
+// Reschedule replaces a unit's unpaid installments with a new plan covering
+// the same remaining balance.
+func (r *Repository) Reschedule(a *Assessment, unitID string, n int, first time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids, total, lastPaid, err := lockUnpaid(tx, a.ID, unitID)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no unpaid installments for this unit")
+	}
+	// The old installments stay on record, marked restructured like dues
+	// moved into a payment plan, and drop out of balances and reports.
+	if _, err := tx.Exec(`UPDATE dues SET status = 'restructured', updated_at = NOW()
+		WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return err
+	}
+	if err := insertInstallments(tx, a, unitID, total, lastPaid+1, n, first); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetProgress aggregates collected, outstanding and overdue amounts per unit.
+func (r *Repository) GetProgress(assessmentID string) ([]UnitProgress, error) {
+	query := `SELECT d.unit_id, COALESCE(u.unit_number, ''),
+		SUM(d.amount - d.adjustment_total),
+		COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.status = 'paid'), 0),
+		COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.status IN ('pending', 'overdue')), 0),
+		COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.status IN ('pending', 'overdue') AND d.due_date < CURRENT_DATE), 0),
+		COUNT(*) FILTER (WHERE d.status = 'paid'),
+		COUNT(*)
+		FROM dues d
+		LEFT JOIN units u ON d.unit_id = u.id
+		WHERE d.assessment_id = $1 AND d.status <> 'restructured'
+		GROUP BY d.unit_id, u.unit_number
+		ORDER BY u.unit_number`
+
+	rows, err := r.db.Query(query, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []UnitProgress
+	for rows.Next() {
+		var p UnitProgress
+		if err := rows.Scan(&p.UnitID, &p.UnitNumber, &p.Share, &p.Collected, &p.Outstanding,
+			&p.Overdue, &p.PaidCount, &p.TotalCount); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
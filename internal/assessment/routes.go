@@ -0,0 +1,15 @@
+package assessment
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/assessments", func(r chi.Router) {
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Get("/{id}", h.GetByID)
+		r.Get("/{id}/installments", h.ListInstallments)
+		r.Get("/{id}/progress", h.Progress)
+		r.Post("/{id}/units/{unitId}/payoff", h.Payoff)
+		r.Put("/{id}/units/{unitId}/schedule", h.Reschedule)
+	})
+}
@@ -0,0 +1,193 @@
+package assessment
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(orgID string, req CreateRequest) (*Assessment, error) {
+	if req.Title == "" || req.TotalAmount <= 0 || req.Installments <= 0 || req.FirstDueDate == "" {
+		return nil, fmt.Errorf("title, total_amount, installments, and first_due_date are required")
+	}
+	if req.Installments > MaxInstallments {
+		return nil, fmt.Errorf("installments can be at most %d", MaxInstallments)
+	}
+	if req.AllocationKey == "" {
+		req.AllocationKey = KeyEqual
+	}
+	if req.AllocationKey != KeyEqual && req.AllocationKey != KeyArea && req.AllocationKey != KeyLandShare {
+		return nil, fmt.Errorf("allocation_key must be equal, area or land_share")
+	}
+
+	first, err := time.Parse("2006-01-02", req.FirstDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid first_due_date format, use YYYY-MM-DD")
+	}
+
+	units, err := s.repo.listUnitWeights(orgID, req.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		if req.BlockID != "" {
+			return nil, fmt.Errorf("block has no units")
+		}
+		return nil, fmt.Errorf("organization has no units")
+	}
+
+	ids := make([]string, len(units))
+	weights := make([]float64, len(units))
+	for i, u := range units {
+		ids[i] = u.ID
+		switch req.AllocationKey {
+		case KeyArea:
+			weights[i] = u.Area
+		case KeyLandShare:
+			weights[i] = u.LandShare
+		default:
+			weights[i] = 1
+		}
+	}
+	shares, err := allocate(dues.ToKurus(req.TotalAmount), weights)
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate by %s: %w", req.AllocationKey, err)
+	}
+
+	a := &Assessment{
+		OrganizationID: orgID,
+		BlockID:        dues.Optional(req.BlockID),
+		Title:          req.Title,
+		Description:    req.Description,
+		TotalAmount:    req.TotalAmount,
+		AllocationKey:  req.AllocationKey,
+		Installments:   req.Installments,
+		FirstDueDate:   first,
+	}
+	if err := s.repo.Create(a, ids, shares); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) GetByID(id string) (*Assessment, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *Service) ListByOrganization(orgID string, p *listing.Params) ([]Assessment, listing.Result, error) {
+	return s.repo.ListByOrganization(orgID, p)
+}
+
+func (s *Service) ListInstallments(id, unitID string) ([]Installment, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInstallments(id, unitID)
+}
+
+// Payoff pays all remaining installments of a unit early.
+func (s *Service) Payoff(id, unitID string, req PayoffRequest) (float64, error) {
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = "cash"
+	}
+	if _, err := s.repo.GetByID(id); err != nil {
+		return 0, err
+	}
+	return s.repo.Payoff(id, unitID, req.PaymentMethod, req.AccountID)
+}
+
+// Reschedule spreads a unit's unpaid balance over a new installment plan.
+func (s *Service) Reschedule(id, unitID string, req RescheduleRequest) ([]Installment, error) {
+	if req.Installments <= 0 || req.FirstDueDate == "" {
+		return nil, fmt.Errorf("installments and first_due_date are required")
+	}
+	if req.Installments > MaxInstallments {
+		return nil, fmt.Errorf("installments can be at most %d", MaxInstallments)
+	}
+	first, err := time.Parse("2006-01-02", req.FirstDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid first_due_date format, use YYYY-MM-DD")
+	}
+
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Reschedule(a, unitID, req.Installments, first); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInstallments(id, unitID)
+}
+
+func (s *Service) GetProgress(id string) (*Progress, error) {
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	units, err := s.repo.GetProgress(id)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Progress{Assessment: a, Units: units}
+	for _, u := range units {
+		p.Collected += u.Collected
+		p.Outstanding += u.Outstanding
+		p.Overdue += u.Overdue
+	}
+	if a.TotalAmount > 0 {
+		p.Percent = math.Round(p.Collected/a.TotalAmount*10000) / 100
+	}
+	return p, nil
+}
+
+// allocate splits total (kuruş) proportionally to weights. Each share is
+// rounded down and the kuruş left over go one at a time to the units with
+// the largest rounded-off fractions, so the shares add up to the total and
+// no unit carries more than one extra kuruş.
+func allocate(total int64, weights []float64) ([]int64, error) {
+	var sum float64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("negative weight")
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("units have no values for this key")
+	}
+
+	shares := make([]int64, len(weights))
+	fractions := make([]float64, len(weights))
+	order := make([]int, len(weights))
+	var given int64
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		shares[i] = int64(math.Floor(exact))
+		fractions[i] = exact - float64(shares[i])
+		order[i] = i
+		given += shares[i]
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fractions[order[a]] > fractions[order[b]]
+	})
+	for i := 0; given < total; i = (i + 1) % len(order) {
+		if weights[order[i]] > 0 {
+			shares[order[i]]++
+			given++
+		}
+	}
+	return shares, nil
+}
@@ -0,0 +1,47 @@
+package assessment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []float64
+		want    []int64
+	}{
+		{"even", 30000, []float64{1, 1, 1}, []int64{10000, 10000, 10000}},
+		{"leftover spread one kuruş each", 100, []float64{1, 1, 1, 1, 1, 1, 1}, []int64{15, 15, 14, 14, 14, 14, 14}},
+		{"largest fraction first", 1000, []float64{85.5, 120, 64.5}, []int64{317, 444, 239}},
+		{"zero weight gets nothing", 101, []float64{1, 0, 1}, []int64{51, 0, 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := allocate(tt.total, tt.weights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("allocate(%d, %v) = %v, want %v", tt.total, tt.weights, got, tt.want)
+			}
+			var sum int64
+			for _, s := range got {
+				sum += s
+			}
+			if sum != tt.total {
+				t.Fatalf("shares add up to %d, want %d", sum, tt.total)
+			}
+		})
+	}
+}
+
+func TestAllocateErrors(t *testing.T) {
+	if _, err := allocate(100, []float64{1, -1}); err == nil {
+		t.Error("expected an error for a negative weight")
+	}
+	if _, err := allocate(100, []float64{0, 0}); err == nil {
+		t.Error("expected an error when all weights are zero")
+	}
+}
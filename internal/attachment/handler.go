@@ -0,0 +1,22 @@
+package attachment
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+)
+
+// FormFile reads the "file" field of a multipart upload, rejecting bodies
+// larger than MaxUploadSize before they are buffered.
+func FormFile(w http.ResponseWriter, r *http.Request) (multipart.File, *multipart.FileHeader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize+1<<20)
+	if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
+		return nil, nil, fmt.Errorf("invalid upload, send a multipart form with a \"file\" field of at most %d MB", MaxUploadSize>>20)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, nil, fmt.Errorf("file is required")
+	}
+	return file, header, nil
+}
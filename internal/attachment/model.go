@@ -0,0 +1,21 @@
+package attachment
+
+import "time"
+
+const (
+	OwnerExpense = "expense"
+	OwnerDue     = "due"
+)
+
+type Attachment struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	OwnerType      string    `json:"owner_type"` // expense, due
+	OwnerID        string    `json:"owner_id"`
+	FileName       string    `json:"file_name"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	StorageKey     string    `json:"-"`
+	URL            string    `json:"url,omitempty"` // signed, short-lived download URL
+	CreatedAt      time.Time `json:"created_at"`
+}
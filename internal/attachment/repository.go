@@ -0,0 +1,73 @@
+package attachment
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(a *Attachment) error {
+	query := `
+		INSERT INTO attachments (organization_id, owner_type, owner_id, file_name, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(query,
+		a.OrganizationID, a.OwnerType, a.OwnerID, a.FileName, a.ContentType, a.Size, a.StorageKey,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *Repository) GetByID(id string) (*Attachment, error) {
+	a := &Attachment{}
+	query := `SELECT id, organization_id, owner_type, owner_id, file_name, content_type, size, storage_key, created_at
+		FROM attachments WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&a.ID, &a.OrganizationID, &a.OwnerType, &a.OwnerID,
+		&a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("attachment not found")
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *Repository) ListByOwner(ownerType, ownerID string) ([]Attachment, error) {
+	query := `SELECT id, organization_id, owner_type, owner_id, file_name, content_type, size, storage_key, created_at
+		FROM attachments WHERE owner_type = $1 AND owner_id = $2
+		ORDER BY created_at`
+
+	rows, err := r.db.Query(query, ownerType, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(
+			&a.ID, &a.OrganizationID, &a.OwnerType, &a.OwnerID,
+			&a.FileName, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+func (r *Repository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM attachments WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,146 @@
+package attachment
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/storage"
+)
+
+// MaxUploadSize is the largest file accepted for a single upload.
+const MaxUploadSize = 10 << 20 // 10 MB
+
+// URLTTL is how long a signed download URL stays valid.
+const URLTTL = 15 * time.Minute
+
+// allowedTypes maps accepted content types (detected from the file
+// contents, not trusted from the client) to the extension we store.
+var allowedTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+type Service struct {
+	repo  *Repository
+	store storage.Storage
+}
+
+func NewService(repo *Repository, store storage.Storage) *Service {
+	return &Service{repo: repo, store: store}
+}
+
+func (s *Service) Upload(orgID, ownerType, ownerID string, file multipart.File, header *multipart.FileHeader) (*Attachment, error) {
+	if header.Size > MaxUploadSize {
+		return nil, fmt.Errorf("file too large, maximum is %d MB", MaxUploadSize>>20)
+	}
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type %s, allowed: PDF, JPEG, PNG, WEBP", contentType)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attachment{
+		OrganizationID: orgID,
+		OwnerType:      ownerType,
+		OwnerID:        ownerID,
+		FileName:       filepath.Base(header.Filename),
+		ContentType:    contentType,
+		Size:           header.Size,
+		StorageKey:     strings.Join([]string{orgID, ownerType, ownerID, name + ext}, "/"),
+	}
+
+	if err := s.store.Put(a.StorageKey, file, a.Size, a.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	if err := s.repo.Create(a); err != nil {
+		s.store.Delete(a.StorageKey)
+		return nil, err
+	}
+
+	a.URL, _ = s.store.SignedURL(a.StorageKey, URLTTL)
+	return a, nil
+}
+
+func (s *Service) GetByID(id string) (*Attachment, error) {
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if a.URL, err = s.store.SignedURL(a.StorageKey, URLTTL); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) ListByOwner(ownerType, ownerID string) ([]Attachment, error) {
+	attachments, err := s.repo.ListByOwner(ownerType, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if attachments[i].URL, err = s.store.SignedURL(attachments[i].StorageKey, URLTTL); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+func (s *Service) Delete(id string) error {
+	a, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(a.StorageKey); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return s.repo.Delete(id)
+}
+
+// DeleteByOwner removes every file attached to an owner, e.g. when an
+// expense is deleted.
+func (s *Service) DeleteByOwner(ownerType, ownerID string) error {
+	attachments, err := s.repo.ListByOwner(ownerType, ownerID)
+	if err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := s.store.Delete(a.StorageKey); err != nil {
+			return fmt.Errorf("failed to delete file %s: %w", a.FileName, err)
+		}
+		if err := s.repo.Delete(a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
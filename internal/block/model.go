@@ -0,0 +1,29 @@
+package block
+
+import "time"
+
+// Block is one building (A Blok, B Blok) of a multi-building site. Units,
+// expenses and assessments can belong to a block; those without one are
+// shared by the whole site.
+type Block struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	Floors         int       `json:"floors"`
+	HasElevator    bool      `json:"has_elevator"`
+	UnitCount      int       `json:"unit_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name        string `json:"name"`
+	Floors      int    `json:"floors"`
+	HasElevator bool   `json:"has_elevator"`
+}
+
+type UpdateRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Floors      *int    `json:"floors,omitempty"`
+	HasElevator *bool   `json:"has_elevator,omitempty"`
+}
@@ -0,0 +1,127 @@
+package block
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectBlock = `SELECT b.id, b.organization_id, b.name, b.floors, b.has_elevator,
+		(SELECT COUNT(*) FROM units u WHERE u.block_id = b.id) as unit_count,
+		b.created_at, b.updated_at
+		FROM blocks b`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBlock(row scanner, b *Block) error {
+	return row.Scan(
+		&b.ID, &b.OrganizationID, &b.Name, &b.Floors, &b.HasElevator,
+		&b.UnitCount, &b.CreatedAt, &b.UpdatedAt,
+	)
+}
+
+// Belongs checks that a block exists in the organization. Packages that
+// scope records to a block call it before writing block_id.
+func Belongs(db database.Querier, blockID, orgID string) error {
+	var ok bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM blocks WHERE id = $1 AND organization_id = $2)",
+		blockID, orgID).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("block not found")
+	}
+	return nil
+}
+
+// EnsureTx returns the ID of the organization's block with the given name,
+// creating it inside tx when it does not exist yet; created reports which.
+func EnsureTx(tx *sql.Tx, orgID, name string, floors int) (id string, created bool, err error) {
+	err = tx.QueryRow("SELECT id FROM blocks WHERE organization_id = $1 AND name = $2", orgID, name).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, false, err
+	}
+	err = tx.QueryRow(`INSERT INTO blocks (organization_id, name, floors) VALUES ($1, $2, $3) RETURNING id`,
+		orgID, name, floors).Scan(&id)
+	return id, err == nil, err
+}
+
+func (r *Repository) Create(b *Block) error {
+	query := `
+		INSERT INTO blocks (organization_id, name, floors, has_elevator)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query,
+		b.OrganizationID, b.Name, b.Floors, b.HasElevator,
+	).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+}
+
+func (r *Repository) GetByID(id string) (*Block, error) {
+	b := &Block{}
+	if err := scanBlock(r.db.QueryRow(selectBlock+" WHERE b.id = $1", id), b); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("block not found")
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"name":       "b.name",
+		"created_at": "b.created_at",
+	},
+	Default: "name",
+	ID:      "b.id",
+	Search:  []string{"b.name"},
+}
+
+func (r *Repository) ListByOrganization(orgID string, p *listing.Params) ([]Block, listing.Result, error) {
+	query, args := p.Filter(selectBlock+" WHERE b.organization_id = $1", []interface{}{orgID})
+	blocks := []Block{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var b Block
+		if err := scanBlock(rows, &b); err != nil {
+			return err
+		}
+		blocks = append(blocks, b)
+		return nil
+	})
+	return blocks, res, err
+}
+
+func (r *Repository) Update(b *Block) error {
+	query := `UPDATE blocks SET name=$1, floors=$2, has_elevator=$3, updated_at=NOW()
+		WHERE id=$4 RETURNING updated_at`
+
+	return r.db.QueryRow(query, b.Name, b.Floors, b.HasElevator, b.ID).Scan(&b.UpdatedAt)
+}
+
+// Delete removes an empty block. Blocks that still have units or
+// assessments are kept; their expenses fall back to site-wide.
+func (r *Repository) Delete(id string) error {
+	var units int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM units WHERE block_id = $1", id).Scan(&units); err != nil {
+		return err
+	}
+	if units > 0 {
+		return fmt.Errorf("block still has %d units", units)
+	}
+	_, err := r.db.Exec("DELETE FROM blocks WHERE id = $1", id)
+	return err
+}
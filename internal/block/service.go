@@ -0,0 +1,80 @@
+package block
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(orgID string, req CreateRequest) (*Block, error) {
+	b := &Block{
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Floors:         req.Floors,
+		HasElevator:    req.HasElevator,
+	}
+	if err := validate(b); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *Service) GetByID(id string) (*Block, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *Service) ListByOrganization(orgID string, p *listing.Params) ([]Block, listing.Result, error) {
+	return s.repo.ListByOrganization(orgID, p)
+}
+
+func (s *Service) Update(id string, req UpdateRequest) (*Block, error) {
+	b, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		b.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Floors != nil {
+		b.Floors = *req.Floors
+	}
+	if req.HasElevator != nil {
+		b.HasElevator = *req.HasElevator
+	}
+	if err := validate(b); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *Service) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+func validate(b *Block) error {
+	if b.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if b.Floors < 0 {
+		return fmt.Errorf("floors cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package dues
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+)
+
+// TypeLabels are the Turkish names of the dues types (funds) used in
+// exports and documents.
+var TypeLabels = map[string]string{
+	TypeAidat:             "Aidat",
+	TypeDemirbas:          "Demirbaş",
+	TypeSpecialAssessment: "Ek toplama",
+	TypeHeating:           "Isınma",
+	TypePenalty:           "Gecikme cezası",
+}
+
+var statusLabels = map[string]string{
+	"pending":      "Bekliyor",
+	"paid":         "Ödendi",
+	"overdue":      "Gecikmiş",
+	"cancelled":    "İptal",
+	"restructured": "Yapılandırıldı",
+}
+
+// MethodLabels are the Turkish names of the payment methods.
+var MethodLabels = map[string]string{
+	"cash":     "Nakit",
+	"transfer": "Havale/EFT",
+	"online":   "Online",
+}
+
+// AdjustmentLabels are the Turkish names of the adjustment kinds.
+var AdjustmentLabels = map[string]string{
+	AdjustmentVoid:           "İptal",
+	AdjustmentDiscount:       "İndirim",
+	AdjustmentCredit:         "Alacak mahsubu",
+	AdjustmentCreditReversal: "Mahsup iptali",
+}
+
+var exportColumns = []export.Column{
+	{Title: "Blok", Width: 10},
+	{Title: "Daire", Width: 10},
+	{Title: "Ödeyen", Width: 28},
+	{Title: "Tür", Width: 14},
+	{Title: "Açıklama", Width: 32},
+	{Title: "Son Ödeme", Width: 12},
+	{Title: "Tutar", Width: 12},
+	{Title: "İndirim/İptal", Width: 14},
+	{Title: "Net Tutar", Width: 12},
+	{Title: "Durum", Width: 14},
+	{Title: "Ödeme Tarihi", Width: 12},
+	{Title: "Ödeme Şekli", Width: 12},
+}
+
+// exportName names a dues export after the period it covers.
+func exportName(filter ListFilter) string {
+	switch {
+	case filter.Year > 0 && filter.Month > 0:
+		return fmt.Sprintf("aidatlar-%d-%02d", filter.Year, filter.Month)
+	case filter.Year > 0:
+		return fmt.Sprintf("aidatlar-%d", filter.Year)
+	default:
+		return "aidatlar"
+	}
+}
+
+func exportRow(add export.AddFunc, d *Due) error {
+	return add(
+		d.BlockName,
+		d.UnitNumber,
+		d.ResidentName,
+		export.Label(TypeLabels, d.Type),
+		d.Description,
+		d.DueDate,
+		d.Amount,
+		d.AdjustmentTotal,
+		d.NetAmount,
+		export.Label(statusLabels, d.Status),
+		d.PaidAt,
+		export.Label(MethodLabels, d.PaymentMethod),
+	)
+}
+
+var agingColumns = []export.Column{
+	{Title: "Blok", Width: 10},
+	{Title: "Daire", Width: 10},
+	{Title: "Sakin", Width: 28},
+	{Title: "Telefon", Width: 16},
+	{Title: "Vadesi gelmemiş", Width: 14},
+	{Title: "1-30 gün", Width: 12},
+	{Title: "31-60 gün", Width: 12},
+	{Title: "61-90 gün", Width: 12},
+	{Title: "90+ gün", Width: 12},
+	{Title: "Gecikmiş toplam", Width: 14},
+	{Title: "Toplam borç", Width: 14},
+	{Title: "Borç sayısı", Width: 10},
+	{Title: "En eski dönem", Width: 14},
+	{Title: "Gecikme (gün)", Width: 12},
+	{Title: "Son ödeme", Width: 12},
+}
+
+func agingName(r *AgingReport) string {
+	return "borclu-listesi-" + r.AsOf.Format("2006-01-02")
+}
+
+// exportAging writes a row per debtor unit and the site totals last.
+func exportAging(add export.AddFunc, r *AgingReport) error {
+	for _, a := range r.Rows {
+		err := add(a.BlockName, a.UnitNumber, a.ResidentName, a.Phone,
+			a.Current, a.Days1To30, a.Days31To60, a.Days61To90, a.Over90, a.Overdue, a.Total,
+			a.DueCount, periodName(a.OldestDue), a.DaysOverdue, a.LastPayment)
+		if err != nil {
+			return err
+		}
+	}
+	t := r.Totals
+	return add("Toplam", fmt.Sprintf("%d daire", r.Units), nil, nil,
+		t.Current, t.Days1To30, t.Days31To60, t.Days61To90, t.Over90, t.Overdue, t.Total,
+		t.DueCount, nil, nil, nil)
+}
+
+// periodName names the month a due belongs to, e.g. "Ocak 2026".
+func periodName(day time.Time) string {
+	return fmt.Sprintf("%s %d", export.MonthName(int(day.Month())), day.Year())
+}
@@ -2,10 +2,16 @@ package dues
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
 
@@ -58,6 +64,12 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	if s := r.URL.Query().Get("status"); s != "" {
 		filter.Status = s
 	}
+	if t := r.URL.Query().Get("type"); t != "" {
+		filter.Type = t
+	}
+	if b := r.URL.Query().Get("block_id"); b != "" {
+		filter.BlockID = b
+	}
 	if y := r.URL.Query().Get("year"); y != "" {
 		filter.Year, _ = strconv.Atoi(y)
 	}
@@ -65,13 +77,26 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		filter.Month, _ = strconv.Atoi(m)
 	}
 
-	dues, err := h.service.List(filter)
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if format := export.Format(r); format != "" {
+		export.Stream(w, format, exportName(filter), exportColumns, func(add export.AddFunc) error {
+			return h.service.Each(filter, p, func(d *Due) error { return exportRow(add, d) })
+		})
+		return
+	}
+
+	dues, res, err := h.service.List(filter, p)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, dues)
+	response.WithMeta(w, http.StatusOK, dues, p.Meta(res, dues))
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -85,6 +110,22 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, d)
 }
 
+// Receipt returns the receipt of a paid due as a PDF.
+func (h *Handler) Receipt(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+
+	rc, doc, err := h.service.Receipt(orgID, id)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err := pdf.Serve(w, "makbuz-"+rc.DocumentNo, doc); err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 func (h *Handler) MarkPaid(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	var req MarkPaidRequest
@@ -93,7 +134,7 @@ func (h *Handler) MarkPaid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.MarkPaid(id, req.PaymentMethod); err != nil {
+	if err := h.service.MarkPaid(id, req); err != nil {
 		response.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -101,13 +142,320 @@ func (h *Handler) MarkPaid(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, map[string]string{"message": "marked as paid"})
 }
 
+func (h *Handler) Void(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req VoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if err := h.service.Void(orgID, id, userID, req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "due voided"})
+}
+
+func (h *Handler) Discount(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req DiscountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	a, err := h.service.Discount(orgID, id, userID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *Handler) ListDueAdjustments(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	adjustments, err := h.service.ListDueAdjustments(id)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, adjustments)
+}
+
+func (h *Handler) ListAdjustments(w http.ResponseWriter, r *http.Request) {
+	filter := AdjustmentFilter{
+		OrganizationID: chi.URLParam(r, "orgId"),
+		Kind:           r.URL.Query().Get("kind"),
+	}
+	if y := r.URL.Query().Get("year"); y != "" {
+		filter.Year, _ = strconv.Atoi(y)
+	}
+	if m := r.URL.Query().Get("month"); m != "" {
+		filter.Month, _ = strconv.Atoi(m)
+	}
+
+	p, err := listing.Parse(r, adjustmentList)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	adjustments, res, err := h.service.ListAdjustments(filter, p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, adjustments, p.Meta(res, adjustments))
+}
+
+func (h *Handler) CreateCreditNote(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req CreditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	c, err := h.service.CreateCreditNote(orgID, userID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, c)
+}
+
+func (h *Handler) ListCreditNotes(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	p, err := listing.Parse(r, creditNoteList)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	notes, res, err := h.service.ListCreditNotes(orgID, r.URL.Query().Get("unit_id"), p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, notes, p.Meta(res, notes))
+}
+
+func (h *Handler) CreateOffer(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req CreateOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	o, err := h.service.CreateOffer(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, o)
+}
+
+func (h *Handler) ListOffers(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	p, err := listing.Parse(r, offerList)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offers, res, err := h.service.ListOffers(orgID, p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, offers, p.Meta(res, offers))
+}
+
+func (h *Handler) UpdateOffer(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req UpdateOfferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	o, err := h.service.UpdateOffer(orgID, id, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, o)
+}
+
+func (h *Handler) Prepay(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req PrepayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	result, err := h.service.Prepay(orgID, id, userID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) SendReminders(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	result, err := h.service.SendReminders(orgID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) ReminderLink(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	link, err := h.service.ReminderLink(orgID, id)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"whatsapp_url": link})
+}
+
 func (h *Handler) GetOverdue(w http.ResponseWriter, r *http.Request) {
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := ListFilter{OrganizationID: chi.URLParam(r, "orgId"), Status: "overdue"}
+	dues, res, err := h.service.List(filter, p)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.WithMeta(w, http.StatusOK, dues, p.Meta(res, dues))
+}
+
+// Aging returns the debtor list grouped by unit and lateness, as JSON or
+// as a CSV/XLSX download. min_overdue and min_days pick the units to hand
+// over for legal action.
+func (h *Handler) Aging(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := AgingFilter{
+		OrganizationID: chi.URLParam(r, "orgId"),
+		BlockID:        q.Get("block_id"),
+		Type:           q.Get("type"),
+		Sort:           q.Get("sort"),
+		Desc:           q.Get("order") == "desc",
+	}
+	if v := q.Get("min_overdue"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid min_overdue")
+			return
+		}
+		filter.MinOverdue = amount
+	}
+	if v := q.Get("min_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid min_days")
+			return
+		}
+		filter.MinDays = days
+	}
+	if err := filter.validate(); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.service.Aging(filter)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format := export.Format(r); format != "" {
+		export.Stream(w, format, agingName(report), agingColumns, func(add export.AddFunc) error {
+			return exportAging(add, report)
+		})
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) UploadProof(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+
+	file, header, err := attachment.FormFile(w, r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	a, err := h.service.AddProof(orgID, id, file, header)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *Handler) ListProofs(w http.ResponseWriter, r *http.Request) {
 	orgID := chi.URLParam(r, "orgId")
-	dues, err := h.service.GetOverdue(orgID)
+	id := chi.URLParam(r, "id")
+	proofs, err := h.service.ListProofs(orgID, id)
+	if errors.Is(err, ErrNotFound) {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, dues)
+	response.JSON(w, http.StatusOK, proofs)
+}
+
+func (h *Handler) DeleteProof(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	fileID := chi.URLParam(r, "fileId")
+	if err := h.service.DeleteProof(orgID, id, fileID); err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
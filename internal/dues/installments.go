@@ -0,0 +1,36 @@
+package dues
+
+import (
+	"math"
+	"time"
+)
+
+// ToKurus converts a lira amount to integer kuruş so installment splits add
+// up exactly.
+func ToKurus(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// SplitKurus divides amount into n installments, the last one absorbing the
+// remainder.
+func SplitKurus(amount int64, n int) []int64 {
+	parts := make([]int64, n)
+	each := amount / int64(n)
+	for i := range parts {
+		parts[i] = each
+	}
+	parts[n-1] += amount - each*int64(n)
+	return parts
+}
+
+// AddMonths moves t forward by n months, clamping to the last day of shorter
+// months (Jan 31 + 1 month = Feb 28).
+func AddMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	firstOfMonth := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	last := firstOfMonth.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return firstOfMonth.AddDate(0, 0, d-1)
+}
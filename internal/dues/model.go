@@ -2,30 +2,61 @@ package dues
 
 import "time"
 
+// Dues types. Each type is collected into its own fund, and expenses are
+// charged against one of these funds.
+const (
+	TypeAidat             = "aidat"
+	TypeDemirbas          = "demirbas" // reserve fund (yedek akçe)
+	TypeSpecialAssessment = "special_assessment"
+	TypeHeating           = "heating"
+	TypePenalty           = "penalty"
+)
+
+var Types = []string{TypeAidat, TypeDemirbas, TypeSpecialAssessment, TypeHeating, TypePenalty}
+
+// ValidType reports whether t is a known dues type (fund).
+func ValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
 type Due struct {
-	ID             string     `json:"id"`
-	OrganizationID string     `json:"organization_id"`
-	UnitID         string     `json:"unit_id"`
-	UnitNumber     string     `json:"unit_number,omitempty"`
-	ResidentName   string     `json:"resident_name,omitempty"`
-	Amount         float64    `json:"amount"`
-	DueDate        time.Time  `json:"due_date"`
-	Status         string     `json:"status"` // pending, paid, overdue
-	PaidAt         *time.Time `json:"paid_at,omitempty"`
-	PaymentMethod  string     `json:"payment_method,omitempty"` // cash, transfer, online
-	Description    string     `json:"description,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID              string     `json:"id"`
+	OrganizationID  string     `json:"organization_id"`
+	UnitID          string     `json:"unit_id"`
+	UnitNumber      string     `json:"unit_number,omitempty"`
+	BlockName       string     `json:"block_name,omitempty"`
+	ResidentName    string     `json:"resident_name,omitempty"` // the payer's name when one is assigned
+	PayerID         *string    `json:"payer_id,omitempty"`      // resident the due is billed to
+	Type            string     `json:"type"`                    // aidat, demirbas, special_assessment, heating, penalty
+	Amount          float64    `json:"amount"`
+	AdjustmentTotal float64    `json:"adjustment_total"` // voids, discounts and credits applied
+	NetAmount       float64    `json:"net_amount"`       // amount - adjustment_total, what is actually owed
+	DueDate         time.Time  `json:"due_date"`
+	Status          string     `json:"status"` // pending, paid, overdue, cancelled, restructured (moved into a payment plan or a new installment plan)
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+	PaymentMethod   string     `json:"payment_method,omitempty"` // cash, transfer, online
+	AccountID       *string    `json:"account_id,omitempty"`     // account the payment was received into
+	Description     string     `json:"description,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type CreateRequest struct {
 	UnitID      string  `json:"unit_id"`
+	Type        string  `json:"type,omitempty"` // defaults to aidat
 	Amount      float64 `json:"amount"`
 	DueDate     string  `json:"due_date"` // YYYY-MM-DD
 	Description string  `json:"description,omitempty"`
 }
 
 type BulkCreateRequest struct {
+	BlockID     string  `json:"block_id,omitempty"` // only the units of this block; all units when empty
+	Type        string  `json:"type,omitempty"`     // defaults to aidat
 	Amount      float64 `json:"amount"`
 	DueDate     string  `json:"due_date"`
 	Description string  `json:"description,omitempty"`
@@ -33,11 +64,197 @@ type BulkCreateRequest struct {
 
 type MarkPaidRequest struct {
 	PaymentMethod string `json:"payment_method"`
+	// AccountID is optional; by default cash goes to the cash box and other
+	// methods to the default bank account.
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// Adjustment kinds. Adjustments are never edited; a reversal is recorded as
+// a new adjustment with a negative amount.
+const (
+	AdjustmentVoid           = "void"
+	AdjustmentDiscount       = "discount"
+	AdjustmentCredit         = "credit"
+	AdjustmentCreditReversal = "credit_reversal"
+)
+
+// Adjustment reduces what a due is worth without editing its amount.
+type Adjustment struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	DueID          string    `json:"due_id"`
+	UnitNumber     string    `json:"unit_number,omitempty"`
+	Kind           string    `json:"kind"` // void, discount, credit, credit_reversal
+	Amount         float64   `json:"amount"`
+	Percent        *float64  `json:"percent,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	CreditNoteID   *string   `json:"credit_note_id,omitempty"`
+	CreatedBy      *string   `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type VoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DiscountRequest takes either a percentage of the due amount or a fixed
+// amount.
+type DiscountRequest struct {
+	Percent float64 `json:"percent,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+	Reason  string  `json:"reason"`
+}
+
+// CreditNote is money owed to a unit that is consumed by its future dues.
+type CreditNote struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	UnitID         string    `json:"unit_id"`
+	UnitNumber     string    `json:"unit_number,omitempty"`
+	Amount         float64   `json:"amount"`
+	Remaining      float64   `json:"remaining"`
+	Reason         string    `json:"reason"`
+	CreatedBy      *string   `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreditNoteRequest struct {
+	UnitID string  `json:"unit_id"`
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+// MaxOfferMonths bounds how many months one prepayment covers.
+const MaxOfferMonths = 36
+
+// Offer is an organization-level prepayment deal: pay Months of dues
+// upfront before ValidUntil and get DiscountPercent off each month.
+type Offer struct {
+	ID              string    `json:"id"`
+	OrganizationID  string    `json:"organization_id"`
+	Title           string    `json:"title"`
+	Type            string    `json:"type"` // dues type the offer covers
+	Months          int       `json:"months"`
+	DiscountPercent float64   `json:"discount_percent"`
+	ValidUntil      time.Time `json:"valid_until"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type CreateOfferRequest struct {
+	Title           string  `json:"title"`
+	Type            string  `json:"type,omitempty"` // defaults to aidat
+	Months          int     `json:"months"`
+	DiscountPercent float64 `json:"discount_percent"`
+	ValidUntil      string  `json:"valid_until"` // YYYY-MM-DD
+}
+
+type UpdateOfferRequest struct {
+	Title           *string  `json:"title,omitempty"`
+	DiscountPercent *float64 `json:"discount_percent,omitempty"`
+	ValidUntil      *string  `json:"valid_until,omitempty"`
+	Active          *bool    `json:"active,omitempty"`
+}
+
+// PrepayRequest accepts an offer for a unit. Months already billed are
+// settled; the others are created at the organization's monthly amount.
+type PrepayRequest struct {
+	UnitID        string `json:"unit_id"`
+	StartMonth    string `json:"start_month,omitempty"` // YYYY-MM, defaults to the current month
+	DueDay        int    `json:"due_day,omitempty"`     // day of month for created dues, defaults to 1
+	PaymentMethod string `json:"payment_method"`
+	AccountID     string `json:"account_id,omitempty"`
+}
+
+type PrepayResult struct {
+	OfferID  string  `json:"offer_id"`
+	UnitID   string  `json:"unit_id"`
+	Gross    float64 `json:"gross"`
+	Discount float64 `json:"discount"`
+	Credits  float64 `json:"credits"`
+	Paid     float64 `json:"paid"`
+	Dues     []Due   `json:"dues"`
+}
+
+// Receipt (tahsilat makbuzu) is issued for every paid due. Numbers run
+// 1, 2, 3... per organization and year in payment order; DocumentNo is
+// the printed form, e.g. MKB-2026-000123.
+type Receipt struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Year           int       `json:"year"`
+	Number         int       `json:"number"`
+	DocumentNo     string    `json:"document_no"`
+	DueID          string    `json:"due_id"`
+	Amount         float64   `json:"amount"`
+	PaymentMethod  string    `json:"payment_method"`
+	IssuedAt       time.Time `json:"issued_at"`
+}
+
+type ReminderResult struct {
+	Sent    int `json:"sent"`
+	Skipped int `json:"skipped"` // payer has no phone number
+}
+
+type AdjustmentFilter struct {
+	OrganizationID string
+	Kind           string
+	Year           int
+	Month          int
 }
 
 type ListFilter struct {
 	OrganizationID string
 	Status         string
+	Type           string
+	BlockID        string
 	Month          int
 	Year           int
 }
+
+// AgingRow is one debtor unit of the aging report (borçlu listesi). Unpaid
+// dues are bucketed by how many days they are past their due date;
+// Current holds dues falling due in the next 30 days, which are billed but
+// not late yet. Dues due later than that are not counted as debt.
+type AgingRow struct {
+	UnitID       string     `json:"unit_id"`
+	UnitNumber   string     `json:"unit_number"`
+	BlockName    string     `json:"block_name,omitempty"`
+	ResidentName string     `json:"resident_name,omitempty"`
+	Phone        string     `json:"phone,omitempty"`
+	Current      float64    `json:"current"`
+	Days1To30    float64    `json:"days_1_30"`
+	Days31To60   float64    `json:"days_31_60"`
+	Days61To90   float64    `json:"days_61_90"`
+	Over90       float64    `json:"over_90"`
+	Overdue      float64    `json:"overdue"` // all buckets but Current
+	Total        float64    `json:"total"`
+	DueCount     int        `json:"due_count"`
+	OldestDue    time.Time  `json:"oldest_due"`   // due date of the oldest unpaid period
+	DaysOverdue  int        `json:"days_overdue"` // how late the oldest unpaid due is
+	LastPayment  *time.Time `json:"last_payment,omitempty"`
+}
+
+// AgingReport lists debtor units with the bucket totals of the whole site.
+type AgingReport struct {
+	AsOf   time.Time  `json:"as_of"`
+	Units  int        `json:"units"`
+	Totals AgingRow   `json:"totals"` // bucket sums; unit fields are empty
+	Rows   []AgingRow `json:"rows"`
+}
+
+// AgingFilter narrows the aging report. MinOverdue and MinDays find the
+// units that qualify for legal action (icra takibi): at least that much
+// overdue debt, or an unpaid due at least that many days late. A zero
+// threshold is not applied; with both zero every unit with debt is listed.
+type AgingFilter struct {
+	OrganizationID string
+	BlockID        string
+	Type           string
+	MinOverdue     float64
+	MinDays        int
+	Sort           string // total, overdue, days, unit, last_payment
+	Desc           bool
+}
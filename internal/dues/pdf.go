@@ -0,0 +1,44 @@
+package dues
+
+import (
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
+)
+
+// receiptPDF lays out a receipt the way it is handed to the resident:
+// what was paid for, the amount in figures and in words, and room for
+// both signatures.
+func receiptPDF(siteName, address string, rc *Receipt, d *Due) *pdf.Document {
+	doc := pdf.New("Tahsilat Makbuzu " + rc.DocumentNo)
+	doc.Header(siteName, address, "TAHSİLAT MAKBUZU")
+
+	unit := d.UnitNumber
+	if d.BlockName != "" {
+		unit = d.BlockName + " / " + unit
+	}
+	doc.Field("Makbuz No", rc.DocumentNo)
+	doc.Field("Tarih", rc.IssuedAt.Format("02.01.2006"))
+	doc.Field("Daire", unit)
+	doc.Field("Ödeyen", d.ResidentName)
+	doc.Field("Ödeme Şekli", export.Label(MethodLabels, rc.PaymentMethod))
+	doc.Space(4)
+
+	description := export.Label(TypeLabels, d.Type)
+	if d.Description != "" {
+		description += " - " + d.Description
+	}
+	period := periodName(d.DueDate)
+	doc.Table([]pdf.Column{
+		{Title: "Açıklama"},
+		{Title: "Dönem", Width: 35},
+		{Title: "Tutar (TL)", Width: 35, Align: pdf.Right},
+	}, [][]string{
+		{description, period, export.Money(rc.Amount)},
+		{"Toplam", "", export.Money(rc.Amount)},
+	}, map[int]bool{1: true})
+
+	doc.Space(3)
+	doc.Text("Yalnız " + pdf.AmountInWords(rc.Amount) + ".")
+	doc.Signatures("Tahsil Eden", "Ödeyen")
+	return doc
+}
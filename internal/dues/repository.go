@@ -2,8 +2,16 @@ package dues
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/account"
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/numbering"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Repository struct {
@@ -14,72 +22,196 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// ErrNotFound is returned when no due has the given ID, or it belongs to
+// another organization.
+var ErrNotFound = errors.New("due not found")
+
+const selectDue = `SELECT d.id, d.organization_id, d.unit_id,
+		COALESCE(u.unit_number, '') as unit_number, COALESCE(b.name, '') as block_name,
+		COALESCE(payer.full_name, occ.full_name, '') as resident_name, d.payer_resident_id,
+		d.type, d.amount, d.adjustment_total, d.due_date, d.status, d.paid_at,
+		COALESCE(d.payment_method, '') as payment_method, d.account_id,
+		COALESCE(d.description, '') as description,
+		d.created_at, d.updated_at
+		FROM dues d
+		LEFT JOIN units u ON d.unit_id = u.id
+		LEFT JOIN blocks b ON u.block_id = b.id
+		LEFT JOIN residents payer ON d.payer_resident_id = payer.id
+		LEFT JOIN LATERAL (
+			SELECT r.full_name FROM unit_occupants o
+			JOIN residents r ON o.resident_id = r.id
+			WHERE o.unit_id = d.unit_id AND o.move_in_date <= d.due_date
+				AND (o.move_out_date IS NULL OR o.move_out_date > d.due_date)
+			ORDER BY CASE o.role WHEN 'tenant' THEN 0 WHEN 'owner' THEN 1 ELSE 2 END
+			LIMIT 1
+		) occ ON TRUE`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDue(row scanner, d *Due) error {
+	err := row.Scan(
+		&d.ID, &d.OrganizationID, &d.UnitID, &d.UnitNumber, &d.BlockName, &d.ResidentName, &d.PayerID,
+		&d.Type, &d.Amount, &d.AdjustmentTotal, &d.DueDate, &d.Status, &d.PaidAt,
+		&d.PaymentMethod, &d.AccountID, &d.Description, &d.CreatedAt, &d.UpdatedAt,
+	)
+	d.NetAmount = d.Amount - d.AdjustmentTotal
+	return err
+}
+
+// Create inserts a due and applies any open credit notes of the unit to it.
 func (r *Repository) Create(d *Due) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO dues (organization_id, unit_id, amount, due_date, status, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO dues (organization_id, unit_id, type, amount, due_date, status, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(query,
-		d.OrganizationID, d.UnitID, d.Amount, d.DueDate, d.Status, d.Description,
+	err = tx.QueryRow(query,
+		d.OrganizationID, d.UnitID, d.Type, d.Amount, d.DueDate, d.Status, d.Description,
 	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := OnIssued(tx, []string{d.ID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	saved, err := r.GetByID(d.ID)
+	if err != nil {
+		return err
+	}
+	*d = *saved
+	return nil
 }
 
-func (r *Repository) BulkCreate(orgID string, amount float64, dueDate time.Time, description string) (int, error) {
+// BulkCreate issues a due to every unit of the organization, or of one block
+// when blockID is set, and applies open credit notes to the new dues. Units
+// that already have a due of the type in that month, such as a prepaid one,
+// are skipped.
+func (r *Repository) BulkCreate(orgID, blockID, dueType string, amount float64, dueDate time.Time, description string) (int, error) {
+	if blockID != "" {
+		if err := block.Belongs(r.db, blockID, orgID); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO dues (organization_id, unit_id, amount, due_date, status, description)
-		SELECT $1, u.id, $2, $3, 'pending', $4
-		FROM units u WHERE u.organization_id = $1`
+		INSERT INTO dues (organization_id, unit_id, type, amount, due_date, status, description)
+		SELECT $1, u.id, $5, $2, $3, 'pending', $4
+		FROM units u WHERE u.organization_id = $1 AND ($6 = '' OR u.block_id::text = $6)
+			AND NOT EXISTS (SELECT 1 FROM dues x
+				WHERE x.unit_id = u.id AND x.type = $5
+					AND date_trunc('month', x.due_date) = date_trunc('month', $3::date)
+					AND x.status NOT IN ('cancelled', 'restructured'))
+		RETURNING id`
 
-	result, err := r.db.Exec(query, orgID, amount, dueDate, description)
+	rows, err := tx.Query(query, orgID, amount, dueDate, description, dueType, blockID)
 	if err != nil {
 		return 0, err
 	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
 
-	count, err := result.RowsAffected()
-	return int(count), err
+	if err := OnIssued(tx, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
 }
 
 func (r *Repository) GetByID(id string) (*Due, error) {
 	d := &Due{}
-	query := `SELECT d.id, d.organization_id, d.unit_id,
-		COALESCE(u.unit_number, '') as unit_number,
-		COALESCE(res.full_name, '') as resident_name,
-		d.amount, d.due_date, d.status, d.paid_at,
-		COALESCE(d.payment_method, '') as payment_method,
-		COALESCE(d.description, '') as description,
-		d.created_at, d.updated_at
-		FROM dues d
-		LEFT JOIN units u ON d.unit_id = u.id
-		LEFT JOIN residents res ON u.resident_id = res.id
-		WHERE d.id = $1`
-
-	err := r.db.QueryRow(query, id).Scan(
-		&d.ID, &d.OrganizationID, &d.UnitID, &d.UnitNumber, &d.ResidentName,
-		&d.Amount, &d.DueDate, &d.Status, &d.PaidAt,
-		&d.PaymentMethod, &d.Description, &d.CreatedAt, &d.UpdatedAt,
-	)
-	if err != nil {
+	if err := scanDue(r.db.QueryRow(selectDue+" WHERE d.id = $1", id), d); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("due not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 	return d, nil
 }
 
-func (r *Repository) List(filter ListFilter) ([]Due, error) {
-	query := `SELECT d.id, d.organization_id, d.unit_id,
-		COALESCE(u.unit_number, '') as unit_number,
-		COALESCE(res.full_name, '') as resident_name,
-		d.amount, d.due_date, d.status, d.paid_at,
-		COALESCE(d.payment_method, '') as payment_method,
-		COALESCE(d.description, '') as description,
-		d.created_at, d.updated_at
-		FROM dues d
-		LEFT JOIN units u ON d.unit_id = u.id
-		LEFT JOIN residents res ON u.resident_id = res.id
-		WHERE d.organization_id = $1`
+// listConfig is what the dues list can be sorted and searched by; ?q=
+// matches the unit number, the payer's name and the description.
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"due_date":    "d.due_date",
+		"amount":      "d.amount",
+		"net_amount":  "(d.amount - d.adjustment_total)",
+		"unit_number": "COALESCE(u.unit_number, '')",
+		"status":      "d.status",
+		"created_at":  "d.created_at",
+	},
+	Default: "-due_date",
+	ID:      "d.id",
+	Search:  []string{"u.unit_number", "payer.full_name", "occ.full_name", "d.description"},
+	Date:    "d.due_date",
+}
+
+func (r *Repository) List(filter ListFilter, p *listing.Params) ([]Due, listing.Result, error) {
+	query, args := p.Filter(listQuery(filter))
+	dues := []Due{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var d Due
+		if err := scanDue(rows, &d); err != nil {
+			return err
+		}
+		dues = append(dues, d)
+		return nil
+	})
+	return dues, res, err
+}
+
+// Each calls fn for every due matching the filter, in List order, while
+// reading them from the database, so exports do not hold the whole list.
+func (r *Repository) Each(filter ListFilter, p *listing.Params, fn func(*Due) error) error {
+	query, args := p.Filter(listQuery(filter))
+	rows, err := r.db.Query(query+p.Order(), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d Due
+		if err := scanDue(rows, &d); err != nil {
+			return err
+		}
+		if err := fn(&d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func listQuery(filter ListFilter) (string, []interface{}) {
+	query := selectDue + " WHERE d.organization_id = $1"
 
 	args := []interface{}{filter.OrganizationID}
 	argIdx := 2
@@ -89,6 +221,16 @@ func (r *Repository) List(filter ListFilter) ([]Due, error) {
 		args = append(args, filter.Status)
 		argIdx++
 	}
+	if filter.Type != "" {
+		query += fmt.Sprintf(" AND d.type = $%d", argIdx)
+		args = append(args, filter.Type)
+		argIdx++
+	}
+	if filter.BlockID != "" {
+		query += fmt.Sprintf(" AND u.block_id = $%d", argIdx)
+		args = append(args, filter.BlockID)
+		argIdx++
+	}
 	if filter.Year > 0 {
 		query += fmt.Sprintf(" AND EXTRACT(YEAR FROM d.due_date) = $%d", argIdx)
 		args = append(args, filter.Year)
@@ -99,37 +241,76 @@ func (r *Repository) List(filter ListFilter) ([]Due, error) {
 		args = append(args, filter.Month)
 		argIdx++
 	}
+	return query, args
+}
 
-	query += " ORDER BY d.due_date DESC, u.unit_number"
-
-	rows, err := r.db.Query(query, args...)
+// MarkPaid settles a due and posts the payment to an account in the same
+// transaction.
+func (r *Repository) MarkPaid(id string, method string, accountID string) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var dues []Due
-	for rows.Next() {
-		var d Due
-		if err := rows.Scan(
-			&d.ID, &d.OrganizationID, &d.UnitID, &d.UnitNumber, &d.ResidentName,
-			&d.Amount, &d.DueDate, &d.Status, &d.PaidAt,
-			&d.PaymentMethod, &d.Description, &d.CreatedAt, &d.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		dues = append(dues, d)
+	if err := MarkPaidTx(tx, id, method, accountID); err != nil {
+		return err
 	}
-	return dues, nil
+	return tx.Commit()
 }
 
-func (r *Repository) MarkPaid(id string, method string) error {
+// MarkPaidTx settles a due inside the caller's transaction, for flows that
+// pay several dues at once (installment payoffs, prepayments).
+func MarkPaidTx(tx *sql.Tx, id string, method string, accountID string) error {
+	entry := &account.Entry{Kind: account.KindPayment, SourceType: "due", SourceID: &id, AccountID: accountID}
 	query := `UPDATE dues SET status='paid', paid_at=NOW(), payment_method=$1, updated_at=NOW()
-		WHERE id=$2`
-	_, err := r.db.Exec(query, method, id)
+		WHERE id=$2 AND status IN ('pending', 'overdue')
+		RETURNING organization_id, amount - adjustment_total, paid_at::date, COALESCE(description, '')`
+	err := tx.QueryRow(query, method, id).Scan(&entry.OrganizationID, &entry.Amount, &entry.Date, &entry.Description)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("due not found or not payable")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := account.Post(tx, entry, method); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE dues SET account_id = $1 WHERE id = $2", entry.AccountID, id); err != nil {
+		return err
+	}
+	return issueReceipt(tx, entry, method)
+}
+
+// issueReceipt gives a payment the organization's next receipt number of
+// the payment's year.
+func issueReceipt(tx *sql.Tx, entry *account.Entry, method string) error {
+	n, err := numbering.Next(tx, entry.OrganizationID, numbering.KindReceipt, entry.Date, entry.SourceID, "")
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO receipts (organization_id, year, number, document_no, due_id, amount, payment_method)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.OrganizationID, n.Year, n.Seq, n.Number, *entry.SourceID, entry.Amount, method)
 	return err
 }
 
+func (r *Repository) GetReceiptByDue(dueID string) (*Receipt, error) {
+	rc := &Receipt{}
+	err := r.db.QueryRow(`SELECT id, organization_id, year, number, document_no, due_id, amount, payment_method, issued_at
+		FROM receipts WHERE due_id = $1`, dueID).Scan(
+		&rc.ID, &rc.OrganizationID, &rc.Year, &rc.Number, &rc.DocumentNo, &rc.DueID, &rc.Amount, &rc.PaymentMethod, &rc.IssuedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("receipt not found, the due is not paid")
+	}
+	return rc, err
+}
+
+func (r *Repository) OrganizationHeader(orgID string) (string, string, error) {
+	return organization.Header(r.db, orgID)
+}
+
 func (r *Repository) MarkOverdue() (int, error) {
 	query := `UPDATE dues SET status='overdue', updated_at=NOW()
 		WHERE status='pending' AND due_date < CURRENT_DATE`
@@ -141,6 +322,718 @@ func (r *Repository) MarkOverdue() (int, error) {
 	return int(count), err
 }
 
-func (r *Repository) GetOverdue(orgID string) ([]Due, error) {
-	return r.List(ListFilter{OrganizationID: orgID, Status: "overdue"})
+// agingSorts maps the aging report's sort keys to their ORDER BY columns.
+var agingSorts = map[string]string{
+	"total":        "a.total",
+	"overdue":      "a.overdue",
+	"days":         "days_overdue",
+	"unit":         "block_name, u.unit_number",
+	"last_payment": "last_payment",
+}
+
+// Aging groups the unpaid dues of each unit by how late they are. The
+// person shown is the payer of the unit's latest open due, or the unit's
+// resident when none of them has a payer.
+func (r *Repository) Aging(filter AgingFilter) ([]AgingRow, error) {
+	order := "DESC NULLS LAST"
+	if !filter.Desc {
+		order = "ASC NULLS FIRST"
+	}
+	query := `
+		SELECT u.id, u.unit_number, COALESCE(b.name, '') as block_name,
+			COALESCE(res.full_name, '') as resident_name, COALESCE(res.phone, '') as phone,
+			a.current, a.days_1_30, a.days_31_60, a.days_61_90, a.over_90, a.overdue, a.total,
+			a.due_count, a.oldest_due, GREATEST(CURRENT_DATE - a.oldest_due, 0) as days_overdue,
+			(SELECT MAX(p.paid_at) FROM dues p
+				WHERE p.unit_id = u.id AND p.status = 'paid' AND p.payment_method <> 'adjustment') as last_payment
+		FROM (
+			SELECT d.unit_id,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.due_date >= CURRENT_DATE), 0) as current,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE CURRENT_DATE - d.due_date BETWEEN 1 AND 30), 0) as days_1_30,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE CURRENT_DATE - d.due_date BETWEEN 31 AND 60), 0) as days_31_60,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE CURRENT_DATE - d.due_date BETWEEN 61 AND 90), 0) as days_61_90,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE CURRENT_DATE - d.due_date > 90), 0) as over_90,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.due_date < CURRENT_DATE), 0) as overdue,
+				SUM(d.amount - d.adjustment_total) as total,
+				COUNT(*) as due_count,
+				MIN(d.due_date) as oldest_due,
+				(ARRAY_AGG(d.payer_resident_id ORDER BY d.due_date DESC)
+					FILTER (WHERE d.payer_resident_id IS NOT NULL))[1] as payer_id
+			FROM dues d
+			WHERE d.organization_id = $1 AND d.status IN ('pending', 'overdue')
+				AND d.due_date <= CURRENT_DATE + 30
+				AND ($2 = '' OR d.type = $2)
+			GROUP BY d.unit_id
+		) a
+		JOIN units u ON a.unit_id = u.id
+		LEFT JOIN blocks b ON u.block_id = b.id
+		LEFT JOIN residents res ON res.id = COALESCE(a.payer_id, u.resident_id)
+		WHERE a.total > 0
+			AND ($3 = '' OR u.block_id::text = $3)
+			AND (($4::numeric = 0 AND $5::int = 0)
+				OR ($4::numeric > 0 AND a.overdue >= $4::numeric)
+				OR ($5::int > 0 AND CURRENT_DATE - a.oldest_due >= $5::int))
+		ORDER BY ` + agingSorts[filter.Sort] + " " + order + ", block_name, u.unit_number"
+
+	rows, err := r.db.Query(query, filter.OrganizationID, filter.Type, filter.BlockID, filter.MinOverdue, filter.MinDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []AgingRow{}
+	for rows.Next() {
+		var a AgingRow
+		if err := rows.Scan(&a.UnitID, &a.UnitNumber, &a.BlockName, &a.ResidentName, &a.Phone,
+			&a.Current, &a.Days1To30, &a.Days31To60, &a.Days61To90, &a.Over90, &a.Overdue, &a.Total,
+			&a.DueCount, &a.OldestDue, &a.DaysOverdue, &a.LastPayment); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// lockOpenDue locks a due that can still be adjusted and returns its
+// organization, amount and current adjustment total.
+func lockOpenDue(tx *sql.Tx, id string) (string, float64, float64, error) {
+	var orgID, status string
+	var amount, adjusted float64
+	err := tx.QueryRow(`SELECT organization_id, amount, adjustment_total, status FROM dues
+		WHERE id = $1 FOR UPDATE`, id).Scan(&orgID, &amount, &adjusted, &status)
+	if err == sql.ErrNoRows {
+		return "", 0, 0, ErrNotFound
+	}
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if status != "pending" && status != "overdue" {
+		return "", 0, 0, fmt.Errorf("only pending or overdue dues can be adjusted, this due is %s", status)
+	}
+	return orgID, amount, adjusted, nil
+}
+
+// addAdjustment records an adjustment and folds it into the due's
+// adjustment total.
+func addAdjustment(tx *sql.Tx, a *Adjustment) error {
+	query := `
+		INSERT INTO due_adjustments (organization_id, due_id, kind, amount, percent, reason, credit_note_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+	err := tx.QueryRow(query,
+		a.OrganizationID, a.DueID, a.Kind, a.Amount, a.Percent, a.Reason, a.CreditNoteID, a.CreatedBy,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE dues SET adjustment_total = adjustment_total + $1, updated_at = NOW()
+		WHERE id = $2`, a.Amount, a.DueID)
+	return err
+}
+
+// settleIfCovered closes a due whose adjustments cover its whole amount.
+// No money moves, so nothing is posted to an account.
+func settleIfCovered(tx *sql.Tx, id string) error {
+	_, err := tx.Exec(`UPDATE dues SET status = 'paid', paid_at = NOW(), payment_method = 'adjustment', updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'overdue') AND amount - adjustment_total <= 0`, id)
+	return err
+}
+
+// Void cancels a due. Credits it consumed go back to their credit notes and
+// the rest of the amount is written off as a void adjustment.
+func (r *Repository) Void(id, reason string, createdBy *string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := VoidTx(tx, id, reason, createdBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// VoidTx is Void inside the caller's transaction, for flows that cancel
+// dues they issued (payment plan cancellations).
+func VoidTx(tx *sql.Tx, id, reason string, createdBy *string) error {
+	orgID, amount, adjusted, err := lockOpenDue(tx, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT credit_note_id, SUM(amount) FROM due_adjustments
+		WHERE due_id = $1 AND credit_note_id IS NOT NULL
+		GROUP BY credit_note_id HAVING SUM(amount) > 0`, id)
+	if err != nil {
+		return err
+	}
+	credits := make(map[string]float64)
+	for rows.Next() {
+		var noteID string
+		var used float64
+		if err := rows.Scan(&noteID, &used); err != nil {
+			rows.Close()
+			return err
+		}
+		credits[noteID] = used
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for noteID, used := range credits {
+		noteID := noteID
+		rev := &Adjustment{
+			OrganizationID: orgID, DueID: id, Kind: AdjustmentCreditReversal, Amount: -used,
+			Reason: "due voided", CreditNoteID: &noteID, CreatedBy: createdBy,
+		}
+		if err := addAdjustment(tx, rev); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE credit_notes SET remaining = remaining + $1, updated_at = NOW()
+			WHERE id = $2`, used, noteID); err != nil {
+			return err
+		}
+		adjusted -= used
+	}
+
+	void := &Adjustment{
+		OrganizationID: orgID, DueID: id, Kind: AdjustmentVoid, Amount: amount - adjusted,
+		Reason: reason, CreatedBy: createdBy,
+	}
+	if err := addAdjustment(tx, void); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE dues SET status = 'cancelled', updated_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// Discount reduces a due by a fixed amount or a percentage of its amount.
+func (r *Repository) Discount(id string, percent, fixed float64, reason string, createdBy *string) (*Adjustment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, err := DiscountTx(tx, id, percent, fixed, reason, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	return a, tx.Commit()
+}
+
+// DiscountTx records a discount on an open due inside tx, settling the due
+// when nothing is left to pay.
+func DiscountTx(tx *sql.Tx, id string, percent, fixed float64, reason string, createdBy *string) (*Adjustment, error) {
+	orgID, amount, adjusted, err := lockOpenDue(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Adjustment{OrganizationID: orgID, DueID: id, Kind: AdjustmentDiscount, Reason: reason, CreatedBy: createdBy}
+	if percent > 0 {
+		a.Percent = &percent
+		a.Amount = float64(ToKurus(amount*percent/100)) / 100
+	} else {
+		a.Amount = fixed
+	}
+	if ToKurus(a.Amount) > ToKurus(amount-adjusted) {
+		return nil, fmt.Errorf("discount exceeds the remaining amount of %.2f", amount-adjusted)
+	}
+
+	if err := addAdjustment(tx, a); err != nil {
+		return nil, err
+	}
+	if err := settleIfCovered(tx, id); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// OnIssued does the bookkeeping every newly issued due needs: it assigns
+// the payer and applies open credit notes. Every flow that inserts dues
+// calls it inside its transaction.
+func OnIssued(tx *sql.Tx, dueIDs []string) error {
+	if err := assignPayers(tx, dueIDs); err != nil {
+		return err
+	}
+	return applyCredits(tx, dueIDs)
+}
+
+// assignPayers bills each due to the unit's designated payer for its type,
+// or else to the occupant on the due date by role: demirbaş and special
+// assessments fall on the owner, everything else on the tenant first.
+func assignPayers(tx *sql.Tx, dueIDs []string) error {
+	_, err := tx.Exec(`UPDATE dues d SET payer_resident_id = COALESCE(
+			(SELECT p.resident_id FROM unit_payers p WHERE p.unit_id = d.unit_id AND p.dues_type = d.type),
+			(SELECT o.resident_id FROM unit_occupants o
+				WHERE o.unit_id = d.unit_id
+					AND o.move_in_date <= d.due_date
+					AND (o.move_out_date IS NULL OR o.move_out_date > d.due_date)
+					AND (o.role IN ('owner', 'co_owner') OR (o.role = 'tenant' AND d.type NOT IN ($2, $3)))
+				ORDER BY CASE o.role WHEN 'tenant' THEN 0 WHEN 'owner' THEN 1 ELSE 2 END, o.move_in_date
+				LIMIT 1),
+			(SELECT u.resident_id FROM units u WHERE u.id = d.unit_id))
+		WHERE d.id = ANY($1)`, pq.Array(dueIDs), TypeDemirbas, TypeSpecialAssessment)
+	return err
+}
+
+// ReassignPayers re-bills a unit's unpaid dues due on or after from, after
+// its occupants changed.
+func ReassignPayers(tx *sql.Tx, unitID string, from time.Time) error {
+	rows, err := tx.Query(`SELECT id FROM dues
+		WHERE unit_id = $1 AND due_date >= $2 AND status IN ('pending', 'overdue')`, unitID, from)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return assignPayers(tx, ids)
+}
+
+// applyCredits consumes the open credit notes of each due's unit, oldest
+// first, until the due or the credit runs out.
+func applyCredits(tx *sql.Tx, dueIDs []string) error {
+	rows, err := tx.Query(`SELECT d.id, d.organization_id, d.unit_id, d.amount - d.adjustment_total
+		FROM dues d
+		WHERE d.id = ANY($1) AND d.status IN ('pending', 'overdue')
+			AND EXISTS (SELECT 1 FROM credit_notes c WHERE c.unit_id = d.unit_id AND c.remaining > 0)
+		ORDER BY d.due_date`, pq.Array(dueIDs))
+	if err != nil {
+		return err
+	}
+	type openDue struct {
+		id, orgID, unitID string
+		net               int64
+	}
+	var open []openDue
+	for rows.Next() {
+		var d openDue
+		var net float64
+		if err := rows.Scan(&d.id, &d.orgID, &d.unitID, &net); err != nil {
+			rows.Close()
+			return err
+		}
+		d.net = ToKurus(net)
+		open = append(open, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range open {
+		notes, err := tx.Query(`SELECT id, remaining FROM credit_notes
+			WHERE unit_id = $1 AND remaining > 0
+			ORDER BY created_at FOR UPDATE`, d.unitID)
+		if err != nil {
+			return err
+		}
+		type credit struct {
+			id        string
+			remaining int64
+		}
+		var credits []credit
+		for notes.Next() {
+			var c credit
+			var remaining float64
+			if err := notes.Scan(&c.id, &remaining); err != nil {
+				notes.Close()
+				return err
+			}
+			c.remaining = ToKurus(remaining)
+			credits = append(credits, c)
+		}
+		notes.Close()
+		if err := notes.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range credits {
+			if d.net == 0 {
+				break
+			}
+			use := c.remaining
+			if use > d.net {
+				use = d.net
+			}
+			noteID := c.id
+			a := &Adjustment{
+				OrganizationID: d.orgID, DueID: d.id, Kind: AdjustmentCredit, Amount: float64(use) / 100,
+				Reason: "credit note applied", CreditNoteID: &noteID,
+			}
+			if err := addAdjustment(tx, a); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE credit_notes SET remaining = remaining - $1, updated_at = NOW()
+				WHERE id = $2`, a.Amount, c.id); err != nil {
+				return err
+			}
+			d.net -= use
+		}
+		if err := settleIfCovered(tx, d.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const selectAdjustment = `SELECT a.id, a.organization_id, a.due_id, COALESCE(u.unit_number, '') as unit_number,
+		a.kind, a.amount, a.percent, a.reason, a.credit_note_id, a.created_by, a.created_at
+		FROM due_adjustments a
+		JOIN dues d ON a.due_id = d.id
+		LEFT JOIN units u ON d.unit_id = u.id`
+
+var adjustmentList = &listing.Config{
+	Sorts: map[string]string{
+		"created_at": "a.created_at",
+		"amount":     "a.amount",
+	},
+	Default: "-created_at",
+	ID:      "a.id",
+	Search:  []string{"u.unit_number", "a.reason"},
+	Date:    "a.created_at",
+}
+
+func scanAdjustment(row scanner, a *Adjustment) error {
+	return row.Scan(&a.ID, &a.OrganizationID, &a.DueID, &a.UnitNumber,
+		&a.Kind, &a.Amount, &a.Percent, &a.Reason, &a.CreditNoteID, &a.CreatedBy, &a.CreatedAt)
+}
+
+func (r *Repository) ListAdjustmentsByDue(dueID string) ([]Adjustment, error) {
+	rows, err := r.db.Query(selectAdjustment+" WHERE a.due_id = $1 ORDER BY a.created_at", dueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Adjustment
+	for rows.Next() {
+		var a Adjustment
+		if err := scanAdjustment(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+// ListAdjustments lists adjustments of an organization, filtered by the
+// period of the adjusted due so they line up with the monthly summary.
+func (r *Repository) ListAdjustments(filter AdjustmentFilter, p *listing.Params) ([]Adjustment, listing.Result, error) {
+	query := selectAdjustment + " WHERE a.organization_id = $1"
+	args := []interface{}{filter.OrganizationID}
+	argIdx := 2
+
+	if filter.Kind != "" {
+		query += fmt.Sprintf(" AND a.kind = $%d", argIdx)
+		args = append(args, filter.Kind)
+		argIdx++
+	}
+	if filter.Year > 0 {
+		query += fmt.Sprintf(" AND EXTRACT(YEAR FROM d.due_date) = $%d", argIdx)
+		args = append(args, filter.Year)
+		argIdx++
+	}
+	if filter.Month > 0 {
+		query += fmt.Sprintf(" AND EXTRACT(MONTH FROM d.due_date) = $%d", argIdx)
+		args = append(args, filter.Month)
+		argIdx++
+	}
+
+	query, args = p.Filter(query, args)
+	list := []Adjustment{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var a Adjustment
+		if err := scanAdjustment(rows, &a); err != nil {
+			return err
+		}
+		list = append(list, a)
+		return nil
+	})
+	return list, res, err
+}
+
+// CreateCreditNote stores a credit note for a unit of the organization. It
+// is consumed by the unit's dues issued from now on.
+func (r *Repository) CreateCreditNote(c *CreditNote) error {
+	query := `
+		INSERT INTO credit_notes (organization_id, unit_id, amount, remaining, reason, created_by)
+		SELECT $1, u.id, $3, $3, $4, $5 FROM units u WHERE u.id = $2 AND u.organization_id = $1
+		RETURNING id, remaining, created_at, updated_at`
+	err := r.db.QueryRow(query, c.OrganizationID, c.UnitID, c.Amount, c.Reason, c.CreatedBy).
+		Scan(&c.ID, &c.Remaining, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unit not found")
+	}
+	return err
+}
+
+var creditNoteList = &listing.Config{
+	Sorts: map[string]string{
+		"created_at": "c.created_at",
+		"amount":     "c.amount",
+		"remaining":  "c.remaining",
+	},
+	Default: "-created_at",
+	ID:      "c.id",
+	Search:  []string{"u.unit_number", "c.reason"},
+	Date:    "c.created_at",
+}
+
+func (r *Repository) ListCreditNotes(orgID, unitID string, p *listing.Params) ([]CreditNote, listing.Result, error) {
+	query := `SELECT c.id, c.organization_id, c.unit_id, COALESCE(u.unit_number, ''),
+		c.amount, c.remaining, c.reason, c.created_by, c.created_at, c.updated_at
+		FROM credit_notes c
+		LEFT JOIN units u ON c.unit_id = u.id
+		WHERE c.organization_id = $1 AND ($2 = '' OR c.unit_id::text = $2)`
+
+	query, args := p.Filter(query, []interface{}{orgID, unitID})
+	notes := []CreditNote{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var c CreditNote
+		if err := rows.Scan(&c.ID, &c.OrganizationID, &c.UnitID, &c.UnitNumber,
+			&c.Amount, &c.Remaining, &c.Reason, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return err
+		}
+		notes = append(notes, c)
+		return nil
+	})
+	return notes, res, err
+}
+
+const selectOffer = `SELECT id, organization_id, title, type, months, discount_percent, valid_until, active,
+		created_at, updated_at
+		FROM prepayment_offers`
+
+func scanOffer(row scanner, o *Offer) error {
+	return row.Scan(&o.ID, &o.OrganizationID, &o.Title, &o.Type, &o.Months, &o.DiscountPercent,
+		&o.ValidUntil, &o.Active, &o.CreatedAt, &o.UpdatedAt)
+}
+
+func (r *Repository) CreateOffer(o *Offer) error {
+	query := `
+		INSERT INTO prepayment_offers (organization_id, title, type, months, discount_percent, valid_until, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(query,
+		o.OrganizationID, o.Title, o.Type, o.Months, o.DiscountPercent, o.ValidUntil, o.Active,
+	).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt)
+}
+
+func (r *Repository) GetOffer(id string) (*Offer, error) {
+	o := &Offer{}
+	if err := scanOffer(r.db.QueryRow(selectOffer+" WHERE id = $1", id), o); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("offer not found")
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+var offerList = &listing.Config{
+	Sorts: map[string]string{
+		"valid_until": "valid_until",
+		"created_at":  "created_at",
+		"title":       "title",
+	},
+	Default: "-valid_until",
+	ID:      "id",
+	Search:  []string{"title"},
+	Date:    "valid_until",
+}
+
+func (r *Repository) ListOffers(orgID string, p *listing.Params) ([]Offer, listing.Result, error) {
+	query, args := p.Filter(selectOffer+" WHERE organization_id = $1", []interface{}{orgID})
+	offers := []Offer{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var o Offer
+		if err := scanOffer(rows, &o); err != nil {
+			return err
+		}
+		offers = append(offers, o)
+		return nil
+	})
+	return offers, res, err
+}
+
+func (r *Repository) UpdateOffer(o *Offer) error {
+	query := `UPDATE prepayment_offers SET title=$1, discount_percent=$2, valid_until=$3, active=$4, updated_at=NOW()
+		WHERE id=$5 RETURNING updated_at`
+	return r.db.QueryRow(query, o.Title, o.DiscountPercent, o.ValidUntil, o.Active, o.ID).Scan(&o.UpdatedAt)
+}
+
+// Prepay settles the given months of a unit under an offer in one
+// transaction. Existing unpaid dues of a month are discounted and paid;
+// months without a due get one at monthlyAmount first.
+func (r *Repository) Prepay(o *Offer, unitID string, months []time.Time, monthlyAmount float64, method, accountID string, createdBy *string) (*PrepayResult, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the unit serializes prepayments of the unit, so two at once
+	// cannot both find a month unbilled and bill it twice.
+	err = tx.QueryRow(`SELECT id FROM units WHERE id = $1 AND organization_id = $2 FOR UPDATE`,
+		unitID, o.OrganizationID).Scan(&unitID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("unit not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reason := fmt.Sprintf("%s (%%%.0f peşin ödeme indirimi)", o.Title, o.DiscountPercent)
+	var ids, created []string
+	for _, day := range months {
+		var id, status string
+		err := tx.QueryRow(`SELECT id, status FROM dues
+			WHERE unit_id = $1 AND type = $2 AND status IN ('pending', 'overdue', 'paid')
+				AND date_trunc('month', due_date) = date_trunc('month', $3::date)
+			ORDER BY due_date LIMIT 1 FOR UPDATE`, unitID, o.Type, day).Scan(&id, &status)
+		switch {
+		case err == sql.ErrNoRows:
+			desc := fmt.Sprintf("%s %s (peşin)", o.Title, day.Format("01/2006"))
+			if err := tx.QueryRow(`INSERT INTO dues (organization_id, unit_id, type, amount, due_date, status, description)
+				VALUES ($1, $2, $3, $4, $5, 'pending', $6) RETURNING id`,
+				o.OrganizationID, unitID, o.Type, monthlyAmount, day, desc).Scan(&id); err != nil {
+				return nil, err
+			}
+			created = append(created, id)
+		case err != nil:
+			return nil, err
+		case status == "paid":
+			return nil, fmt.Errorf("dues for %s are already paid", day.Format("01/2006"))
+		}
+
+		if _, err := DiscountTx(tx, id, o.DiscountPercent, 0, reason, createdBy); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := assignPayers(tx, created); err != nil {
+		return nil, err
+	}
+	if err := applyCredits(tx, ids); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		var status string
+		if err := tx.QueryRow("SELECT status FROM dues WHERE id = $1", id).Scan(&status); err != nil {
+			return nil, err
+		}
+		if status == "paid" {
+			continue // fully covered by discount and credits
+		}
+		if err := MarkPaidTx(tx, id, method, accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &PrepayResult{OfferID: o.ID, UnitID: unitID}
+	err = tx.QueryRow(`SELECT (SELECT COALESCE(SUM(amount), 0) FROM dues WHERE id = ANY($1)),
+			COALESCE(SUM(amount) FILTER (WHERE kind = 'discount'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE kind = 'credit'), 0)
+		FROM due_adjustments WHERE due_id = ANY($1)`, pq.Array(ids)).Scan(&result.Gross, &result.Discount, &result.Credits)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		d, err := r.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		result.Dues = append(result.Dues, *d)
+	}
+	result.Paid = result.Gross - result.Discount - result.Credits
+	return result, nil
+}
+
+// MonthlyAmount is what a unit is billed per month for a dues type: the
+// organization's monthly due for aidat, otherwise the last amount issued.
+func (r *Repository) MonthlyAmount(orgID, unitID, dueType string) (float64, error) {
+	var amount float64
+	var err error
+	if dueType == TypeAidat {
+		err = r.db.QueryRow("SELECT monthly_due_amount FROM organizations WHERE id = $1", orgID).Scan(&amount)
+	} else {
+		err = r.db.QueryRow(`SELECT amount FROM dues WHERE unit_id = $1 AND type = $2
+			ORDER BY due_date DESC LIMIT 1`, unitID, dueType).Scan(&amount)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// reminderTarget is an unpaid due with the contact details of its payer.
+type reminderTarget struct {
+	DueID      string
+	UnitNumber string
+	Name       string
+	Phone      string
+	Amount     float64
+	DueDate    time.Time
+}
+
+// ListReminderTargets returns the overdue dues of an organization (or one
+// due when dueID is set) with the phone of the person they are billed to,
+// falling back to the unit's resident.
+func (r *Repository) ListReminderTargets(orgID, dueID string) ([]reminderTarget, error) {
+	query := `SELECT d.id, COALESCE(u.unit_number, ''),
+		COALESCE(payer.full_name, res.full_name, ''), COALESCE(payer.phone, res.phone, ''),
+		d.amount - d.adjustment_total, d.due_date
+		FROM dues d
+		LEFT JOIN units u ON d.unit_id = u.id
+		LEFT JOIN residents res ON u.resident_id = res.id
+		LEFT JOIN residents payer ON d.payer_resident_id = payer.id
+		WHERE d.organization_id = $1`
+	args := []interface{}{orgID}
+	if dueID != "" {
+		query += " AND d.id = $2 AND d.status IN ('pending', 'overdue')"
+		args = append(args, dueID)
+	} else {
+		query += " AND d.status = 'overdue'"
+	}
+	query += " ORDER BY u.unit_number, d.due_date"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []reminderTarget
+	for rows.Next() {
+		var t reminderTarget
+		if err := rows.Scan(&t.DueID, &t.UnitNumber, &t.Name, &t.Phone, &t.Amount, &t.DueDate); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
 }
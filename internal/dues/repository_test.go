@@ -0,0 +1,102 @@
+package dues
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+)
+
+// testDB connects to TEST_DATABASE_URL and brings its schema up to date.
+// The tests are skipped when it is not set; they create their own
+// organization and remove it when done, so a shared database is fine.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := database.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	t.Chdir("../..") // Migrate reads the migrations directory of the repo root
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testUnit creates an organization with one unit and a monthly due of
+// 1000, and returns their IDs.
+func testUnit(t *testing.T, db *sql.DB) (orgID, unitID string) {
+	t.Helper()
+	var userID string
+	err := db.QueryRow(`INSERT INTO users (email, phone, password_hash, full_name)
+		VALUES (uuid_generate_v4() || '@example.com', '', '', 'Test') RETURNING id`).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`INSERT INTO organizations (name, monthly_due_amount, manager_id)
+		VALUES ('Test Sitesi', 1000, $1) RETURNING id`, userID).Scan(&orgID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM organizations WHERE id = $1", orgID)
+		db.Exec("DELETE FROM users WHERE id = $1", userID)
+	})
+	if err := db.QueryRow(`INSERT INTO units (organization_id, unit_number) VALUES ($1, '1') RETURNING id`,
+		orgID).Scan(&unitID); err != nil {
+		t.Fatal(err)
+	}
+	return orgID, unitID
+}
+
+func TestBulkCreateSkipsPrepaidMonths(t *testing.T) {
+	db := testDB(t)
+	orgID, unitID := testUnit(t, db)
+	var otherID string
+	if err := db.QueryRow(`INSERT INTO units (organization_id, unit_number) VALUES ($1, '2') RETURNING id`,
+		orgID).Scan(&otherID); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(db)
+
+	o := &Offer{OrganizationID: orgID, Title: "Yıllık peşin", Type: TypeAidat, Months: 3,
+		DiscountPercent: 10, ValidUntil: time.Now().AddDate(0, 1, 0), Active: true}
+	if err := repo.CreateOffer(o); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	months := []time.Time{start, AddMonths(start, 1), AddMonths(start, 2)}
+	if _, err := repo.Prepay(o, unitID, months, 1000, "cash", "", nil); err != nil {
+		t.Fatalf("Prepay: %v", err)
+	}
+
+	created, err := repo.BulkCreate(orgID, "", TypeAidat, 1000, start.AddDate(0, 1, 14), "Şubat aidatı")
+	if err != nil {
+		t.Fatalf("BulkCreate: %v", err)
+	}
+	if created != 1 {
+		t.Fatalf("BulkCreate created %d dues, want 1 for the unit that did not prepay", created)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM dues
+		WHERE unit_id = $1 AND type = $2 AND date_trunc('month', due_date) = '2030-02-01'`,
+		unitID, TypeAidat).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("prepaid unit has %d aidat dues for February, want 1", count)
+	}
+
+	// Another type is still billed in a prepaid month.
+	if created, err := repo.BulkCreate(orgID, "", TypeHeating, 400, start.AddDate(0, 1, 14), ""); err != nil || created != 2 {
+		t.Fatalf("BulkCreate(heating) = %d, %v, want 2", created, err)
+	}
+}
@@ -8,7 +8,28 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 		r.Post("/bulk", h.BulkCreate)
 		r.Get("/", h.List)
 		r.Get("/overdue", h.GetOverdue)
+		r.Get("/aging", h.Aging)
+		r.Get("/adjustments", h.ListAdjustments)
+		r.Post("/reminders", h.SendReminders)
 		r.Get("/{id}", h.Get)
 		r.Patch("/{id}/pay", h.MarkPaid)
+		r.Get("/{id}/receipt", h.Receipt)
+		r.Post("/{id}/void", h.Void)
+		r.Post("/{id}/discount", h.Discount)
+		r.Get("/{id}/adjustments", h.ListDueAdjustments)
+		r.Get("/{id}/reminder", h.ReminderLink)
+		r.Post("/{id}/proofs", h.UploadProof)
+		r.Get("/{id}/proofs", h.ListProofs)
+		r.Delete("/{id}/proofs/{fileId}", h.DeleteProof)
+	})
+	r.Route("/organizations/{orgId}/credit-notes", func(r chi.Router) {
+		r.Post("/", h.CreateCreditNote)
+		r.Get("/", h.ListCreditNotes)
+	})
+	r.Route("/organizations/{orgId}/prepayment-offers", func(r chi.Router) {
+		r.Post("/", h.CreateOffer)
+		r.Get("/", h.ListOffers)
+		r.Put("/{id}", h.UpdateOffer)
+		r.Post("/{id}/accept", h.Prepay)
 	})
 }
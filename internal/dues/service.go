@@ -2,15 +2,23 @@ package dues
 
 import (
 	"fmt"
+	"mime/multipart"
 	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+	"github.com/mustafakemalcelik/sitetakip/internal/notification"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
 )
 
 type Service struct {
-	repo *Repository
+	repo          *Repository
+	attachments   *attachment.Service
+	notifications *notification.Service
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, attachments *attachment.Service, notifications *notification.Service) *Service {
+	return &Service{repo: repo, attachments: attachments, notifications: notifications}
 }
 
 func (s *Service) Create(orgID string, req CreateRequest) (*Due, error) {
@@ -23,9 +31,17 @@ func (s *Service) Create(orgID string, req CreateRequest) (*Due, error) {
 		return nil, fmt.Errorf("invalid due_date format, use YYYY-MM-DD")
 	}
 
+	if req.Type == "" {
+		req.Type = TypeAidat
+	}
+	if !ValidType(req.Type) {
+		return nil, fmt.Errorf("invalid type %q", req.Type)
+	}
+
 	d := &Due{
 		OrganizationID: orgID,
 		UnitID:         req.UnitID,
+		Type:           req.Type,
 		Amount:         req.Amount,
 		DueDate:        dueDate,
 		Status:         "pending",
@@ -48,28 +64,377 @@ func (s *Service) BulkCreate(orgID string, req BulkCreateRequest) (int, error) {
 		return 0, fmt.Errorf("invalid due_date format, use YYYY-MM-DD")
 	}
 
-	return s.repo.BulkCreate(orgID, req.Amount, dueDate, req.Description)
+	if req.Type == "" {
+		req.Type = TypeAidat
+	}
+	if !ValidType(req.Type) {
+		return 0, fmt.Errorf("invalid type %q", req.Type)
+	}
+
+	return s.repo.BulkCreate(orgID, req.BlockID, req.Type, req.Amount, dueDate, req.Description)
 }
 
 func (s *Service) GetByID(id string) (*Due, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *Service) List(filter ListFilter) ([]Due, error) {
-	return s.repo.List(filter)
+// Receipt renders the receipt of a paid due of the organization.
+func (s *Service) Receipt(orgID, dueID string) (*Receipt, *pdf.Document, error) {
+	d, err := s.repo.GetByID(dueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.OrganizationID != orgID {
+		return nil, nil, ErrNotFound
+	}
+	rc, err := s.repo.GetReceiptByDue(dueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	name, address, err := s.repo.OrganizationHeader(orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, receiptPDF(name, address, rc, d), nil
+}
+
+func (s *Service) List(filter ListFilter, p *listing.Params) ([]Due, listing.Result, error) {
+	return s.repo.List(filter, p)
+}
+
+func (s *Service) Each(filter ListFilter, p *listing.Params, fn func(*Due) error) error {
+	return s.repo.Each(filter, p, fn)
 }
 
-func (s *Service) MarkPaid(id string, method string) error {
-	if method == "" {
-		method = "cash"
+func (s *Service) MarkPaid(id string, req MarkPaidRequest) error {
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = "cash"
 	}
-	return s.repo.MarkPaid(id, method)
+	return s.repo.MarkPaid(id, req.PaymentMethod, req.AccountID)
 }
 
 func (s *Service) MarkOverdue() (int, error) {
 	return s.repo.MarkOverdue()
 }
 
-func (s *Service) GetOverdue(orgID string) ([]Due, error) {
-	return s.repo.GetOverdue(orgID)
+// Aging returns the debtor units with their debt split by lateness.
+// Without a sort key the largest debts come first.
+func (s *Service) Aging(filter AgingFilter) (*AgingReport, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repo.Aging(filter)
+	if err != nil {
+		return nil, err
+	}
+	report := &AgingReport{AsOf: time.Now().UTC().Truncate(24 * time.Hour), Units: len(rows), Rows: rows}
+	t := &report.Totals
+	for _, a := range rows {
+		t.Current += a.Current
+		t.Days1To30 += a.Days1To30
+		t.Days31To60 += a.Days31To60
+		t.Days61To90 += a.Days61To90
+		t.Over90 += a.Over90
+		t.Overdue += a.Overdue
+		t.Total += a.Total
+		t.DueCount += a.DueCount
+		if t.OldestDue.IsZero() || a.OldestDue.Before(t.OldestDue) {
+			t.OldestDue, t.DaysOverdue = a.OldestDue, a.DaysOverdue
+		}
+	}
+	return report, nil
+}
+
+// validate defaults the sort to the largest debt first and rejects unknown
+// sorts and negative thresholds.
+func (f *AgingFilter) validate() error {
+	if f.Sort == "" {
+		f.Sort, f.Desc = "total", true
+	}
+	if _, ok := agingSorts[f.Sort]; !ok {
+		return fmt.Errorf("invalid sort, use one of: total, overdue, days, unit, last_payment")
+	}
+	if f.MinOverdue < 0 || f.MinDays < 0 {
+		return fmt.Errorf("thresholds cannot be negative")
+	}
+	return nil
+}
+
+// Void cancels a wrongly created due. The due row stays for the audit trail
+// and the cancellation is recorded as an adjustment.
+func (s *Service) Void(orgID, id, userID string, req VoidRequest) error {
+	if req.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if err := s.checkOrganization(orgID, id); err != nil {
+		return err
+	}
+	return s.repo.Void(id, req.Reason, Optional(userID))
+}
+
+// Discount grants a percentage or fixed discount on a due.
+func (s *Service) Discount(orgID, id, userID string, req DiscountRequest) (*Adjustment, error) {
+	if req.Reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+	if (req.Percent > 0) == (req.Amount > 0) {
+		return nil, fmt.Errorf("either percent or amount is required")
+	}
+	if req.Percent < 0 || req.Percent > 100 || req.Amount < 0 {
+		return nil, fmt.Errorf("percent must be between 0 and 100 and amount positive")
+	}
+	if err := s.checkOrganization(orgID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Discount(id, req.Percent, req.Amount, req.Reason, Optional(userID))
+}
+
+func (s *Service) ListDueAdjustments(id string) ([]Adjustment, error) {
+	return s.repo.ListAdjustmentsByDue(id)
+}
+
+func (s *Service) ListAdjustments(filter AdjustmentFilter, p *listing.Params) ([]Adjustment, listing.Result, error) {
+	return s.repo.ListAdjustments(filter, p)
+}
+
+// CreateCreditNote records money owed to a unit; it is applied to the
+// unit's future dues automatically.
+func (s *Service) CreateCreditNote(orgID, userID string, req CreditNoteRequest) (*CreditNote, error) {
+	if req.UnitID == "" || req.Amount <= 0 || req.Reason == "" {
+		return nil, fmt.Errorf("unit_id, amount, and reason are required")
+	}
+
+	c := &CreditNote{
+		OrganizationID: orgID,
+		UnitID:         req.UnitID,
+		Amount:         req.Amount,
+		Reason:         req.Reason,
+		CreatedBy:      Optional(userID),
+	}
+	if err := s.repo.CreateCreditNote(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) ListCreditNotes(orgID, unitID string, p *listing.Params) ([]CreditNote, listing.Result, error) {
+	return s.repo.ListCreditNotes(orgID, unitID, p)
+}
+
+func (s *Service) CreateOffer(orgID string, req CreateOfferRequest) (*Offer, error) {
+	if req.Title == "" || req.Months <= 0 || req.ValidUntil == "" {
+		return nil, fmt.Errorf("title, months, and valid_until are required")
+	}
+	if req.Months > MaxOfferMonths {
+		return nil, fmt.Errorf("months can be at most %d", MaxOfferMonths)
+	}
+	if req.DiscountPercent <= 0 || req.DiscountPercent >= 100 {
+		return nil, fmt.Errorf("discount_percent must be between 0 and 100")
+	}
+	if req.Type == "" {
+		req.Type = TypeAidat
+	}
+	if !ValidType(req.Type) {
+		return nil, fmt.Errorf("invalid type %q", req.Type)
+	}
+	validUntil, err := time.Parse("2006-01-02", req.ValidUntil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid valid_until format, use YYYY-MM-DD")
+	}
+
+	o := &Offer{
+		OrganizationID:  orgID,
+		Title:           req.Title,
+		Type:            req.Type,
+		Months:          req.Months,
+		DiscountPercent: req.DiscountPercent,
+		ValidUntil:      validUntil,
+		Active:          true,
+	}
+	if err := s.repo.CreateOffer(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *Service) ListOffers(orgID string, p *listing.Params) ([]Offer, listing.Result, error) {
+	return s.repo.ListOffers(orgID, p)
+}
+
+func (s *Service) UpdateOffer(orgID, id string, req UpdateOfferRequest) (*Offer, error) {
+	o, err := s.repo.GetOffer(id)
+	if err != nil {
+		return nil, err
+	}
+	if o.OrganizationID != orgID {
+		return nil, fmt.Errorf("offer not found")
+	}
+	if req.Title != nil {
+		o.Title = *req.Title
+	}
+	if req.DiscountPercent != nil {
+		if *req.DiscountPercent <= 0 || *req.DiscountPercent >= 100 {
+			return nil, fmt.Errorf("discount_percent must be between 0 and 100")
+		}
+		o.DiscountPercent = *req.DiscountPercent
+	}
+	if req.ValidUntil != nil {
+		if o.ValidUntil, err = time.Parse("2006-01-02", *req.ValidUntil); err != nil {
+			return nil, fmt.Errorf("invalid valid_until format, use YYYY-MM-DD")
+		}
+	}
+	if req.Active != nil {
+		o.Active = *req.Active
+	}
+	if err := s.repo.UpdateOffer(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Prepay accepts an offer for a unit: the covered months are billed if
+// needed, discounted and paid in one transaction.
+func (s *Service) Prepay(orgID, offerID, userID string, req PrepayRequest) (*PrepayResult, error) {
+	if req.UnitID == "" {
+		return nil, fmt.Errorf("unit_id is required")
+	}
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = "cash"
+	}
+
+	o, err := s.repo.GetOffer(offerID)
+	if err != nil {
+		return nil, err
+	}
+	if o.OrganizationID != orgID {
+		return nil, fmt.Errorf("offer not found")
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !o.Active || today.After(o.ValidUntil) {
+		return nil, fmt.Errorf("offer is no longer valid")
+	}
+	if o.Months <= 0 || o.Months > MaxOfferMonths {
+		return nil, fmt.Errorf("offer covers %d months, at most %d can be prepaid", o.Months, MaxOfferMonths)
+	}
+
+	start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if req.StartMonth != "" {
+		if start, err = time.Parse("2006-01", req.StartMonth); err != nil {
+			return nil, fmt.Errorf("invalid start_month format, use YYYY-MM")
+		}
+	}
+	if req.DueDay == 0 {
+		req.DueDay = 1
+	}
+	if req.DueDay < 1 || req.DueDay > 28 {
+		return nil, fmt.Errorf("due_day must be between 1 and 28")
+	}
+
+	months := make([]time.Time, o.Months)
+	for i := range months {
+		months[i] = AddMonths(start, i).AddDate(0, 0, req.DueDay-1)
+	}
+
+	amount, err := s.repo.MonthlyAmount(orgID, req.UnitID, o.Type)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("no monthly amount is set for %s dues", o.Type)
+	}
+	return s.repo.Prepay(o, req.UnitID, months, amount, req.PaymentMethod, req.AccountID, Optional(userID))
+}
+
+// SendReminders texts the payer of every overdue due of the organization.
+// Dues whose payer has no phone number are skipped.
+func (s *Service) SendReminders(orgID string) (*ReminderResult, error) {
+	targets, err := s.repo.ListReminderTargets(orgID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReminderResult{}
+	for _, t := range targets {
+		if t.Phone == "" {
+			result.Skipped++
+			continue
+		}
+		msg := s.notifications.FormatDueReminder(t.Name, t.UnitNumber, t.Amount, t.DueDate.Format("02.01.2006"))
+		if err := s.notifications.SendSMS(t.Phone, msg); err != nil {
+			return result, err
+		}
+		result.Sent++
+	}
+	return result, nil
+}
+
+// ReminderLink returns a WhatsApp link with a reminder for the payer of a
+// single unpaid due.
+func (s *Service) ReminderLink(orgID, id string) (string, error) {
+	targets, err := s.repo.ListReminderTargets(orgID, id)
+	if err != nil {
+		return "", err
+	}
+	if len(targets) == 0 {
+		return "", fmt.Errorf("due not found or not unpaid")
+	}
+	t := targets[0]
+	if t.Phone == "" {
+		return "", fmt.Errorf("payer has no phone number")
+	}
+	msg := s.notifications.FormatDueReminder(t.Name, t.UnitNumber, t.Amount, t.DueDate.Format("02.01.2006"))
+	return s.notifications.GenerateWhatsAppLink(t.Phone, msg), nil
+}
+
+func (s *Service) checkOrganization(orgID, id string) error {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if d.OrganizationID != orgID {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Optional returns nil for an empty string, for nullable ID columns.
+func Optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// AddProof stores a payment proof (dekont, bank transfer receipt) for a due.
+func (s *Service) AddProof(orgID, id string, file multipart.File, header *multipart.FileHeader) (*attachment.Attachment, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if d.OrganizationID != orgID {
+		return nil, ErrNotFound
+	}
+	return s.attachments.Upload(orgID, attachment.OwnerDue, id, file, header)
+}
+
+func (s *Service) ListProofs(orgID, id string) ([]attachment.Attachment, error) {
+	if err := s.checkOrganization(orgID, id); err != nil {
+		return nil, err
+	}
+	return s.attachments.ListByOwner(attachment.OwnerDue, id)
+}
+
+func (s *Service) DeleteProof(orgID, id, fileID string) error {
+	if err := s.checkOrganization(orgID, id); err != nil {
+		return err
+	}
+	a, err := s.attachments.GetByID(fileID)
+	if err != nil {
+		return err
+	}
+	if a.OwnerType != attachment.OwnerDue || a.OwnerID != id {
+		return fmt.Errorf("attachment not found")
+	}
+	return s.attachments.Delete(fileID)
 }
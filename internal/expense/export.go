@@ -0,0 +1,67 @@
+package expense
+
+import (
+	"fmt"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+)
+
+// CategoryLabels are the Turkish names of the usual expense categories.
+var CategoryLabels = map[string]string{
+	"maintenance": "Bakım-onarım",
+	"cleaning":    "Temizlik",
+	"electricity": "Elektrik",
+	"water":       "Su",
+	"elevator":    "Asansör",
+	"other":       "Diğer",
+}
+
+var statusLabels = map[string]string{
+	StatusDraft:     "Taslak",
+	StatusSubmitted: "Onay bekliyor",
+	StatusApproved:  "Onaylandı",
+	StatusRejected:  "Reddedildi",
+	StatusPaid:      "Ödendi",
+}
+
+var exportColumns = []export.Column{
+	{Title: "Tarih", Width: 12},
+	{Title: "Blok", Width: 10},
+	{Title: "Kategori", Width: 16},
+	{Title: "Fon", Width: 14},
+	{Title: "Açıklama", Width: 36},
+	{Title: "Tedarikçi", Width: 24},
+	{Title: "Tutar", Width: 12},
+	{Title: "Durum", Width: 14},
+	{Title: "Vade", Width: 12},
+	{Title: "Ödeme Tarihi", Width: 12},
+	{Title: "Fiş No", Width: 18},
+}
+
+func exportName(filter ListFilter) string {
+	switch {
+	case filter.Year > 0 && filter.Month > 0:
+		return fmt.Sprintf("giderler-%d-%02d", filter.Year, filter.Month)
+	case filter.Year > 0:
+		return fmt.Sprintf("giderler-%d", filter.Year)
+	default:
+		return "giderler"
+	}
+}
+
+func exportRow(add export.AddFunc, e *Expense) error {
+	return add(
+		e.Date,
+		e.BlockName,
+		export.Label(CategoryLabels, e.Category),
+		export.Label(dues.TypeLabels, e.Fund),
+		e.Description,
+		e.VendorName,
+		e.Amount,
+		export.Label(statusLabels, e.Status),
+		e.DueDate,
+		e.PaidAt,
+		e.VoucherNo,
+	)
+}
@@ -2,10 +2,15 @@ package expense
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
 
@@ -25,7 +30,8 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	e, err := h.service.Create(orgID, req)
+	userID := middleware.GetUserID(r.Context())
+	e, err := h.service.Create(orgID, userID, req)
 	if err != nil {
 		response.Error(w, http.StatusBadRequest, err.Error())
 		return
@@ -36,16 +42,36 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	orgID := chi.URLParam(r, "orgId")
-	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
-	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
 
-	expenses, err := h.service.ListByOrganization(orgID, year, month)
+	filter := ListFilter{
+		OrganizationID: orgID,
+		Status:         r.URL.Query().Get("status"),
+		Fund:           r.URL.Query().Get("fund"),
+		BlockID:        r.URL.Query().Get("block_id"),
+	}
+	filter.Year, _ = strconv.Atoi(r.URL.Query().Get("year"))
+	filter.Month, _ = strconv.Atoi(r.URL.Query().Get("month"))
+
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if format := export.Format(r); format != "" {
+		export.Stream(w, format, exportName(filter), exportColumns, func(add export.AddFunc) error {
+			return h.service.Each(filter, p, func(e *Expense) error { return exportRow(add, e) })
+		})
+		return
+	}
+
+	expenses, res, err := h.service.ListByOrganization(filter, p)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, expenses)
+	response.WithMeta(w, http.StatusOK, expenses, p.Meta(res, expenses))
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -59,12 +85,228 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, e)
 }
 
+func (h *Handler) Submit(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	count, err := h.service.Submit(orgID, req.IDs)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]int{"submitted": count})
+}
+
+func (h *Handler) SubmitOne(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+
+	if _, err := h.service.Submit(orgID, []string{id}); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "submitted"})
+}
+
+func (h *Handler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Approve)
+}
+
+func (h *Handler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.Reject)
+}
+
+func (h *Handler) decide(w http.ResponseWriter, r *http.Request, decide func(orgID, id, userID, comment string) (string, error)) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req DecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	ok, err := h.service.CanVote(orgID, userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		response.Error(w, http.StatusForbidden, "only the manager and board members of the organization can approve or reject expenses")
+		return
+	}
+
+	status, err := decide(orgID, id, userID, req.Comment)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+func (h *Handler) AddComment(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	c, err := h.service.AddComment(orgID, id, userID, req.Body)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, c)
+}
+
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	rules, err := h.service.ListRules(orgID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rules)
+}
+
+func (h *Handler) SetRule(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req ApprovalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule, err := h.service.SetRule(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, rule)
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	if err := h.service.DeleteRule(orgID, id); err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+func (h *Handler) MarkPaid(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	var req MarkPaidRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.service.MarkPaid(orgID, id, req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "marked as paid"})
+}
+
+func (h *Handler) Payables(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 {
+		days = 7
+	}
+
+	payables, err := h.service.GetPayables(orgID, days)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, payables)
+}
+
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
 	id := chi.URLParam(r, "id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(orgID, id); errors.Is(err, ErrHasVoucher) {
+		response.Error(w, http.StatusConflict, err.Error())
+		return
+	} else if errors.Is(err, ErrNotFound) {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
+
+func (h *Handler) UploadReceipt(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+
+	file, header, err := attachment.FormFile(w, r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	a, err := h.service.AddReceipt(orgID, id, file, header)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, a)
+}
+
+func (h *Handler) ListReceipts(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	receipts, err := h.service.ListReceipts(orgID, id)
+	if errors.Is(err, ErrNotFound) {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, receipts)
+}
+
+func (h *Handler) DeleteReceipt(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	id := chi.URLParam(r, "id")
+	fileID := chi.URLParam(r, "fileId")
+	if err := h.service.DeleteReceipt(orgID, id, fileID); err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
@@ -1,23 +1,136 @@
 package expense
 
-import "time"
+import (
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusSubmitted = "submitted"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusPaid      = "paid"
+)
 
 type Expense struct {
-	ID             string    `json:"id"`
-	OrganizationID string    `json:"organization_id"`
-	Category       string    `json:"category"` // maintenance, cleaning, electricity, water, elevator, other
-	Amount         float64   `json:"amount"`
-	Date           time.Time `json:"date"`
-	Description    string    `json:"description"`
-	ReceiptURL     string    `json:"receipt_url,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                string     `json:"id"`
+	OrganizationID    string     `json:"organization_id"`
+	BlockID           *string    `json:"block_id,omitempty"` // set for expenses of one block; site-wide otherwise
+	BlockName         string     `json:"block_name,omitempty"`
+	Category          string     `json:"category"` // maintenance, cleaning, electricity, water, elevator, other
+	Fund              string     `json:"fund"`     // dues type the expense is charged against, see dues.Types
+	Amount            float64    `json:"amount"`
+	Date              time.Time  `json:"date"`
+	Description       string     `json:"description"`
+	ReceiptURL        string     `json:"receipt_url,omitempty"`
+	VendorID          *string    `json:"vendor_id,omitempty"`
+	VendorName        string     `json:"vendor_name,omitempty"`
+	DueDate           *time.Time `json:"due_date,omitempty"`
+	Status            string     `json:"status"` // draft, submitted, approved, rejected, paid
+	RequiredApprovals int        `json:"required_approvals"`
+	CreatedBy         *string    `json:"created_by,omitempty"`
+	SubmittedAt       *time.Time `json:"submitted_at,omitempty"`
+	PaidAt            *time.Time `json:"paid_at,omitempty"`
+	AccountID         *string    `json:"account_id,omitempty"` // account the expense was paid from
+	VoucherNo         string     `json:"voucher_no,omitempty"` // payment voucher (tediye fişi) number, set when paid
+	RecurringID       *string    `json:"recurring_expense_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Receipts  []attachment.Attachment `json:"receipts,omitempty"`
+	Approvals []Approval              `json:"approvals,omitempty"`
+	Comments  []Comment               `json:"comments,omitempty"`
 }
 
 type CreateRequest struct {
+	BlockID     string  `json:"block_id,omitempty"` // charges the expense to one block
 	Category    string  `json:"category"`
+	Fund        string  `json:"fund,omitempty"` // defaults to aidat
 	Amount      float64 `json:"amount"`
 	Date        string  `json:"date"` // YYYY-MM-DD
 	Description string  `json:"description"`
 	ReceiptURL  string  `json:"receipt_url,omitempty"`
+	VendorID    string  `json:"vendor_id,omitempty"`
+	DueDate     string  `json:"due_date,omitempty"` // YYYY-MM-DD, invoice payment deadline
+	// Draft keeps the expense editable instead of submitting it right away.
+	Draft bool `json:"draft,omitempty"`
+	// Unpaid marks an invoice that still has to be paid. Otherwise an
+	// expense that needs no approval is recorded as already paid.
+	Unpaid bool `json:"unpaid,omitempty"`
+	// AccountID is the account an immediately paid expense is paid from;
+	// the default cash box is used when empty.
+	AccountID string `json:"account_id,omitempty"`
+}
+
+type MarkPaidRequest struct {
+	AccountID string `json:"account_id,omitempty"`
+}
+
+type SubmitRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type DecisionRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+type CommentRequest struct {
+	Body string `json:"body"`
+}
+
+// Approval is one board member's vote on a submitted expense.
+type Approval struct {
+	ID        string    `json:"id"`
+	ExpenseID string    `json:"expense_id"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Decision  string    `json:"decision"` // approve, reject
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Comment struct {
+	ID        string    `json:"id"`
+	ExpenseID string    `json:"expense_id"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ApprovalRule requires RequiredApprovals approvals for expenses above
+// Threshold. An empty Category applies to every category without its own rule.
+type ApprovalRule struct {
+	ID                string    `json:"id"`
+	OrganizationID    string    `json:"organization_id"`
+	Category          string    `json:"category"`
+	Threshold         float64   `json:"threshold"`
+	RequiredApprovals int       `json:"required_approvals"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type ApprovalRuleRequest struct {
+	Category          string  `json:"category"`
+	Threshold         float64 `json:"threshold"`
+	RequiredApprovals int     `json:"required_approvals"`
+}
+
+type ListFilter struct {
+	OrganizationID string
+	Status         string
+	Fund           string
+	BlockID        string
+	Year           int
+	Month          int
+}
+
+// Payables summarizes what the building owes its vendors.
+type Payables struct {
+	TotalUnpaid float64   `json:"total_unpaid"`
+	OverdueSum  float64   `json:"overdue_sum"`
+	DueSoonSum  float64   `json:"due_soon_sum"` // due within the requested window
+	Items       []Expense `json:"items"`
 }
@@ -2,7 +2,17 @@ package expense
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/account"
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/numbering"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/internal/vendors"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Repository struct {
@@ -13,57 +23,209 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// ErrNotFound is returned when no expense has the given ID, or it belongs to
+// another organization.
+var ErrNotFound = errors.New("expense not found")
+
+const selectExpense = `SELECT e.id, e.organization_id, e.block_id, COALESCE(b.name, '') as block_name, e.category, e.fund, e.amount, e.date, e.description,
+		COALESCE(e.receipt_url, '') as receipt_url, e.vendor_id, COALESCE(v.name, '') as vendor_name,
+		e.due_date, e.status, e.required_approvals, e.created_by, e.submitted_at, e.paid_at,
+		e.account_id, COALESCE(e.voucher_no, '') as voucher_no, e.recurring_expense_id, e.created_at, e.updated_at
+		FROM expenses e
+		LEFT JOIN blocks b ON e.block_id = b.id
+		LEFT JOIN vendors v ON e.vendor_id = v.id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExpense(row scanner, e *Expense) error {
+	return row.Scan(
+		&e.ID, &e.OrganizationID, &e.BlockID, &e.BlockName, &e.Category, &e.Fund, &e.Amount,
+		&e.Date, &e.Description, &e.ReceiptURL, &e.VendorID, &e.VendorName,
+		&e.DueDate, &e.Status, &e.RequiredApprovals, &e.CreatedBy, &e.SubmittedAt, &e.PaidAt,
+		&e.AccountID, &e.VoucherNo, &e.RecurringID, &e.CreatedAt, &e.UpdatedAt,
+	)
+}
+
+// Create inserts an expense. An expense created as already paid is posted
+// to its account in the same transaction.
 func (r *Repository) Create(e *Expense) error {
+	if e.BlockID != nil {
+		if err := block.Belongs(r.db, *e.BlockID, e.OrganizationID); err != nil {
+			return err
+		}
+	}
+	if e.VendorID != nil {
+		if err := vendors.Belongs(r.db, *e.VendorID, e.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO expenses (organization_id, category, amount, date, description, receipt_url)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO expenses (organization_id, category, fund, amount, date, description, receipt_url,
+			vendor_id, due_date, status, required_approvals, created_by, submitted_at, paid_at, block_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(query,
-		e.OrganizationID, e.Category, e.Amount, e.Date, e.Description, e.ReceiptURL,
+	err = tx.QueryRow(query,
+		e.OrganizationID, e.Category, e.Fund, e.Amount, e.Date, e.Description, e.ReceiptURL,
+		e.VendorID, e.DueDate, e.Status, e.RequiredApprovals, e.CreatedBy, e.SubmittedAt, e.PaidAt, e.BlockID,
 	).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if e.Status == StatusPaid {
+		if err := postPayment(tx, e); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// postPayment posts a paid expense to its account and gives it the next
+// payment voucher number of the year it was paid in.
+func postPayment(tx *sql.Tx, e *Expense) error {
+	entry := &account.Entry{
+		OrganizationID: e.OrganizationID,
+		Date:           e.PaidAt.Truncate(24 * time.Hour),
+		Amount:         -e.Amount,
+		Kind:           account.KindExpense,
+		SourceType:     "expense",
+		SourceID:       &e.ID,
+		Description:    e.Description,
+	}
+	if e.AccountID != nil {
+		entry.AccountID = *e.AccountID
+	}
+	if err := account.Post(tx, entry, "cash"); err != nil {
+		return err
+	}
+	e.AccountID = &entry.AccountID
+
+	n, err := numbering.Next(tx, e.OrganizationID, numbering.KindExpenseVoucher, entry.Date, &e.ID, e.Description)
+	if err != nil {
+		return err
+	}
+	e.VoucherNo = n.Number
+	_, err = tx.Exec("UPDATE expenses SET account_id = $1, voucher_no = $2 WHERE id = $3", entry.AccountID, n.Number, e.ID)
+	return err
 }
 
 func (r *Repository) GetByID(id string) (*Expense, error) {
 	e := &Expense{}
-	query := `SELECT id, organization_id, category, amount, date, description,
-		COALESCE(receipt_url, '') as receipt_url, created_at, updated_at
-		FROM expenses WHERE id = $1`
-
-	err := r.db.QueryRow(query, id).Scan(
-		&e.ID, &e.OrganizationID, &e.Category, &e.Amount,
-		&e.Date, &e.Description, &e.ReceiptURL, &e.CreatedAt, &e.UpdatedAt,
-	)
+	err := scanExpense(r.db.QueryRow(selectExpense+" WHERE e.id = $1", id), e)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("expense not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 	return e, nil
 }
 
-func (r *Repository) ListByOrganization(orgID string, year, month int) ([]Expense, error) {
-	query := `SELECT id, organization_id, category, amount, date, description,
-		COALESCE(receipt_url, '') as receipt_url, created_at, updated_at
-		FROM expenses WHERE organization_id = $1`
+// listConfig is what the expense list can be sorted and searched by; ?q=
+// matches the description, category, vendor and voucher number.
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"date":       "e.date",
+		"amount":     "e.amount",
+		"category":   "e.category",
+		"status":     "e.status",
+		"created_at": "e.created_at",
+	},
+	Default: "-date",
+	ID:      "e.id",
+	Search:  []string{"e.description", "e.category", "v.name", "e.voucher_no"},
+	Date:    "e.date",
+}
+
+func (r *Repository) ListByOrganization(filter ListFilter, p *listing.Params) ([]Expense, listing.Result, error) {
+	query, args := p.Filter(listQuery(filter))
+	expenses := []Expense{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var e Expense
+		if err := scanExpense(rows, &e); err != nil {
+			return err
+		}
+		expenses = append(expenses, e)
+		return nil
+	})
+	return expenses, res, err
+}
 
-	args := []interface{}{orgID}
+// Each calls fn for every expense matching the filter, in
+// ListByOrganization order, while reading them from the database.
+func (r *Repository) Each(filter ListFilter, p *listing.Params, fn func(*Expense) error) error {
+	query, args := p.Filter(listQuery(filter))
+	rows, err := r.db.Query(query+p.Order(), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Expense
+		if err := scanExpense(rows, &e); err != nil {
+			return err
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func listQuery(filter ListFilter) (string, []interface{}) {
+	query := selectExpense + " WHERE e.organization_id = $1"
+
+	args := []interface{}{filter.OrganizationID}
 	argIdx := 2
 
-	if year > 0 {
-		query += fmt.Sprintf(" AND EXTRACT(YEAR FROM date) = $%d", argIdx)
-		args = append(args, year)
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND e.status = $%d", argIdx)
+		args = append(args, filter.Status)
 		argIdx++
 	}
-	if month > 0 {
-		query += fmt.Sprintf(" AND EXTRACT(MONTH FROM date) = $%d", argIdx)
-		args = append(args, month)
+	if filter.Fund != "" {
+		query += fmt.Sprintf(" AND e.fund = $%d", argIdx)
+		args = append(args, filter.Fund)
 		argIdx++
 	}
+	if filter.BlockID != "" {
+		query += fmt.Sprintf(" AND e.block_id = $%d", argIdx)
+		args = append(args, filter.BlockID)
+		argIdx++
+	}
+	if filter.Year > 0 {
+		query += fmt.Sprintf(" AND EXTRACT(YEAR FROM e.date) = $%d", argIdx)
+		args = append(args, filter.Year)
+		argIdx++
+	}
+	if filter.Month > 0 {
+		query += fmt.Sprintf(" AND EXTRACT(MONTH FROM e.date) = $%d", argIdx)
+		args = append(args, filter.Month)
+		argIdx++
+	}
+	return query, args
+}
 
-	query += " ORDER BY date DESC"
+// ListUnpaid returns approved but unpaid expenses ordered by due date,
+// oldest first. Expenses without a due date sort last.
+func (r *Repository) ListUnpaid(orgID string) ([]Expense, error) {
+	query := selectExpense + ` WHERE e.organization_id = $1 AND e.status = 'approved'
+		ORDER BY e.due_date NULLS LAST, e.date`
+	return r.list(query, orgID)
+}
 
+func (r *Repository) list(query string, args ...interface{}) ([]Expense, error) {
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -73,10 +235,7 @@ func (r *Repository) ListByOrganization(orgID string, year, month int) ([]Expens
 	var expenses []Expense
 	for rows.Next() {
 		var e Expense
-		if err := rows.Scan(
-			&e.ID, &e.OrganizationID, &e.Category, &e.Amount,
-			&e.Date, &e.Description, &e.ReceiptURL, &e.CreatedAt, &e.UpdatedAt,
-		); err != nil {
+		if err := scanExpense(rows, &e); err != nil {
 			return nil, err
 		}
 		expenses = append(expenses, e)
@@ -84,7 +243,246 @@ func (r *Repository) ListByOrganization(orgID string, year, month int) ([]Expens
 	return expenses, nil
 }
 
-func (r *Repository) Delete(id string) error {
-	_, err := r.db.Exec("DELETE FROM expenses WHERE id = $1", id)
+// RequiredApprovals returns how many approvals an expense of this category
+// and amount needs. A category rule takes precedence over the
+// organization-wide rule; without a matching rule no approval is needed.
+func (r *Repository) RequiredApprovals(orgID, category string, amount float64) (int, error) {
+	var threshold float64
+	var required int
+	err := r.db.QueryRow(`SELECT threshold, required_approvals FROM expense_approval_rules
+		WHERE organization_id = $1 AND category IN ($2, '')
+		ORDER BY category DESC LIMIT 1`, orgID, category).Scan(&threshold, &required)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if amount <= threshold {
+		return 0, nil
+	}
+	return required, nil
+}
+
+// Submit moves draft expenses into the workflow. Expenses that need no
+// approval go straight to approved.
+func (r *Repository) Submit(id string, required int) error {
+	status := StatusSubmitted
+	if required == 0 {
+		status = StatusApproved
+	}
+	result, err := r.db.Exec(`UPDATE expenses SET status=$1, required_approvals=$2, submitted_at=NOW(), updated_at=NOW()
+		WHERE id=$3 AND status='draft'`, status, required, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("expense not found or not a draft")
+	}
+	return nil
+}
+
+// IsBoardMember reports whether the user votes on the organization's
+// expenses.
+func (r *Repository) IsBoardMember(orgID, userID string) (bool, error) {
+	return organization.IsBoardMember(r.db, orgID, userID)
+}
+
+// RecordDecision stores a board member's vote and moves the expense to
+// approved once enough approvals are collected, or to rejected on the
+// first rejection. The expense row is locked so concurrent votes are
+// counted correctly. It returns the resulting status.
+func (r *Repository) RecordDecision(orgID, id, userID, decision, comment string) (string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var required int
+	var createdBy sql.NullString
+	err = tx.QueryRow(`SELECT status, required_approvals, created_by FROM expenses
+		WHERE id = $1 AND organization_id = $2 FOR UPDATE`, id, orgID).
+		Scan(&status, &required, &createdBy)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if status != StatusSubmitted {
+		return "", fmt.Errorf("expense is %s, only submitted expenses can be approved or rejected", status)
+	}
+	if createdBy.Valid && createdBy.String == userID {
+		return "", fmt.Errorf("you cannot approve an expense you created")
+	}
+
+	_, err = tx.Exec(`INSERT INTO expense_approvals (expense_id, user_id, decision, comment) VALUES ($1, $2, $3, $4)`,
+		id, userID, decision, comment)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return "", fmt.Errorf("you have already voted on this expense")
+		}
+		return "", err
+	}
+
+	if decision == "reject" {
+		status = StatusRejected
+	} else {
+		var approvals int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM expense_approvals WHERE expense_id = $1 AND decision = 'approve'`, id).
+			Scan(&approvals); err != nil {
+			return "", err
+		}
+		if approvals >= required {
+			status = StatusApproved
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE expenses SET status=$1, updated_at=NOW() WHERE id=$2`, status, id); err != nil {
+		return "", err
+	}
+	return status, tx.Commit()
+}
+
+// MarkPaid pays an approved expense from an account.
+func (r *Repository) MarkPaid(id string, paidAt time.Time, accountID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	e := &Expense{ID: id, PaidAt: &paidAt}
+	if accountID != "" {
+		e.AccountID = &accountID
+	}
+	err = tx.QueryRow(`UPDATE expenses SET status='paid', paid_at=$1, updated_at=NOW()
+		WHERE id=$2 AND status='approved'
+		RETURNING organization_id, amount, description`, paidAt, id).Scan(&e.OrganizationID, &e.Amount, &e.Description)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("expense not found or not approved")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := postPayment(tx, e); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) ListApprovals(expenseID string) ([]Approval, error) {
+	rows, err := r.db.Query(`SELECT a.id, a.expense_id, a.user_id, us.full_name, a.decision, a.comment, a.created_at
+		FROM expense_approvals a JOIN users us ON a.user_id = us.id
+		WHERE a.expense_id = $1 ORDER BY a.created_at`, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []Approval
+	for rows.Next() {
+		var a Approval
+		if err := rows.Scan(&a.ID, &a.ExpenseID, &a.UserID, &a.UserName, &a.Decision, &a.Comment, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, nil
+}
+
+func (r *Repository) CreateComment(c *Comment) error {
+	return r.db.QueryRow(`INSERT INTO expense_comments (expense_id, user_id, body) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, c.ExpenseID, c.UserID, c.Body).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (r *Repository) ListComments(expenseID string) ([]Comment, error) {
+	rows, err := r.db.Query(`SELECT c.id, c.expense_id, c.user_id, us.full_name, c.body, c.created_at
+		FROM expense_comments c JOIN users us ON c.user_id = us.id
+		WHERE c.expense_id = $1 ORDER BY c.created_at`, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.ExpenseID, &c.UserID, &c.UserName, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+func (r *Repository) ListRules(orgID string) ([]ApprovalRule, error) {
+	rows, err := r.db.Query(`SELECT id, organization_id, category, threshold, required_approvals, created_at, updated_at
+		FROM expense_approval_rules WHERE organization_id = $1 ORDER BY category`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []ApprovalRule
+	for rows.Next() {
+		var rule ApprovalRule
+		if err := rows.Scan(&rule.ID, &rule.OrganizationID, &rule.Category, &rule.Threshold,
+			&rule.RequiredApprovals, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *Repository) UpsertRule(rule *ApprovalRule) error {
+	query := `
+		INSERT INTO expense_approval_rules (organization_id, category, threshold, required_approvals)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, category)
+		DO UPDATE SET threshold = EXCLUDED.threshold, required_approvals = EXCLUDED.required_approvals, updated_at = NOW()
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, rule.OrganizationID, rule.Category, rule.Threshold, rule.RequiredApprovals).
+		Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *Repository) DeleteRule(orgID, id string) error {
+	_, err := r.db.Exec("DELETE FROM expense_approval_rules WHERE id = $1 AND organization_id = $2", id, orgID)
 	return err
 }
+
+// Delete removes an expense together with its account postings. Expenses
+// with a numbered payment voucher are kept, so the voucher series has no
+// gaps and reconciled periods are not rewritten.
+func (r *Repository) Delete(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hasVoucher bool
+	err = tx.QueryRow("SELECT voucher_no IS NOT NULL FROM expenses WHERE id = $1 FOR UPDATE", id).Scan(&hasVoucher)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if hasVoucher {
+		return ErrHasVoucher
+	}
+
+	if err := account.Unpost(tx, "expense", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM expenses WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -6,7 +6,20 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Route("/organizations/{orgId}/expenses", func(r chi.Router) {
 		r.Post("/", h.Create)
 		r.Get("/", h.List)
+		r.Get("/payables", h.Payables)
+		r.Post("/submit", h.Submit)
+		r.Get("/approval-rules", h.ListRules)
+		r.Put("/approval-rules", h.SetRule)
+		r.Delete("/approval-rules/{id}", h.DeleteRule)
 		r.Get("/{id}", h.Get)
 		r.Delete("/{id}", h.Delete)
+		r.Post("/{id}/submit", h.SubmitOne)
+		r.Post("/{id}/approve", h.Approve)
+		r.Post("/{id}/reject", h.Reject)
+		r.Post("/{id}/comments", h.AddComment)
+		r.Patch("/{id}/pay", h.MarkPaid)
+		r.Post("/{id}/receipts", h.UploadReceipt)
+		r.Get("/{id}/receipts", h.ListReceipts)
+		r.Delete("/{id}/receipts/{fileId}", h.DeleteReceipt)
 	})
 }
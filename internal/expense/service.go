@@ -1,19 +1,26 @@
 package expense
 
 import (
+	"errors"
 	"fmt"
+	"mime/multipart"
 	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Service struct {
-	repo *Repository
+	repo        *Repository
+	attachments *attachment.Service
 }
 
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, attachments *attachment.Service) *Service {
+	return &Service{repo: repo, attachments: attachments}
 }
 
-func (s *Service) Create(orgID string, req CreateRequest) (*Expense, error) {
+func (s *Service) Create(orgID, userID string, req CreateRequest) (*Expense, error) {
 	if req.Category == "" || req.Amount <= 0 || req.Date == "" {
 		return nil, fmt.Errorf("category, amount, and date are required")
 	}
@@ -23,13 +30,62 @@ func (s *Service) Create(orgID string, req CreateRequest) (*Expense, error) {
 		return nil, fmt.Errorf("invalid date format, use YYYY-MM-DD")
 	}
 
+	if req.Fund == "" {
+		req.Fund = dues.TypeAidat
+	}
+	if !dues.ValidType(req.Fund) {
+		return nil, fmt.Errorf("invalid fund %q", req.Fund)
+	}
+
 	e := &Expense{
 		OrganizationID: orgID,
 		Category:       req.Category,
+		Fund:           req.Fund,
 		Amount:         req.Amount,
 		Date:           date,
 		Description:    req.Description,
 		ReceiptURL:     req.ReceiptURL,
+		Status:         StatusDraft,
+	}
+	if userID != "" {
+		e.CreatedBy = &userID
+	}
+
+	if req.VendorID != "" {
+		e.VendorID = &req.VendorID
+	}
+	if req.BlockID != "" {
+		e.BlockID = &req.BlockID
+	}
+	if req.DueDate != "" {
+		dueDate, err := time.Parse("2006-01-02", req.DueDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid due_date format, use YYYY-MM-DD")
+		}
+		e.DueDate = &dueDate
+	}
+
+	if !req.Draft {
+		required, err := s.repo.RequiredApprovals(orgID, e.Category, e.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		e.SubmittedAt = &now
+		e.RequiredApprovals = required
+		switch {
+		case required > 0:
+			e.Status = StatusSubmitted
+		case req.Unpaid:
+			e.Status = StatusApproved
+		default:
+			e.Status = StatusPaid
+			e.PaidAt = &now
+			if req.AccountID != "" {
+				e.AccountID = &req.AccountID
+			}
+		}
 	}
 
 	if err := s.repo.Create(e); err != nil {
@@ -39,13 +95,216 @@ func (s *Service) Create(orgID string, req CreateRequest) (*Expense, error) {
 }
 
 func (s *Service) GetByID(id string) (*Expense, error) {
-	return s.repo.GetByID(id)
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Receipts, err = s.attachments.ListByOwner(attachment.OwnerExpense, id); err != nil {
+		return nil, err
+	}
+	if e.Approvals, err = s.repo.ListApprovals(id); err != nil {
+		return nil, err
+	}
+	if e.Comments, err = s.repo.ListComments(id); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *Service) ListByOrganization(filter ListFilter, p *listing.Params) ([]Expense, listing.Result, error) {
+	return s.repo.ListByOrganization(filter, p)
+}
+
+func (s *Service) Each(filter ListFilter, p *listing.Params, fn func(*Expense) error) error {
+	return s.repo.Each(filter, p, fn)
+}
+
+// Submit sends draft expenses (e.g. generated from recurring templates)
+// into the approval workflow and returns how many were submitted.
+func (s *Service) Submit(orgID string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("ids are required")
+	}
+
+	for i, id := range ids {
+		e, err := s.repo.GetByID(id)
+		if err != nil {
+			return i, err
+		}
+		if e.OrganizationID != orgID {
+			return i, ErrNotFound
+		}
+
+		required, err := s.repo.RequiredApprovals(orgID, e.Category, e.Amount)
+		if err != nil {
+			return i, err
+		}
+		if err := s.repo.Submit(id, required); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// CanVote reports whether the user may approve or reject the
+// organization's expenses: its manager and board members may.
+func (s *Service) CanVote(orgID, userID string) (bool, error) {
+	return s.repo.IsBoardMember(orgID, userID)
+}
+
+func (s *Service) Approve(orgID, id, userID, comment string) (string, error) {
+	return s.repo.RecordDecision(orgID, id, userID, "approve", comment)
+}
+
+func (s *Service) Reject(orgID, id, userID, comment string) (string, error) {
+	if comment == "" {
+		return "", fmt.Errorf("a comment explaining the rejection is required")
+	}
+	return s.repo.RecordDecision(orgID, id, userID, "reject", comment)
+}
+
+func (s *Service) AddComment(orgID, id, userID, body string) (*Comment, error) {
+	if body == "" {
+		return nil, fmt.Errorf("body is required")
+	}
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.OrganizationID != orgID {
+		return nil, ErrNotFound
+	}
+
+	c := &Comment{ExpenseID: id, UserID: userID, Body: body}
+	if err := s.repo.CreateComment(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) MarkPaid(orgID, id string, req MarkPaidRequest) error {
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if e.OrganizationID != orgID {
+		return ErrNotFound
+	}
+	return s.repo.MarkPaid(id, time.Now(), req.AccountID)
+}
+
+func (s *Service) ListRules(orgID string) ([]ApprovalRule, error) {
+	return s.repo.ListRules(orgID)
+}
+
+func (s *Service) SetRule(orgID string, req ApprovalRuleRequest) (*ApprovalRule, error) {
+	if req.Threshold < 0 {
+		return nil, fmt.Errorf("threshold cannot be negative")
+	}
+	if req.RequiredApprovals < 1 {
+		return nil, fmt.Errorf("required_approvals must be at least 1")
+	}
+
+	rule := &ApprovalRule{
+		OrganizationID:    orgID,
+		Category:          req.Category,
+		Threshold:         req.Threshold,
+		RequiredApprovals: req.RequiredApprovals,
+	}
+	if err := s.repo.UpsertRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *Service) DeleteRule(orgID, id string) error {
+	return s.repo.DeleteRule(orgID, id)
+}
+
+func (s *Service) GetPayables(orgID string, days int) (*Payables, error) {
+	items, err := s.repo.ListUnpaid(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	horizon := today.AddDate(0, 0, days)
+
+	p := &Payables{Items: items}
+	for _, e := range items {
+		p.TotalUnpaid += e.Amount
+		if e.DueDate == nil {
+			continue
+		}
+		if e.DueDate.Before(today) {
+			p.OverdueSum += e.Amount
+		} else if !e.DueDate.After(horizon) {
+			p.DueSoonSum += e.Amount
+		}
+	}
+	return p, nil
+}
+
+// ErrHasVoucher is returned when deleting an expense whose payment voucher
+// has been numbered. It is corrected with a reversing entry instead.
+var ErrHasVoucher = errors.New("expense has a payment voucher and cannot be deleted")
+
+// Delete removes the expense first and its files after, so a failed delete
+// leaves the expense with its receipts intact.
+func (s *Service) Delete(orgID, id string) error {
+	e, err := s.checkOrganization(orgID, id)
+	if err != nil {
+		return err
+	}
+	if e.VoucherNo != "" {
+		return ErrHasVoucher
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	return s.attachments.DeleteByOwner(attachment.OwnerExpense, id)
 }
 
-func (s *Service) ListByOrganization(orgID string, year, month int) ([]Expense, error) {
-	return s.repo.ListByOrganization(orgID, year, month)
+func (s *Service) AddReceipt(orgID, id string, file multipart.File, header *multipart.FileHeader) (*attachment.Attachment, error) {
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.OrganizationID != orgID {
+		return nil, ErrNotFound
+	}
+	return s.attachments.Upload(orgID, attachment.OwnerExpense, id, file, header)
 }
 
-func (s *Service) Delete(id string) error {
-	return s.repo.Delete(id)
+func (s *Service) ListReceipts(orgID, id string) ([]attachment.Attachment, error) {
+	if _, err := s.checkOrganization(orgID, id); err != nil {
+		return nil, err
+	}
+	return s.attachments.ListByOwner(attachment.OwnerExpense, id)
+}
+
+func (s *Service) DeleteReceipt(orgID, id, fileID string) error {
+	if _, err := s.checkOrganization(orgID, id); err != nil {
+		return err
+	}
+	a, err := s.attachments.GetByID(fileID)
+	if err != nil {
+		return err
+	}
+	if a.OwnerType != attachment.OwnerExpense || a.OwnerID != id {
+		return fmt.Errorf("attachment not found")
+	}
+	return s.attachments.Delete(fileID)
+}
+
+// checkOrganization returns the expense if it belongs to the organization.
+func (s *Service) checkOrganization(orgID, id string) (*Expense, error) {
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.OrganizationID != orgID {
+		return nil, ErrNotFound
+	}
+	return e, nil
 }
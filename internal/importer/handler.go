@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/internal/attachment"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+// templateHeader is the header row of the downloadable template, in the
+// separator Excel uses in a Turkish locale.
+const templateHeader = "Blok;Daire No;Kat;Alan (m²);Arsa Payı;Ad Soyad;Telefon;E-posta;Rol\r\n"
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	file, header, err := attachment.FormFile(w, r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	var mapping map[string]string
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			response.Error(w, http.StatusBadRequest, "mapping must be a JSON object of field to column header")
+			return
+		}
+	}
+	dryRun := r.FormValue("dry_run") == "true" || r.URL.Query().Get("dry_run") == "true"
+
+	result, err := h.service.Import(orgID, file, header.Size, header.Filename, mapping, dryRun)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	}
+	response.JSON(w, status, result)
+}
+
+func (h *Handler) Template(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="daire-listesi.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("\xEF\xBB\xBF" + templateHeader))
+}
@@ -0,0 +1,53 @@
+package importer
+
+// Fields a spreadsheet column can be mapped to. Each row describes one unit
+// and, optionally, the resident assigned to it.
+const (
+	FieldBlock      = "block"
+	FieldUnitNumber = "unit_number"
+	FieldFloor      = "floor"
+	FieldArea       = "area"
+	FieldLandShare  = "land_share"
+	FieldFullName   = "full_name"
+	FieldPhone      = "phone"
+	FieldEmail      = "email"
+	FieldRole       = "role"
+)
+
+var Fields = []string{
+	FieldBlock, FieldUnitNumber, FieldFloor, FieldArea, FieldLandShare,
+	FieldFullName, FieldPhone, FieldEmail, FieldRole,
+}
+
+// Row statuses
+const (
+	StatusImported = "imported"
+	StatusValid    = "valid" // dry run: the row would be imported
+	StatusFailed   = "failed"
+)
+
+// MaxRows bounds a single import.
+const MaxRows = 5000
+
+// Result reports an import row by row. On a dry run every row is checked
+// against the database as if imported, then rolled back.
+type Result struct {
+	DryRun           bool              `json:"dry_run"`
+	Mapping          map[string]string `json:"mapping"` // field -> column header it was read from
+	TotalRows        int               `json:"total_rows"`
+	Imported         int               `json:"imported"` // rows imported, or importable on a dry run
+	Failed           int               `json:"failed"`
+	UnitsCreated     int               `json:"units_created"`
+	ResidentsCreated int               `json:"residents_created"`
+	BlocksCreated    []string          `json:"blocks_created,omitempty"`
+	Rows             []RowResult       `json:"rows"`
+}
+
+type RowResult struct {
+	Row        int      `json:"row"` // spreadsheet row number; the header is row 1
+	Status     string   `json:"status"`
+	BlockName  string   `json:"block_name,omitempty"`
+	UnitNumber string   `json:"unit_number,omitempty"`
+	FullName   string   `json:"full_name,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/xlsx"
+)
+
+// readTable reads an uploaded CSV or XLSX file into rows of cells.
+func readTable(file io.ReaderAt, size int64, filename string) ([][]string, error) {
+	head := make([]byte, 4)
+	n, _ := file.ReadAt(head, 0)
+	isZip := n == 4 && bytes.Equal(head, []byte("PK\x03\x04"))
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); {
+	case ext == ".xlsx" || (ext != ".csv" && isZip):
+		// The header row comes on top of MaxRows.
+		return xlsx.ReadRows(file, size, MaxRows+1)
+	case ext == ".xls":
+		return nil, fmt.Errorf("old .xls files are not supported, save the sheet as .xlsx or .csv")
+	default:
+		data, err := io.ReadAll(io.NewSectionReader(file, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		return readCSV(data)
+	}
+}
+
+// readCSV reads a CSV export. Excel in a Turkish locale writes ';' as the
+// separator and, without "UTF-8" in the save dialog, Windows-1254 text.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		data = decodeWindows1254(data)
+	}
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	comma := ','
+	best := bytes.Count(firstLine, []byte(","))
+	for _, sep := range []rune{';', '\t'} {
+		if c := bytes.Count(firstLine, []byte(string(sep))); c > best {
+			comma, best = sep, c
+		}
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return rows, nil
+}
+
+// decodeWindows1254 converts Turkish Windows text to UTF-8. It matches
+// Latin-1 except for the six Turkish letters below; the remaining
+// 0x80-0x9F punctuation is rare in names and kept as Latin-1.
+func decodeWindows1254(data []byte) []byte {
+	turkish := map[byte]rune{0xD0: 'Ğ', 0xDD: 'İ', 0xDE: 'Ş', 0xF0: 'ğ', 0xFD: 'ı', 0xFE: 'ş'}
+	var b bytes.Buffer
+	for _, c := range data {
+		if r, ok := turkish[c]; ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.Bytes()
+}
+
+// aliases are the column headers recognised without an explicit mapping,
+// compared after fold.
+var aliases = map[string][]string{
+	FieldBlock:      {"blok", "block", "bina", "blokadi"},
+	FieldUnitNumber: {"daire", "daireno", "dairenumarasi", "kapino", "bagimsizbolum", "bagimsizbolumno", "bbno", "unit", "unitnumber", "no"},
+	FieldFloor:      {"kat", "katno", "floor"},
+	FieldArea:       {"alan", "alanm2", "m2", "metrekare", "netm2", "netalan", "brutm2", "area"},
+	FieldLandShare:  {"arsapayi", "arsa", "landshare"},
+	FieldFullName:   {"adsoyad", "adisoyadi", "adsoyadi", "isim", "isimsoyisim", "sakin", "malik", "name", "fullname"},
+	FieldPhone:      {"telefon", "tel", "telefonno", "gsm", "cep", "ceptelefonu", "phone"},
+	FieldEmail:      {"eposta", "epostaadresi", "email", "mail"},
+	FieldRole:       {"rol", "sifat", "tip", "sakintipi", "durum", "role"},
+}
+
+// columns maps each field to the index of the column it is read from.
+// An explicit mapping (field -> header) wins over the aliases.
+func columns(header []string, mapping map[string]string) (map[string]int, map[string]string, error) {
+	byHeader := make(map[string]int, len(header))
+	for i, h := range header {
+		if key := fold(h); key != "" {
+			if _, dup := byHeader[key]; !dup {
+				byHeader[key] = i
+			}
+		}
+	}
+
+	cols := make(map[string]int)
+	used := make(map[string]string)
+	for field, h := range mapping {
+		if !validField(field) {
+			return nil, nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+		i, ok := byHeader[fold(h)]
+		if !ok {
+			return nil, nil, fmt.Errorf("column %q for %s not found", h, field)
+		}
+		cols[field], used[field] = i, header[i]
+	}
+	for _, field := range Fields {
+		if _, ok := cols[field]; ok {
+			continue
+		}
+		for _, alias := range aliases[field] {
+			if i, ok := byHeader[alias]; ok {
+				cols[field], used[field] = i, header[i]
+				break
+			}
+		}
+	}
+
+	if _, ok := cols[FieldUnitNumber]; !ok {
+		return nil, nil, fmt.Errorf("no unit number column found, map one with {\"unit_number\": \"<header>\"}")
+	}
+	return cols, used, nil
+}
+
+func validField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+var foldReplacer = strings.NewReplacer("ç", "c", "ğ", "g", "ı", "i", "ö", "o", "ş", "s", "ü", "u", "â", "a", "î", "i", "û", "u", "²", "2")
+
+// fold lower-cases a header the Turkish way, drops diacritics and keeps
+// only letters and digits, so "Daire No", "DAİRE NO" and "daire_no" match.
+func fold(s string) string {
+	s = foldReplacer.Replace(strings.ToLowerSpecial(unicode.TurkishCase, s))
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var roles = map[string]string{
+	"malik": "owner", "evsahibi": "owner", "sahibi": "owner", "katmaliki": "owner", "owner": "owner",
+	"hissedar": "co_owner", "ortakmalik": "co_owner", "hissedarmalik": "co_owner", "coowner": "co_owner",
+	"kiraci": "tenant", "tenant": "tenant",
+	"aile": "family", "aileuyesi": "family", "family": "family",
+}
+
+func parseRole(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	if role, ok := roles[fold(s)]; ok {
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q, use malik, hissedar, kiracı or aile", s)
+}
+
+// parseFloor accepts numbers and the usual Turkish names of the lowest
+// floors.
+func parseFloor(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	switch fold(s) {
+	case "":
+		return 0, nil
+	case "zemin", "z", "giris", "zeminkat":
+		return 0, nil
+	case "bodrum", "b", "bodrumkat":
+		return -1, nil
+	}
+	number := strings.TrimSpace(strings.TrimSuffix(strings.ToLower(s), "kat"))
+	n, err := strconv.Atoi(strings.TrimSuffix(number, "."))
+	if err != nil {
+		return 0, fmt.Errorf("invalid floor %q", s)
+	}
+	return n, nil
+}
+
+var thousands = regexp.MustCompile(`^[1-9]\d{0,2}(\.\d{3})+$`)
+
+// parseNumber reads amounts written either way: "1.234,56" and "120,5" as
+// well as "1234.56".
+func parseNumber(value string) (float64, error) {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "m²"))
+	if s == "" {
+		return 0, nil
+	}
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0 && comma > dot:
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case dot >= 0 && comma >= 0:
+		s = strings.ReplaceAll(s, ",", "")
+	case comma >= 0:
+		s = strings.Replace(s, ",", ".", 1)
+	case thousands.MatchString(s):
+		s = strings.ReplaceAll(s, ".", "")
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,10 @@
+package importer
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/imports", func(r chi.Router) {
+		r.Post("/", h.Import)
+		r.Get("/template", h.Template)
+	})
+}
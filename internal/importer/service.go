@@ -0,0 +1,334 @@
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/resident"
+	"github.com/mustafakemalcelik/sitetakip/internal/unit"
+)
+
+type Service struct {
+	db        *sql.DB
+	units     *unit.Service
+	residents *resident.Service
+}
+
+func NewService(db *sql.DB, units *unit.Service, residents *resident.Service) *Service {
+	return &Service{db: db, units: units, residents: residents}
+}
+
+// row is one parsed data row of the sheet.
+type row struct {
+	result    *RowResult
+	block     string
+	unit      unit.CreateRequest
+	existing  *unit.Unit // set when the unit is already in the database
+	resident  *resident.CreateRequest
+	phoneKey  string
+	emailKey  string
+	unitKey   string
+	validated bool
+}
+
+// Import reads units and their residents from a CSV or XLSX sheet. Rows
+// are checked on their own first (formats, duplicates within the file and
+// against existing residents); the valid ones are then written in a single
+// transaction, each under a savepoint so a row the database rejects is
+// reported without losing the others. A dry run does the same and rolls
+// back at the end.
+//
+// Each unit may appear once; a row that names an existing unit assigns its
+// resident to it when the unit is still empty.
+func (s *Service) Import(orgID string, file io.ReaderAt, size int64, filename string, mapping map[string]string, dryRun bool) (*Result, error) {
+	table, err := readTable(file, size, filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(table) < 2 {
+		return nil, fmt.Errorf("the file has no data rows below the header")
+	}
+	if len(table)-1 > MaxRows {
+		return nil, fmt.Errorf("an import can have at most %d rows", MaxRows)
+	}
+	cols, used, err := columns(table[0], mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.parseRows(orgID, table, cols)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: dryRun, Mapping: used, Rows: make([]RowResult, 0, len(rows))}
+	if err := s.write(orgID, rows, result); err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		result.Rows = append(result.Rows, *r.result)
+		if r.result.Status == StatusFailed {
+			result.Failed++
+		} else {
+			result.Imported++
+		}
+	}
+	result.TotalRows = len(rows)
+	return result, nil
+}
+
+// parseRows validates every row without touching the database.
+func (s *Service) parseRows(orgID string, table [][]string, cols map[string]int) ([]*row, error) {
+	existingUnits, err := s.units.ListByOrganization(orgID, "")
+	if err != nil {
+		return nil, err
+	}
+	units := make(map[string]*unit.Unit, len(existingUnits))
+	for i := range existingUnits {
+		u := &existingUnits[i]
+		units[unitKey(u.BlockName, u.UnitNumber)] = u
+	}
+
+	existingResidents, err := s.residents.ListByOrganization(orgID)
+	if err != nil {
+		return nil, err
+	}
+	phones := make(map[string]string)
+	emails := make(map[string]string)
+	for _, r := range existingResidents {
+		if p, err := resident.NormalizePhone(r.Phone); err == nil {
+			phones[p] = r.FullName
+		}
+		if r.Email != "" {
+			emails[strings.ToLower(r.Email)] = r.FullName
+		}
+	}
+
+	seenUnits := make(map[string]int)
+	seenPhones := make(map[string]int)
+	seenEmails := make(map[string]int)
+
+	var rows []*row
+	for i, cells := range table[1:] {
+		get := func(field string) string {
+			c, ok := cols[field]
+			if !ok || c >= len(cells) {
+				return ""
+			}
+			return strings.TrimSpace(cells[c])
+		}
+		if isBlank(cells) {
+			continue
+		}
+
+		line := i + 2
+		r := &row{result: &RowResult{Row: line}}
+		fail := func(format string, args ...interface{}) {
+			r.result.Errors = append(r.result.Errors, fmt.Sprintf(format, args...))
+		}
+
+		r.block = get(FieldBlock)
+		r.unit.UnitNumber = get(FieldUnitNumber)
+		r.result.BlockName, r.result.UnitNumber = r.block, r.unit.UnitNumber
+		if err := unit.CheckUnitNumber(r.unit.UnitNumber); err != nil {
+			fail("%v", err)
+		}
+		if r.unit.Floor, err = parseFloor(get(FieldFloor)); err != nil {
+			fail("%v", err)
+		}
+		if r.unit.Area, err = parseNumber(get(FieldArea)); err != nil {
+			fail("area: %v", err)
+		}
+		if r.unit.LandShare, err = parseNumber(get(FieldLandShare)); err != nil {
+			fail("land share: %v", err)
+		}
+
+		r.unitKey = unitKey(r.block, r.unit.UnitNumber)
+		if prev, ok := seenUnits[r.unitKey]; ok {
+			fail("unit is already listed in row %d", prev)
+		} else {
+			seenUnits[r.unitKey] = line
+		}
+		r.existing = units[r.unitKey]
+
+		name, phone, email := get(FieldFullName), get(FieldPhone), get(FieldEmail)
+		r.result.FullName = name
+		if name != "" || phone != "" || email != "" {
+			req := &resident.CreateRequest{FullName: name, Phone: phone, Email: email}
+			if name == "" || phone == "" {
+				fail("a resident needs both a name and a phone number")
+			}
+			if phone != "" {
+				if req.Phone, err = resident.NormalizePhone(phone); err != nil {
+					fail("%v", err)
+				} else {
+					r.phoneKey = req.Phone
+				}
+			}
+			if req.Email, err = resident.NormalizeEmail(email); err != nil {
+				fail("%v", err)
+			} else {
+				r.emailKey = req.Email
+			}
+			if req.Role, err = parseRole(get(FieldRole)); err != nil {
+				fail("%v", err)
+			}
+			r.resident = req
+
+			if r.phoneKey != "" {
+				if owner, ok := phones[r.phoneKey]; ok {
+					fail("a resident with phone %s already exists (%s)", r.phoneKey, owner)
+				} else if prev, ok := seenPhones[r.phoneKey]; ok {
+					fail("phone %s is already used in row %d", r.phoneKey, prev)
+				} else {
+					seenPhones[r.phoneKey] = line
+				}
+			}
+			if r.emailKey != "" {
+				if owner, ok := emails[r.emailKey]; ok {
+					fail("a resident with email %s already exists (%s)", r.emailKey, owner)
+				} else if prev, ok := seenEmails[r.emailKey]; ok {
+					fail("email %s is already used in row %d", r.emailKey, prev)
+				} else {
+					seenEmails[r.emailKey] = line
+				}
+			}
+		}
+
+		if r.existing != nil {
+			switch {
+			case r.resident == nil:
+				fail("unit already exists")
+			case r.existing.ResidentID != nil:
+				fail("unit already has a resident (%s)", r.existing.ResidentName)
+			}
+		}
+
+		r.validated = len(r.result.Errors) == 0
+		if !r.validated {
+			r.result.Status = StatusFailed
+		}
+		rows = append(rows, r)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the file has no data rows below the header")
+	}
+	return rows, nil
+}
+
+// write imports the valid rows in one transaction and commits it unless
+// the result is a dry run.
+func (s *Service) write(orgID string, rows []*row, result *Result) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	blocks := make(map[string]string)
+	status := StatusImported
+	if result.DryRun {
+		status = StatusValid
+	}
+
+	for _, r := range rows {
+		if !r.validated {
+			continue
+		}
+		if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+			return err
+		}
+
+		createdBlock, createdUnit, err := s.writeRow(tx, orgID, r, blocks)
+		if err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rbErr != nil {
+				return rbErr
+			}
+			if createdBlock != "" {
+				delete(blocks, r.block)
+			}
+			r.result.Status = StatusFailed
+			r.result.Errors = append(r.result.Errors, err.Error())
+			continue
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT import_row"); err != nil {
+			return err
+		}
+
+		r.result.Status = status
+		if createdBlock != "" {
+			result.BlocksCreated = append(result.BlocksCreated, createdBlock)
+		}
+		if createdUnit {
+			result.UnitsCreated++
+		}
+		if r.resident != nil {
+			result.ResidentsCreated++
+		}
+	}
+
+	if result.DryRun {
+		return nil
+	}
+	return tx.Commit()
+}
+
+// writeRow creates the row's block if needed, its unit unless it already
+// exists, and its resident, going through the unit and resident services.
+func (s *Service) writeRow(tx *sql.Tx, orgID string, r *row, blocks map[string]string) (string, bool, error) {
+	createdBlock := ""
+	if r.block != "" && r.existing == nil {
+		id, ok := blocks[r.block]
+		if !ok {
+			var created bool
+			var err error
+			id, created, err = block.EnsureTx(tx, orgID, r.block, 0)
+			if err != nil {
+				return "", false, err
+			}
+			blocks[r.block] = id
+			if created {
+				createdBlock = r.block
+			}
+		}
+		r.unit.BlockID = id
+	}
+
+	unitID := ""
+	createdUnit := false
+	if r.existing != nil {
+		unitID = r.existing.ID
+	} else {
+		u, err := s.units.CreateTx(tx, orgID, r.unit)
+		if err != nil {
+			return createdBlock, false, err
+		}
+		unitID, createdUnit = u.ID, true
+	}
+
+	if r.resident != nil {
+		req := *r.resident
+		req.UnitID = unitID
+		if _, err := s.residents.CreateTx(tx, req); err != nil {
+			return createdBlock, createdUnit, err
+		}
+	}
+	return createdBlock, createdUnit, nil
+}
+
+func unitKey(block, number string) string {
+	return strings.TrimSpace(block) + "\x00" + strings.TrimSpace(number)
+}
+
+func isBlank(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
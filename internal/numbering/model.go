@@ -0,0 +1,58 @@
+package numbering
+
+import "time"
+
+// Document kinds that carry an official number. Receipts and expense
+// vouchers are numbered when the payment is recorded; board decisions are
+// numbered on request.
+const (
+	KindReceipt        = "receipt"         // tahsilat makbuzu
+	KindExpenseVoucher = "expense_voucher" // tediye fişi
+	KindDecision       = "decision"        // yönetim / genel kurul kararı
+)
+
+// DefaultDigits is how many digits the sequence part is padded to.
+const DefaultDigits = 6
+
+// defaultPrefixes are used until an organization sets its own.
+var defaultPrefixes = map[string]string{
+	KindReceipt:        "MKB",
+	KindExpenseVoucher: "TDY",
+	KindDecision:       "KRR",
+}
+
+// Kinds lists the document kinds in display order.
+var Kinds = []string{KindReceipt, KindExpenseVoucher, KindDecision}
+
+// Series is how the numbers of one document kind look, e.g.
+// MKB-2026-000123. Sequences restart at 1 every calendar year.
+type Series struct {
+	Kind       string `json:"kind"`
+	Prefix     string `json:"prefix"`
+	Digits     int    `json:"digits"`
+	LastNumber int    `json:"last_number"` // last sequence issued this year, 0 if none
+	Next       string `json:"next"`        // what the next number will look like
+}
+
+// Number is an issued document number.
+type Number struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Kind           string    `json:"kind"`
+	Year           int       `json:"year"`
+	Seq            int       `json:"seq"`
+	Number         string    `json:"number"`
+	SourceID       *string   `json:"source_id,omitempty"`
+	Note           string    `json:"note,omitempty"`
+	IssuedAt       time.Time `json:"issued_at"`
+}
+
+type UpdateSeriesRequest struct {
+	Prefix *string `json:"prefix,omitempty"`
+	Digits *int    `json:"digits,omitempty"`
+}
+
+type IssueRequest struct {
+	Date string `json:"date,omitempty"` // YYYY-MM-DD, defaults to today; picks the year
+	Note string `json:"note"`           // what was decided
+}
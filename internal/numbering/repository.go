@@ -0,0 +1,145 @@
+package numbering
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Next issues the organization's next number of a kind for the year of
+// date inside the caller's transaction, so the number is only used when
+// the document it belongs to is committed. The sequence row stays locked
+// until the transaction ends: concurrent payments wait for each other
+// rather than share a number, and a rolled back payment gives its number
+// back, so the numbers of a year have no gaps.
+func Next(tx *sql.Tx, orgID, kind string, date time.Time, sourceID *string, note string) (*Number, error) {
+	n := &Number{OrganizationID: orgID, Kind: kind, Year: date.Year(), SourceID: sourceID, Note: note}
+	err := tx.QueryRow(`INSERT INTO document_sequences (organization_id, kind, year, last_number)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (organization_id, kind, year)
+		DO UPDATE SET last_number = document_sequences.last_number + 1
+		RETURNING last_number`, orgID, kind, n.Year).Scan(&n.Seq)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, digits, err := series(tx, orgID, kind)
+	if err != nil {
+		return nil, err
+	}
+	n.Number = format(prefix, digits, n.Year, n.Seq)
+
+	err = tx.QueryRow(`INSERT INTO document_numbers (organization_id, kind, year, seq, number, source_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, issued_at`,
+		orgID, kind, n.Year, n.Seq, n.Number, sourceID, note).Scan(&n.ID, &n.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// series returns the prefix and padding of a kind, falling back to the
+// defaults.
+func series(db database.Querier, orgID, kind string) (string, int, error) {
+	prefix, digits := defaultPrefixes[kind], DefaultDigits
+	err := db.QueryRow("SELECT prefix, digits FROM document_series WHERE organization_id = $1 AND kind = $2",
+		orgID, kind).Scan(&prefix, &digits)
+	if err != nil && err != sql.ErrNoRows {
+		return "", 0, err
+	}
+	return prefix, digits, nil
+}
+
+func (r *Repository) ListSeries(orgID string, year int) ([]Series, error) {
+	list := make([]Series, 0, len(Kinds))
+	for _, kind := range Kinds {
+		s := Series{Kind: kind}
+		var err error
+		if s.Prefix, s.Digits, err = series(r.db, orgID, kind); err != nil {
+			return nil, err
+		}
+		err = r.db.QueryRow(`SELECT COALESCE(MAX(last_number), 0) FROM document_sequences
+			WHERE organization_id = $1 AND kind = $2 AND year = $3`, orgID, kind, year).Scan(&s.LastNumber)
+		if err != nil {
+			return nil, err
+		}
+		s.Next = format(s.Prefix, s.Digits, year, s.LastNumber+1)
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func (r *Repository) SaveSeries(orgID, kind, prefix string, digits int) error {
+	_, err := r.db.Exec(`INSERT INTO document_series (organization_id, kind, prefix, digits)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, kind)
+		DO UPDATE SET prefix = EXCLUDED.prefix, digits = EXCLUDED.digits, updated_at = NOW()`,
+		orgID, kind, prefix, digits)
+	return err
+}
+
+// Issue takes a number in a transaction of its own, for documents that
+// are not recorded anywhere else, such as board decisions.
+func (r *Repository) Issue(orgID, kind string, date time.Time, note string) (*Number, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	n, err := Next(tx, orgID, kind, date, nil, note)
+	if err != nil {
+		return nil, err
+	}
+	return n, tx.Commit()
+}
+
+var issuedList = &listing.Config{
+	Sorts: map[string]string{
+		"seq":       "seq",
+		"issued_at": "issued_at",
+	},
+	Default: "seq",
+	ID:      "id",
+	Search:  []string{"number", "note"},
+	Date:    "issued_at",
+}
+
+func (r *Repository) ListIssued(orgID, kind string, year int, p *listing.Params) ([]Number, listing.Result, error) {
+	query, args := p.Filter(`SELECT id, organization_id, kind, year, seq, number, source_id, note, issued_at
+		FROM document_numbers
+		WHERE organization_id = $1 AND kind = $2 AND year = $3`, []interface{}{orgID, kind, year})
+
+	numbers := []Number{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var n Number
+		if err := rows.Scan(&n.ID, &n.OrganizationID, &n.Kind, &n.Year, &n.Seq, &n.Number,
+			&n.SourceID, &n.Note, &n.IssuedAt); err != nil {
+			return err
+		}
+		numbers = append(numbers, n)
+		return nil
+	})
+	return numbers, res, err
+}
+
+// format builds a number such as MKB-2026-000123. Without a prefix the
+// number starts with the year.
+func format(prefix string, digits, year, seq int) string {
+	s := fmt.Sprintf("%d-%0*d", year, digits, seq)
+	if prefix == "" {
+		return s
+	}
+	return prefix + "-" + s
+}
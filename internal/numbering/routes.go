@@ -0,0 +1,12 @@
+package numbering
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/numbering", func(r chi.Router) {
+		r.Get("/", h.ListSeries)
+		r.Put("/{kind}", h.UpdateSeries)
+		r.Post("/{kind}", h.Issue)
+		r.Get("/{kind}", h.ListIssued)
+	})
+}
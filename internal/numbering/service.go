@@ -0,0 +1,111 @@
+package numbering
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListSeries returns the format and this year's progress of every kind.
+func (s *Service) ListSeries(orgID string) ([]Series, error) {
+	return s.repo.ListSeries(orgID, time.Now().Year())
+}
+
+// UpdateSeries changes how new numbers of a kind are printed. Numbers
+// already issued keep the form they were issued with; the sequence itself
+// carries on, so a prefix change never restarts or skips a count.
+func (s *Service) UpdateSeries(orgID, kind string, req UpdateSeriesRequest) (*Series, error) {
+	if err := checkKind(kind); err != nil {
+		return nil, err
+	}
+	list, err := s.ListSeries(orgID)
+	if err != nil {
+		return nil, err
+	}
+	var current Series
+	for _, sr := range list {
+		if sr.Kind == kind {
+			current = sr
+		}
+	}
+
+	prefix, digits := current.Prefix, current.Digits
+	if req.Prefix != nil {
+		prefix = strings.ToUpperSpecial(unicode.TurkishCase, strings.TrimSpace(*req.Prefix))
+		if len([]rune(prefix)) > 10 {
+			return nil, fmt.Errorf("prefix can have at most 10 characters")
+		}
+		for _, r := range prefix {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return nil, fmt.Errorf("prefix can only contain letters and digits")
+			}
+		}
+	}
+	if req.Digits != nil {
+		digits = *req.Digits
+		if digits < 1 || digits > 10 {
+			return nil, fmt.Errorf("digits must be between 1 and 10")
+		}
+	}
+
+	if err := s.repo.SaveSeries(orgID, kind, prefix, digits); err != nil {
+		return nil, err
+	}
+	current.Prefix, current.Digits = prefix, digits
+	current.Next = format(prefix, digits, time.Now().Year(), current.LastNumber+1)
+	return &current, nil
+}
+
+// Issue numbers a board or general assembly decision. Receipts and
+// expense vouchers get their numbers when the payment is recorded and
+// cannot be issued by hand.
+func (s *Service) Issue(orgID, kind string, req IssueRequest) (*Number, error) {
+	if err := checkKind(kind); err != nil {
+		return nil, err
+	}
+	if kind != KindDecision {
+		return nil, fmt.Errorf("%s numbers are issued when the payment is recorded", kind)
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		return nil, fmt.Errorf("note is required")
+	}
+	date := time.Now()
+	if req.Date != "" {
+		var err error
+		if date, err = time.Parse("2006-01-02", req.Date); err != nil {
+			return nil, fmt.Errorf("invalid date format, use YYYY-MM-DD")
+		}
+	}
+	return s.repo.Issue(orgID, kind, date, note)
+}
+
+// ListIssued returns the numbers of a kind issued in a year, in order,
+// for checking that none is missing.
+func (s *Service) ListIssued(orgID, kind string, year int, p *listing.Params) ([]Number, listing.Result, error) {
+	if err := checkKind(kind); err != nil {
+		return nil, listing.Result{}, err
+	}
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	return s.repo.ListIssued(orgID, kind, year, p)
+}
+
+func checkKind(kind string) error {
+	if _, ok := defaultPrefixes[kind]; !ok {
+		return fmt.Errorf("invalid document kind, use one of: %s", strings.Join(Kinds, ", "))
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package organization
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
@@ -47,13 +49,19 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
-	orgs, err := h.service.ListByManager(userID)
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	orgs, res, err := h.service.ListByManager(userID, p)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, orgs)
+	response.WithMeta(w, http.StatusOK, orgs, p.Meta(res, orgs))
 }
 
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
@@ -82,3 +90,67 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
+
+func (h *Handler) ListBoard(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	members, err := h.service.ListBoard(id)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) AddBoardMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.requireManager(w, r, id) {
+		return
+	}
+	var req AddBoardMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	m, err := h.service.AddBoardMember(id, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, m)
+}
+
+func (h *Handler) RemoveBoardMember(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.requireManager(w, r, id) {
+		return
+	}
+
+	if err := h.service.RemoveBoardMember(id, chi.URLParam(r, "userId")); err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+// requireManager answers 404 or 403 and returns false unless the user
+// manages the organization.
+func (h *Handler) requireManager(w http.ResponseWriter, r *http.Request, orgID string) bool {
+	ok, err := h.service.IsManager(orgID, middleware.GetUserID(r.Context()))
+	if errors.Is(err, ErrNotFound) {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return false
+	}
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !ok {
+		response.Error(w, http.StatusForbidden, "only the manager of the organization can change its board")
+		return false
+	}
+	return true
+}
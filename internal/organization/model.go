@@ -6,8 +6,9 @@ type Organization struct {
 	ID             string    `json:"id"`
 	Name           string    `json:"name"`
 	Address        string    `json:"address"`
-	TotalUnits     int       `json:"total_units"`
+	TotalUnits     int       `json:"total_units"` // counted from the units table
 	MonthlyDueAmount float64 `json:"monthly_due_amount"`
+	FiscalYearStart  int     `json:"fiscal_year_start"` // month (1-12) the accounting year starts in
 	ManagerID      string    `json:"manager_id"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
@@ -16,13 +17,24 @@ type Organization struct {
 type CreateRequest struct {
 	Name             string  `json:"name"`
 	Address          string  `json:"address"`
-	TotalUnits       int     `json:"total_units"`
 	MonthlyDueAmount float64 `json:"monthly_due_amount"`
 }
 
 type UpdateRequest struct {
 	Name             *string  `json:"name,omitempty"`
 	Address          *string  `json:"address,omitempty"`
-	TotalUnits       *int     `json:"total_units,omitempty"`
 	MonthlyDueAmount *float64 `json:"monthly_due_amount,omitempty"`
+	FiscalYearStart  *int     `json:"fiscal_year_start,omitempty"`
+}
+
+// BoardMember is a user sitting on the site's management board.
+type BoardMember struct {
+	UserID    string    `json:"user_id"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AddBoardMemberRequest struct {
+	Email string `json:"email"`
 }
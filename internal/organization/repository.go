@@ -2,7 +2,11 @@ package organization
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Repository struct {
@@ -15,55 +19,96 @@ func NewRepository(db *sql.DB) *Repository {
 
 func (r *Repository) Create(org *Organization) error {
 	query := `
-		INSERT INTO organizations (name, address, total_units, monthly_due_amount, manager_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO organizations (name, address, monthly_due_amount, manager_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, fiscal_year_start, created_at, updated_at`
 
 	return r.db.QueryRow(query,
-		org.Name, org.Address, org.TotalUnits, org.MonthlyDueAmount, org.ManagerID,
-	).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt)
+		org.Name, org.Address, org.MonthlyDueAmount, org.ManagerID,
+	).Scan(&org.ID, &org.FiscalYearStart, &org.CreatedAt, &org.UpdatedAt)
+}
+
+// ErrNotFound is returned when no organization has the given ID.
+var ErrNotFound = errors.New("organization not found")
+
+// Header returns the site name and address printed on documents.
+func Header(db database.Querier, orgID string) (name, address string, err error) {
+	err = db.QueryRow("SELECT name, address FROM organizations WHERE id = $1", orgID).Scan(&name, &address)
+	if err == sql.ErrNoRows {
+		return "", "", ErrNotFound
+	}
+	return name, address, err
+}
+
+// total_units is not stored; it is counted from the organization's units.
+const selectOrganization = `SELECT o.id, o.name, o.address,
+		(SELECT COUNT(*) FROM units u WHERE u.organization_id = o.id) as total_units,
+		o.monthly_due_amount, o.fiscal_year_start, o.manager_id, o.created_at, o.updated_at
+		FROM organizations o`
+
+// FiscalYearStart returns the month the organization's accounting year
+// starts in.
+func FiscalYearStart(db database.Querier, orgID string) (int, error) {
+	var month int
+	err := db.QueryRow("SELECT fiscal_year_start FROM organizations WHERE id = $1", orgID).Scan(&month)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return month, err
+}
+
+// IsBoardMember reports whether the user is the organization's manager or
+// sits on its board.
+func IsBoardMember(db database.Querier, orgID, userID string) (bool, error) {
+	var ok bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1 AND manager_id = $2)
+		OR EXISTS (SELECT 1 FROM organization_board_members WHERE organization_id = $1 AND user_id = $2)`,
+		orgID, userID).Scan(&ok)
+	return ok, err
 }
 
 func (r *Repository) GetByID(id string) (*Organization, error) {
 	org := &Organization{}
-	query := `SELECT id, name, address, total_units, monthly_due_amount, manager_id, created_at, updated_at
-		FROM organizations WHERE id = $1`
+	query := selectOrganization + " WHERE o.id = $1"
 
 	err := r.db.QueryRow(query, id).Scan(
 		&org.ID, &org.Name, &org.Address, &org.TotalUnits,
-		&org.MonthlyDueAmount, &org.ManagerID, &org.CreatedAt, &org.UpdatedAt,
+		&org.MonthlyDueAmount, &org.FiscalYearStart, &org.ManagerID, &org.CreatedAt, &org.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("organization not found")
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 	return org, nil
 }
 
-func (r *Repository) ListByManager(managerID string) ([]Organization, error) {
-	query := `SELECT id, name, address, total_units, monthly_due_amount, manager_id, created_at, updated_at
-		FROM organizations WHERE manager_id = $1 ORDER BY name`
-
-	rows, err := r.db.Query(query, managerID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"name":       "o.name",
+		"created_at": "o.created_at",
+	},
+	Default: "name",
+	ID:      "o.id",
+	Search:  []string{"o.name", "o.address"},
+}
 
-	var orgs []Organization
-	for rows.Next() {
+func (r *Repository) ListByManager(managerID string, p *listing.Params) ([]Organization, listing.Result, error) {
+	query, args := p.Filter(selectOrganization+" WHERE o.manager_id = $1", []interface{}{managerID})
+	orgs := []Organization{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
 		var org Organization
 		if err := rows.Scan(
 			&org.ID, &org.Name, &org.Address, &org.TotalUnits,
-			&org.MonthlyDueAmount, &org.ManagerID, &org.CreatedAt, &org.UpdatedAt,
+			&org.MonthlyDueAmount, &org.FiscalYearStart, &org.ManagerID, &org.CreatedAt, &org.UpdatedAt,
 		); err != nil {
-			return nil, err
+			return err
 		}
 		orgs = append(orgs, org)
-	}
-	return orgs, nil
+		return nil
+	})
+	return orgs, res, err
 }
 
 func (r *Repository) Update(id string, req UpdateRequest) (*Organization, error) {
@@ -78,17 +123,17 @@ func (r *Repository) Update(id string, req UpdateRequest) (*Organization, error)
 	if req.Address != nil {
 		org.Address = *req.Address
 	}
-	if req.TotalUnits != nil {
-		org.TotalUnits = *req.TotalUnits
-	}
 	if req.MonthlyDueAmount != nil {
 		org.MonthlyDueAmount = *req.MonthlyDueAmount
 	}
+	if req.FiscalYearStart != nil {
+		org.FiscalYearStart = *req.FiscalYearStart
+	}
 
-	query := `UPDATE organizations SET name=$1, address=$2, total_units=$3, monthly_due_amount=$4, updated_at=NOW()
+	query := `UPDATE organizations SET name=$1, address=$2, monthly_due_amount=$3, fiscal_year_start=$4, updated_at=NOW()
 		WHERE id=$5 RETURNING updated_at`
 
-	err = r.db.QueryRow(query, org.Name, org.Address, org.TotalUnits, org.MonthlyDueAmount, id).Scan(&org.UpdatedAt)
+	err = r.db.QueryRow(query, org.Name, org.Address, org.MonthlyDueAmount, org.FiscalYearStart, id).Scan(&org.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -99,3 +144,58 @@ func (r *Repository) Delete(id string) error {
 	_, err := r.db.Exec("DELETE FROM organizations WHERE id = $1", id)
 	return err
 }
+
+func (r *Repository) ListBoard(orgID string) ([]BoardMember, error) {
+	rows, err := r.db.Query(`SELECT u.id, u.full_name, u.email, b.created_at
+		FROM organization_board_members b
+		JOIN users u ON b.user_id = u.id
+		WHERE b.organization_id = $1
+		ORDER BY u.full_name`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []BoardMember{}
+	for rows.Next() {
+		var m BoardMember
+		if err := rows.Scan(&m.UserID, &m.FullName, &m.Email, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddBoardMember seats the user with the given email on the board; adding
+// a member twice keeps the first entry.
+func (r *Repository) AddBoardMember(orgID, email string) (*BoardMember, error) {
+	m := &BoardMember{}
+	err := r.db.QueryRow(`SELECT id, full_name, email FROM users WHERE lower(email) = lower($1)`, email).
+		Scan(&m.UserID, &m.FullName, &m.Email)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no user with email %s", email)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(`INSERT INTO organization_board_members (organization_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET created_at = organization_board_members.created_at
+		RETURNING created_at`, orgID, m.UserID).Scan(&m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *Repository) RemoveBoardMember(orgID, userID string) error {
+	result, err := r.db.Exec("DELETE FROM organization_board_members WHERE organization_id = $1 AND user_id = $2", orgID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("board member not found")
+	}
+	return nil
+}
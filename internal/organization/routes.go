@@ -9,5 +9,8 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 		r.Get("/{id}", h.Get)
 		r.Put("/{id}", h.Update)
 		r.Delete("/{id}", h.Delete)
+		r.Get("/{id}/board", h.ListBoard)
+		r.Post("/{id}/board", h.AddBoardMember)
+		r.Delete("/{id}/board/{userId}", h.RemoveBoardMember)
 	})
 }
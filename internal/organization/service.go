@@ -1,6 +1,10 @@
 package organization
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
 
 type Service struct {
 	repo *Repository
@@ -18,7 +22,6 @@ func (s *Service) Create(managerID string, req CreateRequest) (*Organization, er
 	org := &Organization{
 		Name:             req.Name,
 		Address:          req.Address,
-		TotalUnits:       req.TotalUnits,
 		MonthlyDueAmount: req.MonthlyDueAmount,
 		ManagerID:        managerID,
 	}
@@ -33,14 +36,42 @@ func (s *Service) GetByID(id string) (*Organization, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *Service) ListByManager(managerID string) ([]Organization, error) {
-	return s.repo.ListByManager(managerID)
+func (s *Service) ListByManager(managerID string, p *listing.Params) ([]Organization, listing.Result, error) {
+	return s.repo.ListByManager(managerID, p)
 }
 
 func (s *Service) Update(id string, req UpdateRequest) (*Organization, error) {
+	if req.FiscalYearStart != nil && (*req.FiscalYearStart < 1 || *req.FiscalYearStart > 12) {
+		return nil, fmt.Errorf("fiscal_year_start must be a month between 1 and 12")
+	}
 	return s.repo.Update(id, req)
 }
 
 func (s *Service) Delete(id string) error {
 	return s.repo.Delete(id)
 }
+
+func (s *Service) ListBoard(orgID string) ([]BoardMember, error) {
+	return s.repo.ListBoard(orgID)
+}
+
+// IsManager reports whether the user manages the organization; only the
+// manager changes the board.
+func (s *Service) IsManager(orgID, userID string) (bool, error) {
+	org, err := s.repo.GetByID(orgID)
+	if err != nil {
+		return false, err
+	}
+	return org.ManagerID == userID, nil
+}
+
+func (s *Service) AddBoardMember(orgID string, req AddBoardMemberRequest) (*BoardMember, error) {
+	if req.Email == "" {
+		return nil, fmt.Errorf("email is required")
+	}
+	return s.repo.AddBoardMember(orgID, req.Email)
+}
+
+func (s *Service) RemoveBoardMember(orgID, userID string) error {
+	return s.repo.RemoveBoardMember(orgID, userID)
+}
@@ -0,0 +1,74 @@
+package paymentplan
+
+import "time"
+
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusBroken    = "broken"
+	StatusCancelled = "cancelled"
+)
+
+// Plan is a restructuring agreement: a unit's overdue dues are consolidated
+// and repaid in new monthly installments.
+type Plan struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	UnitID         string     `json:"unit_id"`
+	UnitNumber     string     `json:"unit_number,omitempty"`
+	Status         string     `json:"status"` // active, completed, broken, cancelled
+	OriginalAmount float64    `json:"original_amount"`
+	WaivedAmount   float64    `json:"waived_amount"`
+	TotalAmount    float64    `json:"total_amount"`
+	PaidAmount     float64    `json:"paid_amount"`
+	Installments   int        `json:"installments"`
+	FirstDueDate   time.Time  `json:"first_due_date"`
+	GraceDays      int        `json:"grace_days"`
+	Note           string     `json:"note,omitempty"`
+	CreatedBy      *string    `json:"created_by,omitempty"`
+	BrokenAt       *time.Time `json:"broken_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	OriginalDues []PlanDue `json:"original_dues,omitempty"`
+	Schedule     []PlanDue `json:"schedule,omitempty"`
+}
+
+// PlanDue is either a consolidated original due or a plan installment.
+type PlanDue struct {
+	DueID         string     `json:"due_id"`
+	Type          string     `json:"type"`
+	InstallmentNo *int       `json:"installment_no,omitempty"`
+	Amount        float64    `json:"amount"`
+	DueDate       time.Time  `json:"due_date"`
+	Status        string     `json:"status"`
+	PaidAt        *time.Time `json:"paid_at,omitempty"`
+	Description   string     `json:"description,omitempty"`
+}
+
+type CreateRequest struct {
+	UnitID       string   `json:"unit_id"`
+	DueIDs       []string `json:"due_ids"`
+	WaivedAmount float64  `json:"waived_amount,omitempty"` // penalty forgiven
+	Installments int      `json:"installments"`
+	FirstDueDate string   `json:"first_due_date"`       // YYYY-MM-DD
+	GraceDays    *int     `json:"grace_days,omitempty"` // defaults to 15
+	Note         string   `json:"note,omitempty"`
+}
+
+type ListFilter struct {
+	OrganizationID string
+	Status         string
+	UnitID         string
+}
+
+// brokenPlan carries what the manager alert needs.
+type brokenPlan struct {
+	ID               string
+	OrganizationName string
+	UnitNumber       string
+	ManagerName      string
+	ManagerPhone     string
+	Overdue          float64
+}
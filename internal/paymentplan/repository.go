@@ -0,0 +1,360 @@
+package paymentplan
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectPlan = `SELECT p.id, p.organization_id, p.unit_id, COALESCE(u.unit_number, '') as unit_number,
+		p.status, p.original_amount, p.waived_amount, p.total_amount,
+		COALESCE((SELECT SUM(d.amount - d.adjustment_total) FROM dues d
+			WHERE d.payment_plan_id = p.id AND d.status = 'paid'), 0) as paid_amount,
+		p.installments, p.first_due_date, p.grace_days, p.note, p.created_by,
+		p.broken_at, p.completed_at, p.created_at, p.updated_at
+		FROM payment_plans p
+		LEFT JOIN units u ON p.unit_id = u.id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlan(row scanner, p *Plan) error {
+	return row.Scan(
+		&p.ID, &p.OrganizationID, &p.UnitID, &p.UnitNumber,
+		&p.Status, &p.OriginalAmount, &p.WaivedAmount, &p.TotalAmount, &p.PaidAmount,
+		&p.Installments, &p.FirstDueDate, &p.GraceDays, &p.Note, &p.CreatedBy,
+		&p.BrokenAt, &p.CompletedAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+// Create consolidates the given overdue dues of a unit into a plan and
+// issues its installment dues, all in one transaction. The original dues
+// are kept and marked restructured.
+func (r *Repository) Create(p *Plan, dueIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, type, amount - adjustment_total FROM dues
+		WHERE id = ANY($1) AND organization_id = $2 AND unit_id = $3 AND status IN ('pending', 'overdue')
+		FOR UPDATE`, pq.Array(dueIDs), p.OrganizationID, p.UnitID)
+	if err != nil {
+		return err
+	}
+	var found int
+	var original int64
+	dueType, mixed := "", false
+	for rows.Next() {
+		var id, t string
+		var amount float64
+		if err := rows.Scan(&id, &t, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		found++
+		original += dues.ToKurus(amount)
+		if dueType == "" {
+			dueType = t
+		} else if dueType != t {
+			mixed = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if found != len(dueIDs) {
+		return fmt.Errorf("all dues must be unpaid dues of this unit")
+	}
+	// Installments are collected into the fund of the dues they replace,
+	// so each fund needs its own plan.
+	if mixed {
+		return fmt.Errorf("dues of different types cannot share a plan, create one plan per type")
+	}
+
+	waived := dues.ToKurus(p.WaivedAmount)
+	if waived >= original {
+		return fmt.Errorf("waived_amount must be less than the consolidated total")
+	}
+	p.OriginalAmount = float64(original) / 100
+	p.TotalAmount = float64(original-waived) / 100
+	p.Status = StatusActive
+
+	query := `
+		INSERT INTO payment_plans (organization_id, unit_id, status, original_amount, waived_amount, total_amount,
+			installments, first_due_date, grace_days, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+	err = tx.QueryRow(query,
+		p.OrganizationID, p.UnitID, p.Status, p.OriginalAmount, p.WaivedAmount, p.TotalAmount,
+		p.Installments, p.FirstDueDate, p.GraceDays, p.Note, p.CreatedBy,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO payment_plan_dues (plan_id, due_id)
+		SELECT $1, unnest($2::uuid[])`, p.ID, pq.Array(dueIDs)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE dues SET status = 'restructured', updated_at = NOW()
+		WHERE id = ANY($1)`, pq.Array(dueIDs)); err != nil {
+		return err
+	}
+
+	insert := `
+		INSERT INTO dues (organization_id, unit_id, type, amount, due_date, status, description,
+			payment_plan_id, installment_no)
+		VALUES ($1, $2, $3, $4, $5, 'pending', $6, $7, $8)
+		RETURNING id`
+	// Installments carry the whole consolidated amount and the waived part
+	// is taken off each of them as a discount, so the waiver stays in the
+	// books while the original dues drop out as restructured.
+	net := dues.SplitKurus(original-waived, p.Installments)
+	discounts := dues.SplitKurus(waived, p.Installments)
+	ids := make([]string, 0, p.Installments)
+	for i := range net {
+		desc := fmt.Sprintf("Yapılandırma taksiti (%d/%d)", i+1, p.Installments)
+		var id string
+		if err := tx.QueryRow(insert,
+			p.OrganizationID, p.UnitID, dueType, float64(net[i]+discounts[i])/100,
+			dues.AddMonths(p.FirstDueDate, i), desc, p.ID, i+1,
+		).Scan(&id); err != nil {
+			return err
+		}
+		if discounts[i] > 0 {
+			if _, err := dues.DiscountTx(tx, id, 0, float64(discounts[i])/100,
+				"Yapılandırma kapsamında silinen tutar", p.CreatedBy); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, id)
+	}
+	if err := dues.OnIssued(tx, ids); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) GetByID(id string) (*Plan, error) {
+	p := &Plan{}
+	if err := scanPlan(r.db.QueryRow(selectPlan+" WHERE p.id = $1", id), p); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("payment plan not found")
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// listConfig is what the plan list can be sorted and searched by; ?q=
+// matches the unit number and the note.
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"created_at":     "p.created_at",
+		"first_due_date": "p.first_due_date",
+		"total_amount":   "p.total_amount",
+		"status":         "p.status",
+	},
+	Default: "-created_at",
+	ID:      "p.id",
+	Search:  []string{"u.unit_number", "p.note"},
+	Date:    "p.created_at",
+}
+
+func (r *Repository) List(filter ListFilter, params *listing.Params) ([]Plan, listing.Result, error) {
+	query := selectPlan + " WHERE p.organization_id = $1"
+	args := []interface{}{filter.OrganizationID}
+	argIdx := 2
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND p.status = $%d", argIdx)
+		args = append(args, filter.Status)
+		argIdx++
+	}
+	if filter.UnitID != "" {
+		query += fmt.Sprintf(" AND p.unit_id = $%d", argIdx)
+		args = append(args, filter.UnitID)
+		argIdx++
+	}
+
+	query, args = params.Filter(query, args)
+	plans := []Plan{}
+	res, err := params.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var p Plan
+		if err := scanPlan(rows, &p); err != nil {
+			return err
+		}
+		plans = append(plans, p)
+		return nil
+	})
+	return plans, res, err
+}
+
+func (r *Repository) listDues(query string, planID string) ([]PlanDue, error) {
+	rows, err := r.db.Query(query, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []PlanDue
+	for rows.Next() {
+		var d PlanDue
+		if err := rows.Scan(&d.DueID, &d.Type, &d.InstallmentNo, &d.Amount, &d.DueDate,
+			&d.Status, &d.PaidAt, &d.Description); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+// ListOriginalDues returns the dues that were consolidated into the plan.
+func (r *Repository) ListOriginalDues(planID string) ([]PlanDue, error) {
+	return r.listDues(`SELECT d.id, d.type, d.installment_no, d.amount, d.due_date, d.status, d.paid_at,
+		COALESCE(d.description, '')
+		FROM payment_plan_dues pd
+		JOIN dues d ON pd.due_id = d.id
+		WHERE pd.plan_id = $1
+		ORDER BY d.due_date`, planID)
+}
+
+// ListSchedule returns the installments issued under the plan, at what is
+// owed on them after the waiver.
+func (r *Repository) ListSchedule(planID string) ([]PlanDue, error) {
+	return r.listDues(`SELECT id, type, installment_no, amount - adjustment_total, due_date, status, paid_at,
+		COALESCE(description, '')
+		FROM dues
+		WHERE payment_plan_id = $1
+		ORDER BY installment_no`, planID)
+}
+
+// Cancel voids a plan nothing has been paid on yet: its installments are
+// voided, which gives the credits they used back to the unit's credit
+// notes, and the original dues become collectable again.
+func (r *Repository) Cancel(id string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM payment_plans WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("payment plan not found")
+	}
+	if err != nil {
+		return err
+	}
+	if status != StatusActive && status != StatusBroken {
+		return fmt.Errorf("plan is already %s", status)
+	}
+
+	var paid int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM dues WHERE payment_plan_id = $1 AND status = 'paid'`,
+		id).Scan(&paid); err != nil {
+		return err
+	}
+	if paid > 0 {
+		return fmt.Errorf("plan has paid installments and cannot be cancelled")
+	}
+
+	rows, err := tx.Query(`SELECT id FROM dues WHERE payment_plan_id = $1 AND status IN ('pending', 'overdue')`, id)
+	if err != nil {
+		return err
+	}
+	var installments []string
+	for rows.Next() {
+		var dueID string
+		if err := rows.Scan(&dueID); err != nil {
+			rows.Close()
+			return err
+		}
+		installments = append(installments, dueID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, dueID := range installments {
+		if err := dues.VoidTx(tx, dueID, "Ödeme planı iptal edildi", nil); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE dues SET
+			status = CASE WHEN due_date < CURRENT_DATE THEN 'overdue' ELSE 'pending' END,
+			updated_at = NOW()
+		WHERE id IN (SELECT due_id FROM payment_plan_dues WHERE plan_id = $1)`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE payment_plans SET status = $1, updated_at = NOW() WHERE id = $2`,
+		StatusCancelled, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CompletePaidPlans closes plans whose installments have all been paid.
+func (r *Repository) CompletePaidPlans() (int, error) {
+	result, err := r.db.Exec(`UPDATE payment_plans p SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE p.status IN ('active', 'broken')
+			AND NOT EXISTS (SELECT 1 FROM dues d WHERE d.payment_plan_id = p.id AND d.status IN ('pending', 'overdue'))`)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// BreakLatePlans marks active plans broken when an installment is unpaid
+// past its grace period, and returns them for alerting.
+func (r *Repository) BreakLatePlans(day time.Time) ([]brokenPlan, error) {
+	query := `WITH broken AS (
+			UPDATE payment_plans p SET status = 'broken', broken_at = NOW(), updated_at = NOW()
+			WHERE p.status = 'active'
+				AND EXISTS (SELECT 1 FROM dues d WHERE d.payment_plan_id = p.id
+					AND d.status IN ('pending', 'overdue') AND d.due_date + p.grace_days < $1::date)
+			RETURNING p.id, p.organization_id, p.unit_id
+		)
+		SELECT b.id, o.name, COALESCE(u.unit_number, ''), us.full_name, us.phone,
+			COALESCE((SELECT SUM(d.amount - d.adjustment_total) FROM dues d
+				WHERE d.payment_plan_id = b.id AND d.status IN ('pending', 'overdue') AND d.due_date < $1::date), 0)
+		FROM broken b
+		JOIN organizations o ON b.organization_id = o.id
+		JOIN users us ON o.manager_id = us.id
+		LEFT JOIN units u ON b.unit_id = u.id`
+
+	rows, err := r.db.Query(query, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []brokenPlan
+	for rows.Next() {
+		var b brokenPlan
+		if err := rows.Scan(&b.ID, &b.OrganizationName, &b.UnitNumber,
+			&b.ManagerName, &b.ManagerPhone, &b.Overdue); err != nil {
+			return nil, err
+		}
+		plans = append(plans, b)
+	}
+	return plans, nil
+}
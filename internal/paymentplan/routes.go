@@ -0,0 +1,12 @@
+package paymentplan
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/payment-plans", func(r chi.Router) {
+		r.Post("/", h.Create)
+		r.Get("/", h.List)
+		r.Get("/{id}", h.GetByID)
+		r.Post("/{id}/cancel", h.Cancel)
+	})
+}
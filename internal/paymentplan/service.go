@@ -0,0 +1,113 @@
+package paymentplan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/notification"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+const defaultGraceDays = 15
+
+type Service struct {
+	repo          *Repository
+	notifications *notification.Service
+}
+
+func NewService(repo *Repository, notifications *notification.Service) *Service {
+	return &Service{repo: repo, notifications: notifications}
+}
+
+func (s *Service) Create(orgID, userID string, req CreateRequest) (*Plan, error) {
+	if req.UnitID == "" || len(req.DueIDs) == 0 || req.Installments <= 0 || req.FirstDueDate == "" {
+		return nil, fmt.Errorf("unit_id, due_ids, installments, and first_due_date are required")
+	}
+	if req.WaivedAmount < 0 {
+		return nil, fmt.Errorf("waived_amount cannot be negative")
+	}
+	first, err := time.Parse("2006-01-02", req.FirstDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid first_due_date format, use YYYY-MM-DD")
+	}
+
+	graceDays := defaultGraceDays
+	if req.GraceDays != nil {
+		if *req.GraceDays < 0 {
+			return nil, fmt.Errorf("grace_days cannot be negative")
+		}
+		graceDays = *req.GraceDays
+	}
+
+	seen := make(map[string]bool)
+	var dueIDs []string
+	for _, id := range req.DueIDs {
+		if !seen[id] {
+			seen[id] = true
+			dueIDs = append(dueIDs, id)
+		}
+	}
+
+	p := &Plan{
+		OrganizationID: orgID,
+		UnitID:         req.UnitID,
+		WaivedAmount:   req.WaivedAmount,
+		Installments:   req.Installments,
+		FirstDueDate:   first,
+		GraceDays:      graceDays,
+		Note:           req.Note,
+	}
+	if userID != "" {
+		p.CreatedBy = &userID
+	}
+	if err := s.repo.Create(p, dueIDs); err != nil {
+		return nil, err
+	}
+	return s.GetByID(p.ID)
+}
+
+// GetByID returns the plan with its consolidated dues and installments.
+func (s *Service) GetByID(id string) (*Plan, error) {
+	p, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.OriginalDues, err = s.repo.ListOriginalDues(id); err != nil {
+		return nil, err
+	}
+	if p.Schedule, err = s.repo.ListSchedule(id); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) List(filter ListFilter, params *listing.Params) ([]Plan, listing.Result, error) {
+	return s.repo.List(filter, params)
+}
+
+func (s *Service) Cancel(id string) error {
+	return s.repo.Cancel(id)
+}
+
+// CheckPlans completes fully paid plans and breaks plans with an installment
+// unpaid past the grace period, alerting the site manager about each one.
+func (s *Service) CheckPlans() (int, error) {
+	if _, err := s.repo.CompletePaidPlans(); err != nil {
+		return 0, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	broken, err := s.repo.BreakLatePlans(today)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, b := range broken {
+		msg := fmt.Sprintf("Sayın %s, %s sitesinde %s numaralı dairenin yapılandırma planı bozuldu. Gecikmiş taksit tutarı: %.2f TL.",
+			b.ManagerName, b.OrganizationName, b.UnitNumber, b.Overdue)
+		if err := s.notifications.SendSMS(b.ManagerPhone, msg); err != nil {
+			return len(broken), err
+		}
+	}
+	return len(broken), nil
+}
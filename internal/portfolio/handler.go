@@ -0,0 +1,46 @@
+package portfolio
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/pkg/middleware"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	p, err := h.service.Get(userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) Detail(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	orgID := chi.URLParam(r, "orgId")
+	d, err := h.service.Detail(userID, orgID)
+	if errors.Is(err, organization.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, d)
+}
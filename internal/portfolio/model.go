@@ -0,0 +1,75 @@
+package portfolio
+
+import (
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/report"
+)
+
+// Reasons a site is flagged for attention.
+const (
+	FlagLowCollection = "low_collection" // collection rate below LowCollectionRate
+	FlagOldDebt       = "old_debt"       // dues more than 90 days overdue
+	FlagOverdueUnits  = "overdue_units"  // a large share of units is behind
+	FlagCashShortfall = "cash_shortfall" // cash does not cover the bills due soon
+	FlagNegativeCash  = "negative_cash"
+	FlagOverdueBills  = "overdue_bills" // approved bills past their due date
+)
+
+// Site is one organization's row of the portfolio. CollectionRate covers
+// the dues that fell due in the last twelve months.
+type Site struct {
+	OrganizationID string   `json:"organization_id"`
+	Name           string   `json:"name"`
+	Units          int      `json:"units"`
+	Receivable     float64  `json:"receivable"` // unpaid dues already due
+	Overdue        float64  `json:"overdue"`
+	OverdueCount   int      `json:"overdue_count"`
+	OverdueUnits   int      `json:"overdue_units"`
+	Over90         float64  `json:"over_90"` // overdue by more than 90 days
+	CollectionRate float64  `json:"collection_rate"`
+	Cash           float64  `json:"cash"`              // all accounts today
+	Upcoming       float64  `json:"upcoming_expenses"` // approved bills due in the next 30 days
+	UpcomingCount  int      `json:"upcoming_count"`
+	OverdueBills   float64  `json:"overdue_bills"` // approved bills past their due date
+	Score          int      `json:"attention_score"`
+	Flags          []string `json:"flags"`
+}
+
+// Summary adds up the sites of a portfolio.
+type Summary struct {
+	Sites          int     `json:"sites"`
+	Units          int     `json:"units"`
+	Receivable     float64 `json:"receivable"`
+	Overdue        float64 `json:"overdue"`
+	OverdueCount   int     `json:"overdue_count"`
+	CollectionRate float64 `json:"collection_rate"`
+	Cash           float64 `json:"cash"`
+	Upcoming       float64 `json:"upcoming_expenses"`
+	NeedsAttention int     `json:"needs_attention"` // sites with at least one flag
+}
+
+// Portfolio lists the sites a manager runs, those needing attention first.
+type Portfolio struct {
+	Summary Summary `json:"summary"`
+	Sites   []Site  `json:"sites"`
+}
+
+// Bill is an approved expense still to be paid.
+type Bill struct {
+	ExpenseID   string     `json:"expense_id"`
+	Description string     `json:"description"`
+	VendorName  string     `json:"vendor_name,omitempty"`
+	Amount      float64    `json:"amount"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+}
+
+// Detail is the drill-down of one site: its portfolio row, the last twelve
+// months, its largest debtors and the bills to pay.
+type Detail struct {
+	Site    Site                `json:"site"`
+	Trends  []report.TrendPoint `json:"trends"`
+	Debtors []dues.AgingRow     `json:"debtors"`
+	Bills   []Bill              `json:"bills"`
+}
@@ -0,0 +1,10 @@
+package portfolio
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/portfolio", func(r chi.Router) {
+		r.Get("/", h.Get)
+		r.Get("/{orgId}", h.Detail)
+	})
+}
@@ -0,0 +1,252 @@
+package portfolio
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/internal/report"
+)
+
+// Thresholds of the attention flags.
+const (
+	LowCollectionRate = 85.0 // percent
+	OverdueUnitsShare = 0.25 // of all units
+	UpcomingDays      = 30
+)
+
+type Service struct {
+	db      *sql.DB
+	reports *report.Service
+	dues    *dues.Service
+}
+
+func NewService(db *sql.DB, reports *report.Service, dues *dues.Service) *Service {
+	return &Service{db: db, reports: reports, dues: dues}
+}
+
+// siteQuery aggregates every site of a manager in one statement: each part
+// reads only the rows of those sites and is grouped once, so the cost grows
+// with the data of the sites rather than with the number of round trips.
+// $2 narrows it to one organization.
+const siteQuery = `
+	WITH orgs AS (
+		SELECT id, name FROM organizations
+		WHERE manager_id = $1 AND ($2 = '' OR id::text = $2)
+	),
+	unit_counts AS (
+		SELECT organization_id, COUNT(*) AS units
+		FROM units WHERE organization_id IN (SELECT id FROM orgs)
+		GROUP BY organization_id
+	),
+	open_dues AS (
+		SELECT organization_id,
+			SUM(amount - adjustment_total) AS receivable,
+			COALESCE(SUM(amount - adjustment_total) FILTER (WHERE due_date < CURRENT_DATE), 0) AS overdue,
+			COUNT(*) FILTER (WHERE due_date < CURRENT_DATE) AS overdue_count,
+			COUNT(DISTINCT unit_id) FILTER (WHERE due_date < CURRENT_DATE) AS overdue_units,
+			COALESCE(SUM(amount - adjustment_total) FILTER (WHERE due_date < CURRENT_DATE - 90), 0) AS over_90
+		FROM dues
+		WHERE organization_id IN (SELECT id FROM orgs) AND status IN ('pending', 'overdue')
+			AND due_date <= CURRENT_DATE
+		GROUP BY organization_id
+	),
+	billed AS (
+		SELECT organization_id,
+			SUM(amount - adjustment_total) AS net_dues,
+			COALESCE(SUM(amount - adjustment_total) FILTER (WHERE status = 'paid'), 0) AS collected
+		FROM dues
+		WHERE organization_id IN (SELECT id FROM orgs) AND status <> 'restructured'
+			AND due_date > CURRENT_DATE - INTERVAL '12 months' AND due_date <= CURRENT_DATE
+		GROUP BY organization_id
+	),
+	opening AS (
+		SELECT organization_id, SUM(opening_balance) AS amount
+		FROM accounts WHERE organization_id IN (SELECT id FROM orgs) AND opening_date <= CURRENT_DATE
+		GROUP BY organization_id
+	),
+	entries AS (
+		SELECT organization_id, SUM(amount) AS amount
+		FROM account_entries WHERE organization_id IN (SELECT id FROM orgs) AND date <= CURRENT_DATE
+		GROUP BY organization_id
+	),
+	bills AS (
+		SELECT organization_id,
+			COALESCE(SUM(amount) FILTER (WHERE COALESCE(due_date, CURRENT_DATE) BETWEEN CURRENT_DATE AND CURRENT_DATE + $3), 0) AS upcoming,
+			COUNT(*) FILTER (WHERE COALESCE(due_date, CURRENT_DATE) BETWEEN CURRENT_DATE AND CURRENT_DATE + $3) AS upcoming_count,
+			COALESCE(SUM(amount) FILTER (WHERE due_date < CURRENT_DATE), 0) AS overdue_bills
+		FROM expenses
+		WHERE organization_id IN (SELECT id FROM orgs) AND status = 'approved'
+		GROUP BY organization_id
+	)
+	SELECT o.id, o.name, COALESCE(u.units, 0),
+		COALESCE(od.receivable, 0), COALESCE(od.overdue, 0), COALESCE(od.overdue_count, 0),
+		COALESCE(od.overdue_units, 0), COALESCE(od.over_90, 0),
+		COALESCE(bd.net_dues, 0), COALESCE(bd.collected, 0),
+		COALESCE(op.amount, 0) + COALESCE(en.amount, 0),
+		COALESCE(bl.upcoming, 0), COALESCE(bl.upcoming_count, 0), COALESCE(bl.overdue_bills, 0)
+	FROM orgs o
+	LEFT JOIN unit_counts u ON u.organization_id = o.id
+	LEFT JOIN open_dues od ON od.organization_id = o.id
+	LEFT JOIN billed bd ON bd.organization_id = o.id
+	LEFT JOIN opening op ON op.organization_id = o.id
+	LEFT JOIN entries en ON en.organization_id = o.id
+	LEFT JOIN bills bl ON bl.organization_id = o.id
+	ORDER BY o.name`
+
+// Get returns the portfolio of every organization the user manages, the
+// sites that need attention most first.
+func (s *Service) Get(userID string) (*Portfolio, error) {
+	sites, netDues, collected, err := s.sites(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sites, func(i, j int) bool {
+		if sites[i].Score != sites[j].Score {
+			return sites[i].Score > sites[j].Score
+		}
+		return sites[i].Overdue > sites[j].Overdue
+	})
+
+	p := &Portfolio{Sites: sites}
+	sum := &p.Summary
+	sum.Sites = len(sites)
+	for _, site := range sites {
+		sum.Units += site.Units
+		sum.Receivable += site.Receivable
+		sum.Overdue += site.Overdue
+		sum.OverdueCount += site.OverdueCount
+		sum.Cash += site.Cash
+		sum.Upcoming += site.Upcoming
+		if len(site.Flags) > 0 {
+			sum.NeedsAttention++
+		}
+	}
+	sum.CollectionRate = report.CollectionRate(collected, netDues)
+	return p, nil
+}
+
+// Detail drills down into one site of the user's portfolio: its row, the
+// last twelve months, the ten units most behind and the bills to pay.
+func (s *Service) Detail(userID, orgID string) (*Detail, error) {
+	sites, _, _, err := s.sites(userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, organization.ErrNotFound
+	}
+	d := &Detail{Site: sites[0]}
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	trends, err := s.reports.GetTrends(orgID, to.AddDate(0, -11, 0), to, report.GroupNone)
+	if err != nil {
+		return nil, err
+	}
+	if len(trends.Series) > 0 {
+		d.Trends = trends.Series[0].Points
+	}
+
+	aging, err := s.dues.Aging(dues.AgingFilter{OrganizationID: orgID, Sort: "overdue", Desc: true})
+	if err != nil {
+		return nil, err
+	}
+	d.Debtors = []dues.AgingRow{}
+	for _, a := range aging.Rows {
+		if a.Overdue <= 0 || len(d.Debtors) == 10 {
+			break
+		}
+		d.Debtors = append(d.Debtors, a)
+	}
+
+	if d.Bills, err = s.bills(orgID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// sites runs siteQuery and flags each site. It also returns the billed and
+// collected totals behind the collection rates so the portfolio's overall
+// rate is weighted by site size.
+func (s *Service) sites(userID, orgID string) ([]Site, float64, float64, error) {
+	rows, err := s.db.Query(siteQuery, userID, orgID, UpcomingDays)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	sites := []Site{}
+	var totalDues, totalCollected float64
+	for rows.Next() {
+		var site Site
+		var netDues, collected float64
+		if err := rows.Scan(&site.OrganizationID, &site.Name, &site.Units,
+			&site.Receivable, &site.Overdue, &site.OverdueCount, &site.OverdueUnits, &site.Over90,
+			&netDues, &collected, &site.Cash,
+			&site.Upcoming, &site.UpcomingCount, &site.OverdueBills); err != nil {
+			return nil, 0, 0, err
+		}
+		site.CollectionRate = report.CollectionRate(collected, netDues)
+		flag(&site, netDues > 0)
+		totalDues += netDues
+		totalCollected += collected
+		sites = append(sites, site)
+	}
+	return sites, totalDues, totalCollected, rows.Err()
+}
+
+// flag sets the attention flags of a site and scores it; the score weighs
+// money at risk now (no cash, unpaid bills) above slow collection.
+func flag(site *Site, billed bool) {
+	site.Flags = []string{}
+	add := func(f string, score int) {
+		site.Flags = append(site.Flags, f)
+		site.Score += score
+	}
+	if site.Cash < 0 {
+		add(FlagNegativeCash, 40)
+	} else if site.Cash < site.Upcoming+site.OverdueBills {
+		add(FlagCashShortfall, 30)
+	}
+	if site.OverdueBills > 0 {
+		add(FlagOverdueBills, 15)
+	}
+	if billed && site.CollectionRate < LowCollectionRate {
+		add(FlagLowCollection, 10+int(LowCollectionRate-site.CollectionRate))
+	}
+	if site.Over90 > 0 {
+		add(FlagOldDebt, 10+int(20*site.Over90/site.Receivable))
+	}
+	if site.OverdueUnits > 0 && float64(site.OverdueUnits) >= OverdueUnitsShare*float64(site.Units) {
+		add(FlagOverdueUnits, 15)
+	}
+}
+
+// bills lists the approved expenses of a site still to be paid, the most
+// urgent first.
+func (s *Service) bills(orgID string) ([]Bill, error) {
+	rows, err := s.db.Query(`
+		SELECT e.id, e.description, COALESCE(v.name, ''), e.amount, e.due_date
+		FROM expenses e
+		LEFT JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.organization_id = $1 AND e.status = 'approved'
+		ORDER BY e.due_date NULLS FIRST, e.amount DESC
+		LIMIT 20`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bills := []Bill{}
+	for rows.Next() {
+		var b Bill
+		if err := rows.Scan(&b.ExpenseID, &b.Description, &b.VendorName, &b.Amount, &b.DueDate); err != nil {
+			return nil, err
+		}
+		bills = append(bills, b)
+	}
+	return bills, rows.Err()
+}
@@ -0,0 +1,101 @@
+package recurring
+
+import "time"
+
+const (
+	FrequencyMonthly   = "monthly"
+	FrequencyQuarterly = "quarterly"
+	FrequencyYearly    = "yearly"
+)
+
+// Template describes an expense that repeats every period, e.g. the
+// monthly invoice of the cleaning company.
+type Template struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	BlockID        *string    `json:"block_id,omitempty"` // generated expenses are charged to this block
+	BlockName      string     `json:"block_name,omitempty"`
+	VendorID       *string    `json:"vendor_id,omitempty"`
+	VendorName     string     `json:"vendor_name,omitempty"`
+	ContractID     *string    `json:"contract_id,omitempty"`
+	Category       string     `json:"category"`
+	Fund           string     `json:"fund"`
+	Amount         float64    `json:"amount"`
+	Description    string     `json:"description"`
+	Frequency      string     `json:"frequency"` // monthly, quarterly, yearly
+	DayOfMonth     int        `json:"day_of_month"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	NextRunDate    time.Time  `json:"next_run_date"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateTemplateRequest struct {
+	BlockID     string  `json:"block_id,omitempty"`
+	VendorID    string  `json:"vendor_id,omitempty"`
+	ContractID  string  `json:"contract_id,omitempty"`
+	Category    string  `json:"category"`
+	Fund        string  `json:"fund,omitempty"` // defaults to aidat
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description"`
+	Frequency   string  `json:"frequency"`
+	DayOfMonth  int     `json:"day_of_month"`
+	StartDate   string  `json:"start_date"`         // YYYY-MM-DD
+	EndDate     string  `json:"end_date,omitempty"` // YYYY-MM-DD
+}
+
+type UpdateTemplateRequest struct {
+	Amount      *float64 `json:"amount,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Category    *string  `json:"category,omitempty"`
+	EndDate     *string  `json:"end_date,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+type Contract struct {
+	ID                string     `json:"id"`
+	OrganizationID    string     `json:"organization_id"`
+	VendorID          *string    `json:"vendor_id,omitempty"`
+	VendorName        string     `json:"vendor_name,omitempty"`
+	Title             string     `json:"title"`
+	StartDate         time.Time  `json:"start_date"`
+	EndDate           *time.Time `json:"end_date,omitempty"`
+	Amount            float64    `json:"amount"`
+	RenewalNoticeDays int        `json:"renewal_notice_days"`
+	AutoRenew         bool       `json:"auto_renew"`
+	Notes             string     `json:"notes,omitempty"`
+	ReminderSentAt    *time.Time `json:"reminder_sent_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type CreateContractRequest struct {
+	VendorID          string  `json:"vendor_id,omitempty"`
+	Title             string  `json:"title"`
+	StartDate         string  `json:"start_date"`         // YYYY-MM-DD
+	EndDate           string  `json:"end_date,omitempty"` // YYYY-MM-DD
+	Amount            float64 `json:"amount"`
+	RenewalNoticeDays int     `json:"renewal_notice_days,omitempty"`
+	AutoRenew         bool    `json:"auto_renew"`
+	Notes             string  `json:"notes,omitempty"`
+}
+
+type UpdateContractRequest struct {
+	Title             *string  `json:"title,omitempty"`
+	EndDate           *string  `json:"end_date,omitempty"`
+	Amount            *float64 `json:"amount,omitempty"`
+	RenewalNoticeDays *int     `json:"renewal_notice_days,omitempty"`
+	AutoRenew         *bool    `json:"auto_renew,omitempty"`
+	Notes             *string  `json:"notes,omitempty"`
+}
+
+// expiringContract is a contract whose renewal reminder is due, together
+// with the manager who should receive it.
+type expiringContract struct {
+	Contract
+	OrganizationName string
+	ManagerName      string
+	ManagerPhone     string
+}
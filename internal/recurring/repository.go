@@ -0,0 +1,374 @@
+package recurring
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/vendors"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectTemplate = `SELECT t.id, t.organization_id, t.block_id, COALESCE(b.name, '') as block_name, t.vendor_id, COALESCE(v.name, '') as vendor_name,
+		t.contract_id, t.category, t.fund, t.amount, t.description, t.frequency, t.day_of_month,
+		t.start_date, t.end_date, t.next_run_date, t.active, t.created_at, t.updated_at
+		FROM recurring_expenses t
+		LEFT JOIN blocks b ON t.block_id = b.id
+		LEFT JOIN vendors v ON t.vendor_id = v.id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row scanner, t *Template) error {
+	return row.Scan(
+		&t.ID, &t.OrganizationID, &t.BlockID, &t.BlockName, &t.VendorID, &t.VendorName,
+		&t.ContractID, &t.Category, &t.Fund, &t.Amount, &t.Description, &t.Frequency, &t.DayOfMonth,
+		&t.StartDate, &t.EndDate, &t.NextRunDate, &t.Active, &t.CreatedAt, &t.UpdatedAt,
+	)
+}
+
+// CreateTemplate stores a template after checking that its block, vendor
+// and contract are the organization's own.
+func (r *Repository) CreateTemplate(t *Template) error {
+	if t.BlockID != nil {
+		if err := block.Belongs(r.db, *t.BlockID, t.OrganizationID); err != nil {
+			return err
+		}
+	}
+	if t.VendorID != nil {
+		if err := vendors.Belongs(r.db, *t.VendorID, t.OrganizationID); err != nil {
+			return err
+		}
+	}
+	if t.ContractID != nil {
+		var ok bool
+		if err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM contracts WHERE id = $1 AND organization_id = $2)",
+			*t.ContractID, t.OrganizationID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("contract not found")
+		}
+	}
+
+	query := `
+		INSERT INTO recurring_expenses (organization_id, vendor_id, contract_id, category, fund, amount, description,
+			frequency, day_of_month, start_date, end_date, next_run_date, active, block_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query,
+		t.OrganizationID, t.VendorID, t.ContractID, t.Category, t.Fund, t.Amount, t.Description,
+		t.Frequency, t.DayOfMonth, t.StartDate, t.EndDate, t.NextRunDate, t.Active, t.BlockID,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *Repository) GetTemplate(id string) (*Template, error) {
+	t := &Template{}
+	if err := scanTemplate(r.db.QueryRow(selectTemplate+" WHERE t.id = $1", id), t); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("recurring expense not found")
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+var templateList = &listing.Config{
+	Sorts: map[string]string{
+		"category":      "t.category",
+		"amount":        "t.amount",
+		"next_run_date": "t.next_run_date",
+		"created_at":    "t.created_at",
+	},
+	Default: "category",
+	ID:      "t.id",
+	Search:  []string{"t.category", "t.description", "v.name"},
+}
+
+func (r *Repository) ListTemplates(orgID string, p *listing.Params) ([]Template, listing.Result, error) {
+	query, args := p.Filter(selectTemplate+" WHERE t.organization_id = $1", []interface{}{orgID})
+	templates := []Template{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var t Template
+		if err := scanTemplate(rows, &t); err != nil {
+			return err
+		}
+		templates = append(templates, t)
+		return nil
+	})
+	return templates, res, err
+}
+
+func (r *Repository) UpdateTemplate(t *Template) error {
+	query := `UPDATE recurring_expenses SET amount=$1, description=$2, category=$3, end_date=$4, active=$5, updated_at=NOW()
+		WHERE id=$6 RETURNING updated_at`
+	return r.db.QueryRow(query, t.Amount, t.Description, t.Category, t.EndDate, t.Active, t.ID).Scan(&t.UpdatedAt)
+}
+
+func (r *Repository) DeleteTemplate(id string) error {
+	_, err := r.db.Exec("DELETE FROM recurring_expenses WHERE id = $1", id)
+	return err
+}
+
+// DueTemplateIDs returns active templates whose next run is on or before day.
+func (r *Repository) DueTemplateIDs(day time.Time) ([]string, error) {
+	rows, err := r.db.Query(`SELECT id FROM recurring_expenses WHERE active AND next_run_date <= $1`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GenerateDrafts creates draft expenses for every period of the template
+// up to and including day, then advances next_run_date. The template row
+// is locked so concurrent runs (several server instances) skip it instead
+// of generating twice.
+func (r *Repository) GenerateDrafts(id string, day time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	t := &Template{}
+	err = tx.QueryRow(`SELECT id, organization_id, block_id, vendor_id, category, fund, amount, description, frequency,
+			day_of_month, end_date, next_run_date
+		FROM recurring_expenses WHERE id = $1 AND active FOR UPDATE SKIP LOCKED`, id).Scan(
+		&t.ID, &t.OrganizationID, &t.BlockID, &t.VendorID, &t.Category, &t.Fund, &t.Amount, &t.Description, &t.Frequency,
+		&t.DayOfMonth, &t.EndDate, &t.NextRunDate,
+	)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for !t.NextRunDate.After(day) {
+		if t.EndDate != nil && t.NextRunDate.After(*t.EndDate) {
+			if _, err := tx.Exec(`UPDATE recurring_expenses SET active = FALSE, updated_at = NOW() WHERE id = $1`, id); err != nil {
+				return 0, err
+			}
+			break
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO expenses (organization_id, category, fund, amount, date, description, vendor_id,
+				due_date, status, recurring_expense_id, block_id)
+			VALUES ($1, $2, $8, $3, $4, $5, $6, $4, 'draft', $7, $9)
+			ON CONFLICT DO NOTHING`,
+			t.OrganizationID, t.Category, t.Amount, t.NextRunDate, t.Description, t.VendorID, t.ID, t.Fund, t.BlockID,
+		)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := result.RowsAffected()
+		created += int(n)
+
+		t.NextRunDate = nextRun(t.NextRunDate, t.Frequency, t.DayOfMonth)
+	}
+
+	if _, err := tx.Exec(`UPDATE recurring_expenses SET next_run_date = $1, updated_at = NOW() WHERE id = $2`,
+		t.NextRunDate, id); err != nil {
+		return 0, err
+	}
+	return created, tx.Commit()
+}
+
+const selectContract = `SELECT c.id, c.organization_id, c.vendor_id, COALESCE(v.name, '') as vendor_name,
+		c.title, c.start_date, c.end_date, c.amount, c.renewal_notice_days, c.auto_renew, c.notes,
+		c.reminder_sent_at, c.created_at, c.updated_at
+		FROM contracts c
+		LEFT JOIN vendors v ON c.vendor_id = v.id`
+
+func scanContract(row scanner, c *Contract) error {
+	return row.Scan(
+		&c.ID, &c.OrganizationID, &c.VendorID, &c.VendorName,
+		&c.Title, &c.StartDate, &c.EndDate, &c.Amount, &c.RenewalNoticeDays, &c.AutoRenew, &c.Notes,
+		&c.ReminderSentAt, &c.CreatedAt, &c.UpdatedAt,
+	)
+}
+
+func (r *Repository) CreateContract(c *Contract) error {
+	if c.VendorID != nil {
+		if err := vendors.Belongs(r.db, *c.VendorID, c.OrganizationID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO contracts (organization_id, vendor_id, title, start_date, end_date, amount,
+			renewal_notice_days, auto_renew, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query,
+		c.OrganizationID, c.VendorID, c.Title, c.StartDate, c.EndDate, c.Amount,
+		c.RenewalNoticeDays, c.AutoRenew, c.Notes,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (r *Repository) GetContract(id string) (*Contract, error) {
+	c := &Contract{}
+	if err := scanContract(r.db.QueryRow(selectContract+" WHERE c.id = $1", id), c); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("contract not found")
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+var contractList = &listing.Config{
+	Sorts: map[string]string{
+		"title":      "c.title",
+		"start_date": "c.start_date",
+		"amount":     "c.amount",
+		"created_at": "c.created_at",
+	},
+	Default: "title",
+	ID:      "c.id",
+	Search:  []string{"c.title", "c.notes", "v.name"},
+	Date:    "c.start_date",
+}
+
+func (r *Repository) ListContracts(orgID string, p *listing.Params) ([]Contract, listing.Result, error) {
+	query, args := p.Filter(selectContract+" WHERE c.organization_id = $1", []interface{}{orgID})
+	contracts := []Contract{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var c Contract
+		if err := scanContract(rows, &c); err != nil {
+			return err
+		}
+		contracts = append(contracts, c)
+		return nil
+	})
+	return contracts, res, err
+}
+
+// ListExpiringContracts returns contracts of an organization ending within
+// the given number of days.
+func (r *Repository) ListExpiringContracts(orgID string, day time.Time, days int) ([]Contract, error) {
+	return r.listContracts(selectContract+` WHERE c.organization_id = $1
+		AND c.end_date IS NOT NULL AND c.end_date >= $2 AND c.end_date <= $2::date + $3::int
+		ORDER BY c.end_date`, orgID, day, days)
+}
+
+func (r *Repository) listContracts(query string, args ...interface{}) ([]Contract, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []Contract
+	for rows.Next() {
+		var c Contract
+		if err := scanContract(rows, &c); err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, nil
+}
+
+func (r *Repository) UpdateContract(c *Contract) error {
+	query := `UPDATE contracts SET title=$1, end_date=$2, amount=$3, renewal_notice_days=$4, auto_renew=$5, notes=$6,
+		reminder_sent_at=$7, updated_at=NOW()
+		WHERE id=$8 RETURNING updated_at`
+	return r.db.QueryRow(query,
+		c.Title, c.EndDate, c.Amount, c.RenewalNoticeDays, c.AutoRenew, c.Notes, c.ReminderSentAt, c.ID,
+	).Scan(&c.UpdatedAt)
+}
+
+func (r *Repository) DeleteContract(id string) error {
+	_, err := r.db.Exec("DELETE FROM contracts WHERE id = $1", id)
+	return err
+}
+
+// ContractsNeedingReminder returns contracts that entered their renewal
+// notice window and have not been reminded about yet.
+func (r *Repository) ContractsNeedingReminder(day time.Time) ([]expiringContract, error) {
+	query := `SELECT c.id, c.organization_id, c.title, c.end_date, c.auto_renew,
+			o.name, us.full_name, us.phone
+		FROM contracts c
+		JOIN organizations o ON c.organization_id = o.id
+		JOIN users us ON o.manager_id = us.id
+		WHERE c.end_date IS NOT NULL
+			AND c.reminder_sent_at IS NULL
+			AND c.end_date >= $1
+			AND c.end_date <= $1::date + c.renewal_notice_days`
+
+	rows, err := r.db.Query(query, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []expiringContract
+	for rows.Next() {
+		var c expiringContract
+		if err := rows.Scan(
+			&c.ID, &c.OrganizationID, &c.Title, &c.EndDate, &c.AutoRenew,
+			&c.OrganizationName, &c.ManagerName, &c.ManagerPhone,
+		); err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, nil
+}
+
+// ContractsToRenew returns auto-renewing contracts that ended before day.
+func (r *Repository) ContractsToRenew(day time.Time) ([]Contract, error) {
+	rows, err := r.db.Query(selectContract+" WHERE c.auto_renew AND c.end_date < $1", day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contracts []Contract
+	for rows.Next() {
+		var c Contract
+		if err := scanContract(rows, &c); err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, c)
+	}
+	return contracts, rows.Err()
+}
+
+// RenewContract moves a contract to its next term and starts a new
+// reminder cycle. The old end date guards against a concurrent renewal.
+func (r *Repository) RenewContract(id string, oldEnd, start, end time.Time) error {
+	_, err := r.db.Exec(`UPDATE contracts SET start_date=$1, end_date=$2, reminder_sent_at=NULL, updated_at=NOW()
+		WHERE id=$3 AND end_date=$4`, start, end, id, oldEnd)
+	return err
+}
+
+func (r *Repository) MarkReminderSent(id string) error {
+	_, err := r.db.Exec("UPDATE contracts SET reminder_sent_at = NOW() WHERE id = $1", id)
+	return err
+}
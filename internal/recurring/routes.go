@@ -0,0 +1,21 @@
+package recurring
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Route("/organizations/{orgId}/recurring-expenses", func(r chi.Router) {
+		r.Post("/", h.CreateTemplate)
+		r.Get("/", h.ListTemplates)
+		r.Get("/{id}", h.GetTemplate)
+		r.Put("/{id}", h.UpdateTemplate)
+		r.Delete("/{id}", h.DeleteTemplate)
+	})
+	r.Route("/organizations/{orgId}/contracts", func(r chi.Router) {
+		r.Post("/", h.CreateContract)
+		r.Get("/", h.ListContracts)
+		r.Get("/expiring", h.ListExpiringContracts)
+		r.Get("/{id}", h.GetContract)
+		r.Put("/{id}", h.UpdateContract)
+		r.Delete("/{id}", h.DeleteContract)
+	})
+}
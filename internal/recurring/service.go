@@ -0,0 +1,354 @@
+package recurring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/notification"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo          *Repository
+	notifications *notification.Service
+}
+
+func NewService(repo *Repository, notifications *notification.Service) *Service {
+	return &Service{repo: repo, notifications: notifications}
+}
+
+func (s *Service) CreateTemplate(orgID string, req CreateTemplateRequest) (*Template, error) {
+	if req.Category == "" || req.Amount <= 0 || req.StartDate == "" {
+		return nil, fmt.Errorf("category, amount, and start_date are required")
+	}
+	if req.Frequency == "" {
+		req.Frequency = FrequencyMonthly
+	}
+	if req.Fund == "" {
+		req.Fund = dues.TypeAidat
+	}
+	if !dues.ValidType(req.Fund) {
+		return nil, fmt.Errorf("invalid fund %q", req.Fund)
+	}
+	if req.Frequency != FrequencyMonthly && req.Frequency != FrequencyQuarterly && req.Frequency != FrequencyYearly {
+		return nil, fmt.Errorf("frequency must be monthly, quarterly or yearly")
+	}
+
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date format, use YYYY-MM-DD")
+	}
+	if req.DayOfMonth == 0 {
+		req.DayOfMonth = start.Day()
+	}
+	if req.DayOfMonth < 1 || req.DayOfMonth > 31 {
+		return nil, fmt.Errorf("day_of_month must be between 1 and 31")
+	}
+
+	t := &Template{
+		OrganizationID: orgID,
+		Category:       req.Category,
+		Fund:           req.Fund,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		Frequency:      req.Frequency,
+		DayOfMonth:     req.DayOfMonth,
+		StartDate:      start,
+		NextRunDate:    firstRun(start, req.DayOfMonth),
+		Active:         true,
+	}
+	if req.BlockID != "" {
+		t.BlockID = &req.BlockID
+	}
+	if req.VendorID != "" {
+		t.VendorID = &req.VendorID
+	}
+	if req.ContractID != "" {
+		t.ContractID = &req.ContractID
+	}
+	if req.EndDate != "" {
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date format, use YYYY-MM-DD")
+		}
+		t.EndDate = &end
+	}
+
+	if err := s.repo.CreateTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Service) GetTemplate(id string) (*Template, error) {
+	return s.repo.GetTemplate(id)
+}
+
+func (s *Service) ListTemplates(orgID string, p *listing.Params) ([]Template, listing.Result, error) {
+	return s.repo.ListTemplates(orgID, p)
+}
+
+func (s *Service) UpdateTemplate(id string, req UpdateTemplateRequest) (*Template, error) {
+	t, err := s.repo.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			return nil, fmt.Errorf("amount must be positive")
+		}
+		t.Amount = *req.Amount
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.Category != nil {
+		t.Category = *req.Category
+	}
+	if req.EndDate != nil {
+		if *req.EndDate == "" {
+			t.EndDate = nil
+		} else {
+			end, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end_date format, use YYYY-MM-DD")
+			}
+			t.EndDate = &end
+		}
+	}
+	if req.Active != nil {
+		t.Active = *req.Active
+	}
+
+	if err := s.repo.UpdateTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Service) DeleteTemplate(id string) error {
+	return s.repo.DeleteTemplate(id)
+}
+
+// GenerateDue creates draft expenses for every template whose period has
+// started. It is run by the scheduler; drafts wait for the manager to
+// submit them into the approval workflow.
+func (s *Service) GenerateDue() (int, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	ids, err := s.repo.DueTemplateIDs(today)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		n, err := s.repo.GenerateDrafts(id, today)
+		if err != nil {
+			return total, fmt.Errorf("recurring expense %s: %w", id, err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (s *Service) CreateContract(orgID string, req CreateContractRequest) (*Contract, error) {
+	if req.Title == "" || req.StartDate == "" {
+		return nil, fmt.Errorf("title and start_date are required")
+	}
+
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date format, use YYYY-MM-DD")
+	}
+	if req.RenewalNoticeDays <= 0 {
+		req.RenewalNoticeDays = 30
+	}
+
+	c := &Contract{
+		OrganizationID:    orgID,
+		Title:             req.Title,
+		StartDate:         start,
+		Amount:            req.Amount,
+		RenewalNoticeDays: req.RenewalNoticeDays,
+		AutoRenew:         req.AutoRenew,
+		Notes:             req.Notes,
+	}
+	if req.VendorID != "" {
+		c.VendorID = &req.VendorID
+	}
+	if req.EndDate != "" {
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end_date format, use YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("end_date must be after start_date")
+		}
+		c.EndDate = &end
+	}
+
+	if err := s.repo.CreateContract(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) GetContract(id string) (*Contract, error) {
+	return s.repo.GetContract(id)
+}
+
+func (s *Service) ListContracts(orgID string, p *listing.Params) ([]Contract, listing.Result, error) {
+	return s.repo.ListContracts(orgID, p)
+}
+
+func (s *Service) ListExpiringContracts(orgID string, days int) ([]Contract, error) {
+	return s.repo.ListExpiringContracts(orgID, time.Now().UTC().Truncate(24*time.Hour), days)
+}
+
+func (s *Service) UpdateContract(id string, req UpdateContractRequest) (*Contract, error) {
+	c, err := s.repo.GetContract(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		c.Title = *req.Title
+	}
+	if req.EndDate != nil {
+		if *req.EndDate == "" {
+			c.EndDate = nil
+		} else {
+			end, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end_date format, use YYYY-MM-DD")
+			}
+			c.EndDate = &end
+		}
+		// A new end date (renewal) starts a new reminder cycle.
+		c.ReminderSentAt = nil
+	}
+	if req.Amount != nil {
+		c.Amount = *req.Amount
+	}
+	if req.RenewalNoticeDays != nil {
+		c.RenewalNoticeDays = *req.RenewalNoticeDays
+	}
+	if req.AutoRenew != nil {
+		c.AutoRenew = *req.AutoRenew
+	}
+	if req.Notes != nil {
+		c.Notes = *req.Notes
+	}
+
+	if err := s.repo.UpdateContract(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *Service) DeleteContract(id string) error {
+	return s.repo.DeleteContract(id)
+}
+
+// RenewContracts extends auto-renewing contracts that have ended by their
+// own term, as often as needed to reach today, and returns how many were
+// renewed. It is run by the scheduler.
+func (s *Service) RenewContracts() (int, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	contracts, err := s.repo.ContractsToRenew(today)
+	if err != nil {
+		return 0, err
+	}
+
+	renewed := 0
+	for _, c := range contracts {
+		start, end := c.StartDate, *c.EndDate
+		for end.Before(today) {
+			start, end = renewal(start, end)
+		}
+		if err := s.repo.RenewContract(c.ID, *c.EndDate, start, end); err != nil {
+			return renewed, fmt.Errorf("contract %s: %w", c.ID, err)
+		}
+		renewed++
+	}
+	return renewed, nil
+}
+
+// renewal returns the term following one that ran from start to end, both
+// included. Terms of whole months (01.01–31.12) stay whole months; other
+// terms keep their length in days.
+func renewal(start, end time.Time) (time.Time, time.Time) {
+	next := end.AddDate(0, 0, 1)
+	months := (next.Year()-start.Year())*12 + int(next.Month()-start.Month())
+	if months > 0 && start.AddDate(0, months, 0).Equal(next) {
+		return next, next.AddDate(0, months, -1)
+	}
+	return next, next.Add(end.Sub(start))
+}
+
+// SendRenewalReminders notifies managers once about each contract that
+// entered its renewal notice window.
+func (s *Service) SendRenewalReminders() (int, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	contracts, err := s.repo.ContractsNeedingReminder(today)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, c := range contracts {
+		msg := fmt.Sprintf("Sayın %s, %s için \"%s\" sözleşmesi %s tarihinde sona eriyor.",
+			c.ManagerName, c.OrganizationName, c.Title, c.EndDate.Format("02.01.2006"))
+		if c.AutoRenew {
+			msg += " Sözleşme otomatik yenilenecektir."
+		} else {
+			msg += " Lütfen yenileme işlemlerini planlayınız."
+		}
+
+		if err := s.notifications.SendSMS(c.ManagerPhone, msg); err != nil {
+			return sent, err
+		}
+		if err := s.repo.MarkReminderSent(c.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// firstRun returns the first run date on or after start that falls on day.
+func firstRun(start time.Time, day int) time.Time {
+	d := clampDay(start.Year(), start.Month(), day)
+	if d.Before(start) {
+		next := start.AddDate(0, 1, 1-start.Day())
+		d = clampDay(next.Year(), next.Month(), day)
+	}
+	return d
+}
+
+// nextRun advances a run date by one period, keeping the configured day of
+// month (clamped to the month's length, e.g. the 31st becomes Feb 28).
+func nextRun(current time.Time, frequency string, day int) time.Time {
+	months := 1
+	switch frequency {
+	case FrequencyQuarterly:
+		months = 3
+	case FrequencyYearly:
+		months = 12
+	}
+	first := time.Date(current.Year(), current.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	return clampDay(first.Year(), first.Month(), day)
+}
+
+func clampDay(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,51 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRenewal(t *testing.T) {
+	tests := []struct {
+		start, end, wantStart, wantEnd string
+	}{
+		{"2025-01-01", "2025-12-31", "2026-01-01", "2026-12-31"},
+		{"2024-03-01", "2024-05-31", "2024-06-01", "2024-08-31"},
+		{"2026-01-15", "2026-02-14", "2026-02-15", "2026-03-14"},
+		{"2026-01-01", "2026-01-10", "2026-01-11", "2026-01-20"}, // 10 days
+	}
+	for _, tt := range tests {
+		start, end := renewal(date(tt.start), date(tt.end))
+		if !start.Equal(date(tt.wantStart)) || !end.Equal(date(tt.wantEnd)) {
+			t.Errorf("renewal(%s, %s) = %s, %s, want %s, %s", tt.start, tt.end,
+				start.Format("2006-01-02"), end.Format("2006-01-02"), tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		current, frequency string
+		day                int
+		want               string
+	}{
+		{"2026-01-31", FrequencyMonthly, 31, "2026-02-28"},
+		{"2026-02-28", FrequencyMonthly, 31, "2026-03-31"},
+		{"2026-11-15", FrequencyQuarterly, 15, "2027-02-15"},
+		{"2024-02-29", FrequencyYearly, 29, "2025-02-28"},
+	}
+	for _, tt := range tests {
+		if got := nextRun(date(tt.current), tt.frequency, tt.day); !got.Equal(date(tt.want)) {
+			t.Errorf("nextRun(%s, %s, %d) = %s, want %s", tt.current, tt.frequency, tt.day,
+				got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
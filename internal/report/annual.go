@@ -0,0 +1,326 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
+)
+
+// AnnualReport is the year's accounts as presented to the general assembly
+// (genel kurul). The year is the organization's fiscal year: Year is the
+// calendar year it starts in and StartMonth its first month, so a year
+// starting in July runs from July Year to June Year+1.
+type AnnualReport struct {
+	Year       int           `json:"year"`
+	StartMonth int           `json:"start_month"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"` // last day of the year
+	Months     []AnnualMonth `json:"months"`
+	Totals     AnnualTotals  `json:"totals"`
+	// Previous and PreviousMonths are the year before, for comparison.
+	Previous       AnnualTotals     `json:"previous"`
+	PreviousMonths []AnnualMonth    `json:"previous_months"`
+	Categories     []CategoryTotal  `json:"categories"`
+	Funds          []FundBalance    `json:"funds"`
+	Accounts       []AccountBalance `json:"accounts"` // balances at the end of the year
+	Debtors        []Debtor         `json:"debtors"`  // units owing at the end of the year
+}
+
+// AnnualMonth is one row of the 12-month income and expense table. Billed
+// counts dues by due date, Collected payments by the day they were made.
+type AnnualMonth struct {
+	Year      int     `json:"year"`
+	Month     int     `json:"month"`
+	Billed    float64 `json:"billed"`
+	Collected float64 `json:"collected"`
+	Expenses  float64 `json:"expenses"` // approved and paid expenses
+	Net       float64 `json:"net"`      // collected minus expenses
+}
+
+type AnnualTotals struct {
+	Billed    float64 `json:"billed"`
+	Collected float64 `json:"collected"`
+	Expenses  float64 `json:"expenses"`
+	Net       float64 `json:"net"`
+	// CollectionRate is the share of the year's billed dues that has been
+	// paid, 0-100.
+	CollectionRate float64 `json:"collection_rate"`
+	OpeningCash    float64 `json:"opening_cash"`
+	// OpenedAccounts is the opening balances of accounts opened during the
+	// year. They change the cash between OpeningCash and ClosingCash without
+	// being income, so they are not part of Net.
+	OpenedAccounts float64 `json:"opened_accounts"`
+	ClosingCash    float64 `json:"closing_cash"`
+	Receivable     float64 `json:"receivable"` // unpaid at the end of the year
+}
+
+// CategoryTotal is a year's spending in one expense category next to the
+// year before.
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+	Previous float64 `json:"previous"`
+}
+
+// Debtor is a unit with dues that were unpaid at the end of the year.
+type Debtor struct {
+	UnitID       string    `json:"unit_id"`
+	UnitNumber   string    `json:"unit_number"`
+	BlockName    string    `json:"block_name,omitempty"`
+	ResidentName string    `json:"resident_name,omitempty"`
+	DueCount     int       `json:"due_count"`
+	OldestDue    time.Time `json:"oldest_due"`
+	Amount       float64   `json:"amount"`
+}
+
+// FiscalYear returns the fiscal year containing day for a year starting in
+// startMonth, as the calendar year it starts in.
+func FiscalYear(day time.Time, startMonth int) int {
+	if int(day.Month()) < startMonth {
+		return day.Year() - 1
+	}
+	return day.Year()
+}
+
+// GetAnnualReport builds the annual report of a fiscal year. startMonth
+// overrides the organization's fiscal year start when it is not zero.
+func (s *Service) GetAnnualReport(orgID string, year, startMonth int) (*AnnualReport, error) {
+	if startMonth == 0 {
+		var err error
+		if startMonth, err = organization.FiscalYearStart(s.db, orgID); err != nil {
+			return nil, err
+		}
+	}
+	if startMonth < 1 || startMonth > 12 {
+		return nil, fmt.Errorf("start_month must be a month between 1 and 12")
+	}
+	if year == 0 {
+		year = FiscalYear(time.Now(), startMonth)
+	}
+
+	from := time.Date(year, time.Month(startMonth), 1, 0, 0, 0, 0, time.UTC)
+	next := from.AddDate(1, 0, 0)
+	prev := from.AddDate(-1, 0, 0)
+	report := &AnnualReport{Year: year, StartMonth: startMonth, From: from, To: next.AddDate(0, 0, -1)}
+
+	var err error
+	if report.Months, report.Totals, err = s.annualMonths(orgID, from, next); err != nil {
+		return nil, fmt.Errorf("failed to get monthly totals: %w", err)
+	}
+	if report.PreviousMonths, report.Previous, err = s.annualMonths(orgID, prev, from); err != nil {
+		return nil, fmt.Errorf("failed to get previous year: %w", err)
+	}
+	if report.Categories, err = s.categoryTotals(orgID, prev, from, next); err != nil {
+		return nil, fmt.Errorf("failed to get expense categories: %w", err)
+	}
+	if report.Funds, err = s.fundBalances(orgID, from, next); err != nil {
+		return nil, fmt.Errorf("failed to get fund balances: %w", err)
+	}
+	if report.Debtors, err = s.debtorsAt(orgID, next); err != nil {
+		return nil, fmt.Errorf("failed to get debtors: %w", err)
+	}
+	for _, d := range report.Debtors {
+		report.Totals.Receivable += d.Amount
+	}
+	if report.Previous.Receivable, err = s.receivableAt(orgID, from); err != nil {
+		return nil, err
+	}
+
+	asOf := report.To
+	if today := time.Now().UTC().Truncate(24 * time.Hour); today.Before(asOf) {
+		asOf = today
+	}
+	if report.Accounts, err = s.accountBalances(orgID, asOf); err != nil {
+		return nil, fmt.Errorf("failed to get account balances: %w", err)
+	}
+	for _, c := range []struct {
+		into *float64
+		day  time.Time
+	}{
+		{&report.Previous.OpeningCash, prev.AddDate(0, 0, -1)},
+		{&report.Previous.ClosingCash, from.AddDate(0, 0, -1)},
+		{&report.Totals.OpeningCash, from.AddDate(0, 0, -1)},
+		{&report.Totals.ClosingCash, asOf},
+	} {
+		if *c.into, err = s.cashAsOf(orgID, c.day); err != nil {
+			return nil, fmt.Errorf("failed to get cash position: %w", err)
+		}
+	}
+	if report.Previous.OpenedAccounts, err = s.openedBalance(orgID, prev, from.AddDate(0, 0, -1)); err != nil {
+		return nil, fmt.Errorf("failed to get opened accounts: %w", err)
+	}
+	if report.Totals.OpenedAccounts, err = s.openedBalance(orgID, from, asOf); err != nil {
+		return nil, fmt.Errorf("failed to get opened accounts: %w", err)
+	}
+	return report, nil
+}
+
+// AnnualPDF renders an annual report of the organization for the general
+// assembly.
+func (s *Service) AnnualPDF(orgID string, report *AnnualReport) (*pdf.Document, error) {
+	name, address, err := organization.Header(s.db, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return annualPDF(name, address, report), nil
+}
+
+// annualMonths returns the twelve months starting at from with their
+// totals. The collection rate is worked out from the dues billed in the
+// period, whenever they were paid.
+func (s *Service) annualMonths(orgID string, from, next time.Time) ([]AnnualMonth, AnnualTotals, error) {
+	var totals AnnualTotals
+	rows, err := s.db.Query(`
+		SELECT EXTRACT(YEAR FROM m)::int, EXTRACT(MONTH FROM m)::int,
+			COALESCE((SELECT SUM(amount - adjustment_total) FROM dues
+				WHERE organization_id = $1 AND status <> 'restructured'
+					AND due_date >= m AND due_date < m + INTERVAL '1 month'), 0) as billed,
+			COALESCE((SELECT SUM(amount - adjustment_total) FROM dues
+				WHERE organization_id = $1 AND status = 'paid'
+					AND paid_at >= m AND paid_at < m + INTERVAL '1 month'), 0) as collected,
+			COALESCE((SELECT SUM(amount) FROM expenses
+				WHERE organization_id = $1 AND status IN ('approved', 'paid')
+					AND date >= m AND date < m + INTERVAL '1 month'), 0) as expenses
+		FROM generate_series($2::date, $3::date - INTERVAL '1 month', INTERVAL '1 month') m
+		ORDER BY m`, orgID, from, next)
+	if err != nil {
+		return nil, totals, err
+	}
+	defer rows.Close()
+
+	months := make([]AnnualMonth, 0, 12)
+	for rows.Next() {
+		var m AnnualMonth
+		if err := rows.Scan(&m.Year, &m.Month, &m.Billed, &m.Collected, &m.Expenses); err != nil {
+			return nil, totals, err
+		}
+		m.Net = m.Collected - m.Expenses
+		totals.Billed += m.Billed
+		totals.Collected += m.Collected
+		totals.Expenses += m.Expenses
+		months = append(months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, totals, err
+	}
+	totals.Net = totals.Collected - totals.Expenses
+
+	var billed, paid float64
+	err = s.db.QueryRow(`
+		SELECT COALESCE(SUM(amount - adjustment_total), 0),
+			COALESCE(SUM(amount - adjustment_total) FILTER (WHERE status = 'paid'), 0)
+		FROM dues
+		WHERE organization_id = $1 AND status <> 'restructured'
+			AND due_date >= $2 AND due_date < $3`, orgID, from, next).Scan(&billed, &paid)
+	if err != nil {
+		return nil, totals, err
+	}
+	totals.CollectionRate = CollectionRate(paid, billed)
+	return months, totals, nil
+}
+
+// categoryTotals sums approved and paid expenses per category for the year
+// [from, next) next to the year [prev, from) before it.
+func (s *Service) categoryTotals(orgID string, prev, from, next time.Time) ([]CategoryTotal, error) {
+	rows, err := s.db.Query(`
+		SELECT category,
+			COALESCE(SUM(amount) FILTER (WHERE date >= $3), 0) as amount,
+			COUNT(*) FILTER (WHERE date >= $3) as count,
+			COALESCE(SUM(amount) FILTER (WHERE date < $3), 0) as previous
+		FROM expenses
+		WHERE organization_id = $1 AND status IN ('approved', 'paid')
+			AND date >= $2 AND date < $4
+		GROUP BY category
+		ORDER BY amount DESC, previous DESC`, orgID, prev, from, next)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []CategoryTotal{}
+	for rows.Next() {
+		var c CategoryTotal
+		if err := rows.Scan(&c.Category, &c.Amount, &c.Count, &c.Previous); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// unpaidAt selects the dues that fell due before a day and were still open
+// on it: unpaid now, or paid on or after that day.
+const unpaidAt = `FROM dues d
+		WHERE d.organization_id = $1 AND d.due_date < $2
+			AND (d.status IN ('pending', 'overdue') OR (d.status = 'paid' AND d.paid_at >= $2))`
+
+// debtorsAt lists the units that owed money at the start of day, largest
+// debt first.
+func (s *Service) debtorsAt(orgID string, day time.Time) ([]Debtor, error) {
+	rows, err := s.db.Query(`
+		SELECT u.id, u.unit_number, COALESCE(b.name, ''), COALESCE(r.full_name, ''),
+			o.due_count, o.oldest_due, o.amount
+		FROM (
+			SELECT d.unit_id, COUNT(*) as due_count, MIN(d.due_date) as oldest_due,
+				SUM(d.amount - d.adjustment_total) as amount
+			`+unpaidAt+`
+			GROUP BY d.unit_id
+		) o
+		JOIN units u ON o.unit_id = u.id
+		LEFT JOIN blocks b ON u.block_id = b.id
+		LEFT JOIN residents r ON u.resident_id = r.id
+		WHERE o.amount > 0
+		ORDER BY o.amount DESC, b.name NULLS FIRST, u.unit_number`, orgID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	debtors := []Debtor{}
+	for rows.Next() {
+		var d Debtor
+		if err := rows.Scan(&d.UnitID, &d.UnitNumber, &d.BlockName, &d.ResidentName,
+			&d.DueCount, &d.OldestDue, &d.Amount); err != nil {
+			return nil, err
+		}
+		debtors = append(debtors, d)
+	}
+	return debtors, rows.Err()
+}
+
+// receivableAt is the total of debtorsAt.
+func (s *Service) receivableAt(orgID string, day time.Time) (float64, error) {
+	var total float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(d.amount - d.adjustment_total), 0) `+unpaidAt, orgID, day).Scan(&total)
+	return total, err
+}
+
+// accountBalances returns the balance of every account at the end of day.
+func (s *Service) accountBalances(orgID string, day time.Time) ([]AccountBalance, error) {
+	rows, err := s.db.Query(`
+		SELECT a.id, a.name, a.type,
+			CASE WHEN a.opening_date <= $2 THEN a.opening_balance ELSE 0 END
+				+ COALESCE(SUM(e.amount), 0) as balance
+		FROM accounts a
+		LEFT JOIN account_entries e ON e.account_id = a.id AND e.date <= $2
+		WHERE a.organization_id = $1
+		GROUP BY a.id, a.name, a.type, a.opening_balance, a.opening_date
+		ORDER BY a.type, a.name`, orgID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []AccountBalance
+	for rows.Next() {
+		var b AccountBalance
+		if err := rows.Scan(&b.AccountID, &b.Name, &b.Type, &b.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
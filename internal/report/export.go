@@ -0,0 +1,144 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/expense"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/xlsx"
+)
+
+var summaryColumns = []export.Column{
+	{Title: "Kalem", Width: 36},
+	{Title: "Tutar", Width: 16},
+}
+
+func summaryName(s *MonthlySummary) string {
+	return fmt.Sprintf("aylik-ozet-%d-%02d", s.Year, s.Month)
+}
+
+// exportSummary writes the monthly summary as label/value pairs, followed
+// by the month's expenses per category.
+func exportSummary(add export.AddFunc, s *MonthlySummary, breakdown []ExpenseBreakdown) error {
+	rows := [][]interface{}{
+		{"Dönem", fmt.Sprintf("%s %d", export.MonthName(s.Month), s.Year)},
+		{"Tahakkuk eden", s.TotalDues},
+		{"İptal edilen", s.Adjustments.Voided},
+		{"İndirimler", s.Adjustments.Discounts},
+		{"Alacak mahsupları", s.Adjustments.Credits},
+		{"Net tahakkuk", s.NetDues},
+		{"Tahsil edilen", s.TotalPaid},
+		{"Gecikmiş", s.TotalOverdue},
+		{"Giderler", s.TotalExpenses},
+		{"Onay bekleyen giderler", s.UnapprovedExpenses},
+		{"Dönem bakiyesi", s.Balance},
+		{"Ay sonu kasa ve banka", s.ClosingCash},
+		{"Ödenen aidat sayısı", s.PaidCount},
+		{"Bekleyen aidat sayısı", s.PendingCount},
+		{"Gecikmiş aidat sayısı", s.OverdueCount},
+	}
+	if len(breakdown) > 0 {
+		rows = append(rows, []interface{}{}, []interface{}{"Gider dağılımı"})
+		for _, b := range breakdown {
+			rows = append(rows, []interface{}{export.Label(expense.CategoryLabels, b.Category), b.Amount})
+		}
+	}
+	for _, row := range rows {
+		if err := add(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annualColumns head the 12-month table that opens the annual export;
+// the sections below it carry their own header rows.
+var annualColumns = []export.Column{
+	{Title: "Ay", Width: 30},
+	{Title: "Tahakkuk", Width: 16},
+	{Title: "Tahsilat", Width: 16},
+	{Title: "Gider", Width: 16},
+	{Title: "Net", Width: 16},
+	{Title: "Önceki dönem net", Width: 18},
+}
+
+func annualName(r *AnnualReport) string {
+	return "yillik-rapor-" + strings.Replace(periodLabel(r), "/", "-", 1)
+}
+
+// exportAnnual writes the annual report as one sheet: the month table,
+// then the comparison with the previous year, expense categories, funds,
+// accounts and debtors, each under a bold heading.
+func exportAnnual(add export.AddFunc, r *AnnualReport) error {
+	for i, m := range r.Months {
+		var previous interface{}
+		if i < len(r.PreviousMonths) {
+			previous = r.PreviousMonths[i].Net
+		}
+		err := add(fmt.Sprintf("%s %d", export.MonthName(m.Month), m.Year), m.Billed, m.Collected, m.Expenses, m.Net, previous)
+		if err != nil {
+			return err
+		}
+	}
+	cur, prev := r.Totals, r.Previous
+	rows := [][]interface{}{
+		{bold("Toplam"), cur.Billed, cur.Collected, cur.Expenses, cur.Net, prev.Net},
+		{},
+		{bold("Önceki dönemle karşılaştırma")},
+		{bold("Kalem"), bold("Bu dönem"), bold("Önceki dönem"), bold("Değişim")},
+		{"Tahakkuk", cur.Billed, prev.Billed, change(cur.Billed, prev.Billed)},
+		{"Tahsilat", cur.Collected, prev.Collected, change(cur.Collected, prev.Collected)},
+		{"Gider", cur.Expenses, prev.Expenses, change(cur.Expenses, prev.Expenses)},
+		{"Dönem sonucu", cur.Net, prev.Net, change(cur.Net, prev.Net)},
+		{"Tahsilat oranı (%)", cur.CollectionRate, prev.CollectionRate},
+		{"Dönem başı kasa ve banka", cur.OpeningCash, prev.OpeningCash, change(cur.OpeningCash, prev.OpeningCash)},
+		{"Yeni açılan hesapların açılış bakiyesi", cur.OpenedAccounts, prev.OpenedAccounts, change(cur.OpenedAccounts, prev.OpenedAccounts)},
+		{"Dönem sonu kasa ve banka", cur.ClosingCash, prev.ClosingCash, change(cur.ClosingCash, prev.ClosingCash)},
+		{"Dönem sonu alacak", cur.Receivable, prev.Receivable, change(cur.Receivable, prev.Receivable)},
+		{},
+		{bold("Gider dağılımı")},
+		{bold("Kategori"), bold("Bu dönem"), bold("Önceki dönem"), bold("Değişim"), bold("Adet")},
+	}
+	for _, c := range r.Categories {
+		rows = append(rows, []interface{}{export.Label(expense.CategoryLabels, c.Category),
+			c.Amount, c.Previous, change(c.Amount, c.Previous), c.Count})
+	}
+
+	rows = append(rows, []interface{}{},
+		[]interface{}{bold("Fonlar")},
+		[]interface{}{bold("Fon"), bold("Devir"), bold("Giriş"), bold("Çıkış"), bold("Kalan"), bold("Alacak")})
+	for _, f := range r.Funds {
+		rows = append(rows, []interface{}{export.Label(dues.TypeLabels, f.Fund),
+			f.Opening, f.Inflows, f.Outflows, f.Closing, f.Receivable})
+	}
+
+	rows = append(rows, []interface{}{},
+		[]interface{}{bold("Kasa ve banka hesapları")},
+		[]interface{}{bold("Hesap"), bold("Tür"), bold("Bakiye")})
+	for _, a := range r.Accounts {
+		rows = append(rows, []interface{}{a.Name, export.Label(accountTypeLabels, a.Type), a.Balance})
+	}
+
+	rows = append(rows, []interface{}{},
+		[]interface{}{bold("Borçlu daireler")},
+		[]interface{}{bold("Daire"), bold("Sakin"), bold("Borç sayısı"), bold("En eski vade"), bold("Tutar")})
+	for _, d := range r.Debtors {
+		rows = append(rows, []interface{}{unitLabel(d.BlockName, d.UnitNumber), d.ResidentName,
+			d.DueCount, d.OldestDue, d.Amount})
+	}
+
+	for _, row := range rows {
+		if err := add(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bold marks a text cell as a heading in XLSX; CSV has no styles and
+// prints it as is.
+func bold(s string) xlsx.Cell {
+	return xlsx.Cell{Value: s, Style: xlsx.StyleHeader}
+}
@@ -1,11 +1,14 @@
 package report
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
 
@@ -30,15 +33,61 @@ func (h *Handler) MonthlySummary(w http.ResponseWriter, r *http.Request) {
 		month = int(now.Month())
 	}
 
-	summary, err := h.service.GetMonthlySummary(orgID, year, month)
+	blockID := r.URL.Query().Get("block_id")
+	if export.Format(r) == export.PDF {
+		doc, err := h.service.MonthlyPDF(orgID, blockID, year, month)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := pdf.Serve(w, fmt.Sprintf("aylik-rapor-%d-%02d", year, month), doc); err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	summary, err := h.service.GetMonthlySummary(orgID, blockID, year, month)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if format := export.Format(r); format != "" {
+		export.Stream(w, format, summaryName(summary), summaryColumns, func(add export.AddFunc) error {
+			breakdown, err := h.service.GetExpenseBreakdown(orgID, summary.BlockID, year, month)
+			if err != nil {
+				return err
+			}
+			return exportSummary(add, summary, breakdown)
+		})
+		return
+	}
+
 	response.JSON(w, http.StatusOK, summary)
 }
 
+func (h *Handler) BlockReport(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	now := time.Now()
+
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
+	if year == 0 {
+		year = now.Year()
+	}
+	if month == 0 {
+		month = int(now.Month())
+	}
+
+	report, err := h.service.GetBlockReport(orgID, year, month)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
+
 func (h *Handler) ExpenseBreakdown(w http.ResponseWriter, r *http.Request) {
 	orgID := chi.URLParam(r, "orgId")
 	now := time.Now()
@@ -52,7 +101,7 @@ func (h *Handler) ExpenseBreakdown(w http.ResponseWriter, r *http.Request) {
 		month = int(now.Month())
 	}
 
-	breakdown, err := h.service.GetExpenseBreakdown(orgID, year, month)
+	breakdown, err := h.service.GetExpenseBreakdown(orgID, r.URL.Query().Get("block_id"), year, month)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
@@ -60,3 +109,128 @@ func (h *Handler) ExpenseBreakdown(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusOK, breakdown)
 }
+
+func (h *Handler) VendorSpend(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	spend, err := h.service.GetVendorSpend(orgID, year)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, spend)
+}
+
+func (h *Handler) CashPosition(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	pos, err := h.service.GetCashPosition(orgID, year)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, pos)
+}
+
+func (h *Handler) FundBalances(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	if year == 0 {
+		year = time.Now().Year()
+	}
+
+	balances, err := h.service.GetFundBalances(orgID, year)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, balances)
+}
+
+// AnnualReport returns the accounts of a fiscal year for the general
+// assembly, as JSON, PDF, XLSX or CSV. year is the calendar year the
+// fiscal year starts in; start_month overrides the organization's fiscal
+// year start.
+func (h *Handler) AnnualReport(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	startMonth, _ := strconv.Atoi(r.URL.Query().Get("start_month"))
+	if startMonth < 0 || startMonth > 12 {
+		response.Error(w, http.StatusBadRequest, "start_month must be a month between 1 and 12")
+		return
+	}
+
+	report, err := h.service.GetAnnualReport(orgID, year, startMonth)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	switch format := export.Format(r); format {
+	case "":
+		response.JSON(w, http.StatusOK, report)
+	case export.PDF:
+		doc, err := h.service.AnnualPDF(orgID, report)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := pdf.Serve(w, annualName(report), doc); err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+		}
+	default:
+		export.Stream(w, format, annualName(report), annualColumns, func(add export.AddFunc) error {
+			return exportAnnual(add, report)
+		})
+	}
+}
+
+// Trends returns monthly series for charts: from and to are months
+// (YYYY-MM) and default to the last twelve months; group_by=block|type
+// returns a series per block or dues type.
+func (h *Handler) Trends(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid to, use YYYY-MM")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, -11, 0)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse("2006-01", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid from, use YYYY-MM")
+			return
+		}
+		from = t
+	}
+
+	trends, err := h.service.GetTrends(orgID, from, to, r.URL.Query().Get("group_by"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, trends)
+}
@@ -0,0 +1,228 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/expense"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
+)
+
+// monthlyPDF is the income and expense table hung on the lobby board.
+func monthlyPDF(siteName, address, blockName string, s *MonthlySummary, breakdown []ExpenseBreakdown) *pdf.Document {
+	period := fmt.Sprintf("%s %d", export.MonthName(s.Month), s.Year)
+	doc := pdf.New("Aylık Gelir-Gider Tablosu " + period)
+	doc.Header(siteName, address, "AYLIK GELİR-GİDER TABLOSU")
+	doc.Field("Dönem", period)
+	if blockName != "" {
+		doc.Field("Blok", blockName)
+	}
+
+	amounts := []pdf.Column{{Title: "Kalem"}, {Title: "Tutar (TL)", Width: 40, Align: pdf.Right}}
+
+	doc.Heading("Gelirler")
+	doc.Table(amounts, [][]string{
+		{"Tahakkuk eden aidat ve ödemeler", export.Money(s.TotalDues)},
+		{"İptal, indirim ve mahsuplar", export.Money(-(s.Adjustments.Voided + s.Adjustments.Discounts + s.Adjustments.Credits))},
+		{"Net tahakkuk", export.Money(s.NetDues)},
+		{"Tahsil edilen", export.Money(s.TotalPaid)},
+		{"Gecikmiş alacak", export.Money(s.TotalOverdue)},
+	}, map[int]bool{3: true})
+
+	doc.Heading("Giderler")
+	rows := make([][]string, 0, len(breakdown)+1)
+	for _, b := range breakdown {
+		rows = append(rows, []string{
+			export.Label(expense.CategoryLabels, b.Category) + " (" + strconv.Itoa(b.Count) + ")",
+			export.Money(b.Amount),
+		})
+	}
+	rows = append(rows, []string{"Toplam gider", export.Money(s.TotalExpenses)})
+	doc.Table(amounts, rows, map[int]bool{len(rows) - 1: true})
+	if s.UnapprovedExpenses > 0 {
+		doc.Text("Onay bekleyen " + export.Money(s.UnapprovedExpenses) + " TL gider tabloya dahil değildir.")
+	}
+
+	doc.Heading("Özet")
+	doc.Table(amounts, [][]string{
+		{"Tahsilat", export.Money(s.TotalPaid)},
+		{"Gider", export.Money(s.TotalExpenses)},
+		{"Dönem sonucu", export.Money(s.Balance)},
+		{"Ay sonu kasa ve banka mevcudu", export.Money(s.ClosingCash)},
+	}, map[int]bool{2: true, 3: true})
+
+	doc.Space(2)
+	doc.Text(fmt.Sprintf("Ödenen: %d, bekleyen: %d, gecikmiş: %d borç kaydı.", s.PaidCount, s.PendingCount, s.OverdueCount))
+	doc.Signatures("Yönetici", "Denetçi")
+	return doc
+}
+
+var accountTypeLabels = map[string]string{
+	"cash":    "Kasa",
+	"bank":    "Banka",
+	"reserve": "Yedek akçe",
+}
+
+// annualPDF is the accounts report read out and voted on at the general
+// assembly: the year against the one before, month by month income and
+// expenses, where the money went, what is left in each fund and account,
+// and who still owes.
+func annualPDF(siteName, address string, r *AnnualReport) *pdf.Document {
+	period := periodLabel(r)
+	doc := pdf.New("Yıllık Hesap Raporu " + period)
+	doc.Header(siteName, address, "YILLIK FAALİYET VE HESAP RAPORU")
+	doc.Field("Hesap Dönemi", r.From.Format("02.01.2006")+" - "+r.To.Format("02.01.2006"))
+
+	cur, prev := r.Totals, r.Previous
+	doc.Heading("Özet")
+	compare := []pdf.Column{
+		{Title: "Kalem"},
+		{Title: "Bu dönem", Width: 32, Align: pdf.Right},
+		{Title: "Önceki dönem", Width: 32, Align: pdf.Right},
+		{Title: "Değişim", Width: 22, Align: pdf.Right},
+	}
+	line := func(label string, a, b float64) []string {
+		return []string{label, export.Money(a), export.Money(b), change(a, b)}
+	}
+	doc.Table(compare, [][]string{
+		line("Tahakkuk", cur.Billed, prev.Billed),
+		line("Tahsilat", cur.Collected, prev.Collected),
+		line("Gider", cur.Expenses, prev.Expenses),
+		line("Dönem sonucu", cur.Net, prev.Net),
+		{"Tahsilat oranı", rate(cur.CollectionRate), rate(prev.CollectionRate), ""},
+		line("Dönem başı kasa ve banka", cur.OpeningCash, prev.OpeningCash),
+		line("Yeni açılan hesapların açılış bakiyesi", cur.OpenedAccounts, prev.OpenedAccounts),
+		line("Dönem sonu kasa ve banka", cur.ClosingCash, prev.ClosingCash),
+		line("Dönem sonu alacak", cur.Receivable, prev.Receivable),
+	}, map[int]bool{3: true})
+
+	doc.Heading("Aylık Gelir-Gider Tablosu")
+	rows := make([][]string, 0, len(r.Months)+1)
+	for _, m := range r.Months {
+		rows = append(rows, []string{
+			fmt.Sprintf("%s %d", export.MonthName(m.Month), m.Year),
+			export.Money(m.Billed), export.Money(m.Collected), export.Money(m.Expenses), export.Money(m.Net),
+		})
+	}
+	rows = append(rows, []string{"Toplam",
+		export.Money(cur.Billed), export.Money(cur.Collected), export.Money(cur.Expenses), export.Money(cur.Net)})
+	doc.Table([]pdf.Column{
+		{Title: "Ay"},
+		{Title: "Tahakkuk", Width: 32, Align: pdf.Right},
+		{Title: "Tahsilat", Width: 32, Align: pdf.Right},
+		{Title: "Gider", Width: 32, Align: pdf.Right},
+		{Title: "Net", Width: 32, Align: pdf.Right},
+	}, rows, map[int]bool{len(rows) - 1: true})
+
+	doc.Heading("Gider Dağılımı")
+	rows = make([][]string, 0, len(r.Categories)+1)
+	for _, c := range r.Categories {
+		rows = append(rows, []string{
+			export.Label(expense.CategoryLabels, c.Category), strconv.Itoa(c.Count),
+			export.Money(c.Amount), export.Money(c.Previous), change(c.Amount, c.Previous),
+		})
+	}
+	rows = append(rows, []string{"Toplam", "",
+		export.Money(cur.Expenses), export.Money(prev.Expenses), change(cur.Expenses, prev.Expenses)})
+	doc.Table([]pdf.Column{
+		{Title: "Kategori"},
+		{Title: "Adet", Width: 16, Align: pdf.Right},
+		{Title: "Bu dönem", Width: 32, Align: pdf.Right},
+		{Title: "Önceki dönem", Width: 32, Align: pdf.Right},
+		{Title: "Değişim", Width: 22, Align: pdf.Right},
+	}, rows, map[int]bool{len(rows) - 1: true})
+
+	doc.Heading("Fonlar")
+	rows = make([][]string, 0, len(r.Funds))
+	for _, f := range r.Funds {
+		rows = append(rows, []string{
+			export.Label(dues.TypeLabels, f.Fund), export.Money(f.Opening), export.Money(f.Inflows),
+			export.Money(f.Outflows), export.Money(f.Closing), export.Money(f.Receivable),
+		})
+	}
+	doc.Table([]pdf.Column{
+		{Title: "Fon"},
+		{Title: "Devir", Width: 26, Align: pdf.Right},
+		{Title: "Giriş", Width: 26, Align: pdf.Right},
+		{Title: "Çıkış", Width: 26, Align: pdf.Right},
+		{Title: "Kalan", Width: 26, Align: pdf.Right},
+		{Title: "Alacak", Width: 26, Align: pdf.Right},
+	}, rows, nil)
+
+	if len(r.Accounts) > 0 {
+		doc.Heading("Kasa ve Banka Hesapları")
+		rows = make([][]string, 0, len(r.Accounts)+1)
+		total := 0.0
+		for _, a := range r.Accounts {
+			rows = append(rows, []string{a.Name, export.Label(accountTypeLabels, a.Type), export.Money(a.Balance)})
+			total += a.Balance
+		}
+		rows = append(rows, []string{"Toplam", "", export.Money(total)})
+		doc.Table([]pdf.Column{
+			{Title: "Hesap"},
+			{Title: "Tür", Width: 30},
+			{Title: "Bakiye", Width: 40, Align: pdf.Right},
+		}, rows, map[int]bool{len(rows) - 1: true})
+	}
+
+	doc.Heading("Borçlu Daireler")
+	if len(r.Debtors) == 0 {
+		doc.Text("Dönem sonunda borçlu daire bulunmamaktadır.")
+	} else {
+		rows = make([][]string, 0, len(r.Debtors)+1)
+		for _, d := range r.Debtors {
+			rows = append(rows, []string{
+				unitLabel(d.BlockName, d.UnitNumber), d.ResidentName, strconv.Itoa(d.DueCount),
+				d.OldestDue.Format("02.01.2006"), export.Money(d.Amount),
+			})
+		}
+		rows = append(rows, []string{"Toplam", "", "", "", export.Money(cur.Receivable)})
+		doc.Table([]pdf.Column{
+			{Title: "Daire", Width: 30},
+			{Title: "Sakin"},
+			{Title: "Borç", Width: 16, Align: pdf.Right},
+			{Title: "En eski vade", Width: 28, Align: pdf.Center},
+			{Title: "Tutar (TL)", Width: 32, Align: pdf.Right},
+		}, rows, map[int]bool{len(rows) - 1: true})
+	}
+
+	doc.Signatures("Yönetim Kurulu Başkanı", "Yönetim Kurulu Üyesi", "Denetçi")
+	return doc
+}
+
+// periodLabel names a fiscal year: 2026 for a calendar year, 2025/2026
+// for one that starts later in the year.
+func periodLabel(r *AnnualReport) string {
+	if r.StartMonth == 1 {
+		return strconv.Itoa(r.Year)
+	}
+	return fmt.Sprintf("%d/%d", r.Year, r.Year+1)
+}
+
+func unitLabel(blockName, unitNumber string) string {
+	if blockName == "" {
+		return unitNumber
+	}
+	return blockName + " / " + unitNumber
+}
+
+// change formats the change from prev to cur as a percentage, e.g. +%12,5.
+func change(cur, prev float64) string {
+	if prev == 0 {
+		return "-"
+	}
+	v := math.Round((cur-prev)/math.Abs(prev)*1000) / 10
+	sign := "+"
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return sign + "%" + strings.Replace(strconv.FormatFloat(v, 'f', 1, 64), ".", ",", 1)
+}
+
+func rate(v float64) string {
+	return "%" + strings.Replace(strconv.FormatFloat(v, 'f', 1, 64), ".", ",", 1)
+}
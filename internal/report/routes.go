@@ -5,6 +5,12 @@ import "github.com/go-chi/chi/v5"
 func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Route("/organizations/{orgId}/reports", func(r chi.Router) {
 		r.Get("/monthly", h.MonthlySummary)
+		r.Get("/blocks", h.BlockReport)
 		r.Get("/expenses", h.ExpenseBreakdown)
+		r.Get("/vendors", h.VendorSpend)
+		r.Get("/cash-position", h.CashPosition)
+		r.Get("/funds", h.FundBalances)
+		r.Get("/annual", h.AnnualReport)
+		r.Get("/trends", h.Trends)
 	})
 }
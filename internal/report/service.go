@@ -3,6 +3,11 @@ package report
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
 )
 
 type Service struct {
@@ -14,16 +19,33 @@ func NewService(db *sql.DB) *Service {
 }
 
 type MonthlySummary struct {
-	Month         int     `json:"month"`
-	Year          int     `json:"year"`
-	TotalDues     float64 `json:"total_dues"`
-	TotalPaid     float64 `json:"total_paid"`
-	TotalOverdue  float64 `json:"total_overdue"`
-	TotalExpenses float64 `json:"total_expenses"`
-	Balance       float64 `json:"balance"`
-	PaidCount     int     `json:"paid_count"`
-	PendingCount  int     `json:"pending_count"`
-	OverdueCount  int     `json:"overdue_count"`
+	Month     int     `json:"month"`
+	Year      int     `json:"year"`
+	BlockID   string  `json:"block_id,omitempty"` // set when the summary covers one block
+	TotalDues float64 `json:"total_dues"`         // billed amount before adjustments
+	// Adjustments explain the gap between TotalDues and what is collectable:
+	// TotalDues - Voided - Discounts - Credits = NetDues.
+	Adjustments   AdjustmentSummary `json:"adjustments"`
+	NetDues       float64           `json:"net_dues"`
+	TotalPaid     float64           `json:"total_paid"`
+	TotalOverdue  float64           `json:"total_overdue"`
+	TotalExpenses float64           `json:"total_expenses"` // approved and paid expenses only
+	// UnapprovedExpenses are submitted expenses still waiting for board
+	// approval; they are not part of TotalExpenses or Balance.
+	UnapprovedExpenses float64 `json:"unapproved_expenses"`
+	Balance            float64 `json:"balance"` // paid dues minus expenses of the month
+	// ClosingCash is the money actually held across all accounts (cash box,
+	// banks, reserve) at the end of the month.
+	ClosingCash  float64 `json:"closing_cash"`
+	PaidCount    int     `json:"paid_count"`
+	PendingCount int     `json:"pending_count"`
+	OverdueCount int     `json:"overdue_count"`
+}
+
+type AdjustmentSummary struct {
+	Voided    float64 `json:"voided"`
+	Discounts float64 `json:"discounts"`
+	Credits   float64 `json:"credits"` // credit notes applied, net of reversals
 }
 
 type ExpenseBreakdown struct {
@@ -32,24 +54,99 @@ type ExpenseBreakdown struct {
 	Count    int     `json:"count"`
 }
 
-func (s *Service) GetMonthlySummary(orgID string, year, month int) (*MonthlySummary, error) {
-	summary := &MonthlySummary{Month: month, Year: year}
+type VendorSpend struct {
+	VendorID   string  `json:"vendor_id"`
+	VendorName string  `json:"vendor_name"`
+	Amount     float64 `json:"amount"`
+	Unpaid     float64 `json:"unpaid"`
+	Count      int     `json:"count"`
+}
+
+type AccountBalance struct {
+	AccountID string  `json:"account_id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Balance   float64 `json:"balance"`
+}
+
+type CashMonth struct {
+	Month   int     `json:"month"`
+	Opening float64 `json:"opening"`
+	Inflow  float64 `json:"inflow"`
+	Outflow float64 `json:"outflow"`
+	Opened  float64 `json:"opened"` // opening balances of accounts opened in the month
+	Closing float64 `json:"closing"`
+}
+
+// CashPosition shows where the money is at the end of a year (or today for
+// the current year) and how the total moved month by month.
+type CashPosition struct {
+	Year     int              `json:"year"`
+	AsOf     time.Time        `json:"as_of"`
+	Accounts []AccountBalance `json:"accounts"`
+	Total    float64          `json:"total"`
+	Months   []CashMonth      `json:"months"`
+}
+
+// FundBalance shows the money collected into and spent from one fund
+// (dues type) during a year.
+type FundBalance struct {
+	Fund       string  `json:"fund"`
+	Opening    float64 `json:"opening"`
+	Inflows    float64 `json:"inflows"`  // dues of this type paid during the year
+	Outflows   float64 `json:"outflows"` // approved and paid expenses charged to the fund
+	Closing    float64 `json:"closing"`
+	Receivable float64 `json:"receivable"` // billed but still unpaid at year end
+}
+
+// BlockSummary is one block's share of a month. Units without a block are
+// grouped under an empty BlockID.
+type BlockSummary struct {
+	BlockID   *string `json:"block_id"`
+	BlockName string  `json:"block_name"`
+	Units     int     `json:"units"`
+	NetDues   float64 `json:"net_dues"`
+	Collected float64 `json:"collected"`
+	Overdue   float64 `json:"overdue"`
+	// BlockExpenses are charged to the block itself; SharedExpenses is its
+	// part of the site's common expenses, split by number of units.
+	BlockExpenses  float64 `json:"block_expenses"`
+	SharedExpenses float64 `json:"shared_expenses"`
+	Balance        float64 `json:"balance"` // collected minus both kinds of expenses
+}
+
+// BlockReport rolls a month up per block and for the whole site.
+type BlockReport struct {
+	Month          int            `json:"month"`
+	Year           int            `json:"year"`
+	Blocks         []BlockSummary `json:"blocks"`
+	Site           BlockSummary   `json:"site"`
+	CommonExpenses float64        `json:"common_expenses"` // expenses not charged to a block
+}
+
+// GetMonthlySummary summarizes a month for the whole site, or for one block
+// when blockID is set. A block summary counts the dues of the block's units
+// and the expenses charged to the block; common expenses are left out.
+// ClosingCash is always the site's, as accounts are not split by block.
+func (s *Service) GetMonthlySummary(orgID, blockID string, year, month int) (*MonthlySummary, error) {
+	summary := &MonthlySummary{Month: month, Year: year, BlockID: blockID}
 
 	// Dues summary
 	duesQuery := `
 		SELECT
 			COALESCE(SUM(amount), 0) as total,
-			COALESCE(SUM(CASE WHEN status = 'paid' THEN amount ELSE 0 END), 0) as paid,
-			COALESCE(SUM(CASE WHEN status = 'overdue' THEN amount ELSE 0 END), 0) as overdue,
+			COALESCE(SUM(CASE WHEN status = 'paid' THEN amount - adjustment_total ELSE 0 END), 0) as paid,
+			COALESCE(SUM(CASE WHEN status = 'overdue' THEN amount - adjustment_total ELSE 0 END), 0) as overdue,
 			COUNT(CASE WHEN status = 'paid' THEN 1 END) as paid_count,
 			COUNT(CASE WHEN status = 'pending' THEN 1 END) as pending_count,
 			COUNT(CASE WHEN status = 'overdue' THEN 1 END) as overdue_count
 		FROM dues
-		WHERE organization_id = $1
+		WHERE organization_id = $1 AND status <> 'restructured'
 			AND EXTRACT(YEAR FROM due_date) = $2
-			AND EXTRACT(MONTH FROM due_date) = $3`
+			AND EXTRACT(MONTH FROM due_date) = $3
+			AND ($4 = '' OR unit_id IN (SELECT id FROM units WHERE block_id::text = $4))`
 
-	err := s.db.QueryRow(duesQuery, orgID, year, month).Scan(
+	err := s.db.QueryRow(duesQuery, orgID, year, month, blockID).Scan(
 		&summary.TotalDues, &summary.TotalPaid, &summary.TotalOverdue,
 		&summary.PaidCount, &summary.PendingCount, &summary.OverdueCount,
 	)
@@ -57,34 +154,94 @@ func (s *Service) GetMonthlySummary(orgID string, year, month int) (*MonthlySumm
 		return nil, fmt.Errorf("failed to get dues summary: %w", err)
 	}
 
+	adjustmentQuery := `
+		SELECT
+			COALESCE(SUM(CASE WHEN a.kind = 'void' THEN a.amount ELSE 0 END), 0) as voided,
+			COALESCE(SUM(CASE WHEN a.kind = 'discount' THEN a.amount ELSE 0 END), 0) as discounts,
+			COALESCE(SUM(CASE WHEN a.kind IN ('credit', 'credit_reversal') THEN a.amount ELSE 0 END), 0) as credits
+		FROM due_adjustments a
+		JOIN dues d ON a.due_id = d.id
+		WHERE d.organization_id = $1 AND d.status <> 'restructured'
+			AND EXTRACT(YEAR FROM d.due_date) = $2
+			AND EXTRACT(MONTH FROM d.due_date) = $3
+			AND ($4 = '' OR d.unit_id IN (SELECT id FROM units WHERE block_id::text = $4))`
+
+	adj := &summary.Adjustments
+	err = s.db.QueryRow(adjustmentQuery, orgID, year, month, blockID).Scan(&adj.Voided, &adj.Discounts, &adj.Credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustment summary: %w", err)
+	}
+	summary.NetDues = summary.TotalDues - adj.Voided - adj.Discounts - adj.Credits
+
 	// Expenses summary
 	expenseQuery := `
-		SELECT COALESCE(SUM(amount), 0)
+		SELECT
+			COALESCE(SUM(CASE WHEN status IN ('approved', 'paid') THEN amount ELSE 0 END), 0) as total,
+			COALESCE(SUM(CASE WHEN status = 'submitted' THEN amount ELSE 0 END), 0) as unapproved
 		FROM expenses
 		WHERE organization_id = $1
 			AND EXTRACT(YEAR FROM date) = $2
-			AND EXTRACT(MONTH FROM date) = $3`
+			AND EXTRACT(MONTH FROM date) = $3
+			AND ($4 = '' OR block_id::text = $4)`
 
-	err = s.db.QueryRow(expenseQuery, orgID, year, month).Scan(&summary.TotalExpenses)
+	err = s.db.QueryRow(expenseQuery, orgID, year, month, blockID).Scan(&summary.TotalExpenses, &summary.UnapprovedExpenses)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expense summary: %w", err)
 	}
 
 	summary.Balance = summary.TotalPaid - summary.TotalExpenses
+
+	monthEnd := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+	if summary.ClosingCash, err = s.cashAsOf(orgID, monthEnd); err != nil {
+		return nil, fmt.Errorf("failed to get cash position: %w", err)
+	}
 	return summary, nil
 }
 
-func (s *Service) GetExpenseBreakdown(orgID string, year, month int) ([]ExpenseBreakdown, error) {
+// MonthlyPDF renders a month's summary and expense breakdown with the
+// site's name and address, for the whole site or one block.
+func (s *Service) MonthlyPDF(orgID, blockID string, year, month int) (*pdf.Document, error) {
+	summary, err := s.GetMonthlySummary(orgID, blockID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := s.GetExpenseBreakdown(orgID, blockID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	name, address, err := organization.Header(s.db, orgID)
+	if err != nil {
+		return nil, err
+	}
+	blockName := ""
+	if blockID != "" {
+		err := s.db.QueryRow("SELECT name FROM blocks WHERE id = $1 AND organization_id = $2",
+			blockID, orgID).Scan(&blockName)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("block not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return monthlyPDF(name, address, blockName, summary, breakdown), nil
+}
+
+// GetExpenseBreakdown totals a month's expenses per category, for the whole
+// site or for the expenses charged to one block.
+func (s *Service) GetExpenseBreakdown(orgID, blockID string, year, month int) ([]ExpenseBreakdown, error) {
 	query := `
 		SELECT category, SUM(amount) as total, COUNT(*) as count
 		FROM expenses
 		WHERE organization_id = $1
+			AND status IN ('approved', 'paid')
 			AND EXTRACT(YEAR FROM date) = $2
 			AND EXTRACT(MONTH FROM date) = $3
+			AND ($4 = '' OR block_id::text = $4)
 		GROUP BY category
 		ORDER BY total DESC`
 
-	rows, err := s.db.Query(query, orgID, year, month)
+	rows, err := s.db.Query(query, orgID, year, month, blockID)
 	if err != nil {
 		return nil, err
 	}
@@ -100,3 +257,304 @@ func (s *Service) GetExpenseBreakdown(orgID string, year, month int) ([]ExpenseB
 	}
 	return breakdown, nil
 }
+
+// GetVendorSpend totals a year's expenses per vendor, including the part
+// that is still unpaid. Expenses without a vendor are not included.
+func (s *Service) GetVendorSpend(orgID string, year int) ([]VendorSpend, error) {
+	query := `
+		SELECT v.id, v.name, SUM(e.amount) as total,
+			COALESCE(SUM(CASE WHEN e.status = 'approved' THEN e.amount ELSE 0 END), 0) as unpaid,
+			COUNT(*) as count
+		FROM expenses e
+		JOIN vendors v ON e.vendor_id = v.id
+		WHERE e.organization_id = $1
+			AND e.status IN ('approved', 'paid')
+			AND EXTRACT(YEAR FROM e.date) = $2
+		GROUP BY v.id, v.name
+		ORDER BY total DESC`
+
+	rows, err := s.db.Query(query, orgID, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spend []VendorSpend
+	for rows.Next() {
+		var v VendorSpend
+		if err := rows.Scan(&v.VendorID, &v.VendorName, &v.Amount, &v.Unpaid, &v.Count); err != nil {
+			return nil, err
+		}
+		spend = append(spend, v)
+	}
+	return spend, nil
+}
+
+// openedBalance sums the opening balances of the accounts opened between
+// from and to, both included.
+func (s *Service) openedBalance(orgID string, from, to time.Time) (float64, error) {
+	var total float64
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(opening_balance), 0) FROM accounts
+		WHERE organization_id = $1 AND opening_date >= $2 AND opening_date <= $3`,
+		orgID, from, to).Scan(&total)
+	return total, err
+}
+
+// cashAsOf returns the total balance of all accounts at the end of day. An
+// account's opening balance counts from its opening date on.
+func (s *Service) cashAsOf(orgID string, day time.Time) (float64, error) {
+	var total float64
+	err := s.db.QueryRow(`
+		SELECT COALESCE((SELECT SUM(opening_balance) FROM accounts
+				WHERE organization_id = $1 AND opening_date <= $2), 0)
+			+ COALESCE((SELECT SUM(amount) FROM account_entries WHERE organization_id = $1 AND date <= $2), 0)`,
+		orgID, day).Scan(&total)
+	return total, err
+}
+
+func (s *Service) GetCashPosition(orgID string, year int) (*CashPosition, error) {
+	yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOf := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+	if today := time.Now().UTC().Truncate(24 * time.Hour); today.Before(asOf) {
+		asOf = today
+	}
+	pos := &CashPosition{Year: year, AsOf: asOf}
+
+	var err error
+	if pos.Accounts, err = s.accountBalances(orgID, asOf); err != nil {
+		return nil, err
+	}
+	for _, b := range pos.Accounts {
+		pos.Total += b.Balance
+	}
+
+	opening, err := s.cashAsOf(orgID, yearStart.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	// Transfers between accounts net to zero and are left out of the flows.
+	// Accounts opened during the year bring their opening balance in the
+	// month they were opened, as cashAsOf counts it from then on.
+	flowRows, err := s.db.Query(`
+		SELECT EXTRACT(MONTH FROM day)::int as month,
+			COALESCE(SUM(CASE WHEN NOT opened AND amount > 0 THEN amount ELSE 0 END), 0) as inflow,
+			COALESCE(SUM(CASE WHEN NOT opened AND amount < 0 THEN -amount ELSE 0 END), 0) as outflow,
+			COALESCE(SUM(CASE WHEN opened THEN amount ELSE 0 END), 0) as opened
+		FROM (
+			SELECT date as day, amount, FALSE as opened
+			FROM account_entries
+			WHERE organization_id = $1 AND kind <> 'transfer'
+				AND date >= $2 AND date <= $3
+			UNION ALL
+			SELECT opening_date, opening_balance, TRUE
+			FROM accounts
+			WHERE organization_id = $1 AND opening_date >= $2 AND opening_date <= $3
+		) movements
+		GROUP BY month`, orgID, yearStart, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer flowRows.Close()
+
+	flows := make(map[int]CashMonth)
+	for flowRows.Next() {
+		var m CashMonth
+		if err := flowRows.Scan(&m.Month, &m.Inflow, &m.Outflow, &m.Opened); err != nil {
+			return nil, err
+		}
+		flows[m.Month] = m
+	}
+	if err := flowRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for month := 1; month <= int(asOf.Month()); month++ {
+		m := flows[month]
+		m.Month = month
+		m.Opening = opening
+		m.Closing = opening + m.Inflow - m.Outflow + m.Opened
+		opening = m.Closing
+		pos.Months = append(pos.Months, m)
+	}
+	return pos, nil
+}
+
+// GetFundBalances returns inflows, outflows and closing balances per fund so
+// residents can see e.g. that the reserve fund was not spent on cleaning.
+func (s *Service) GetFundBalances(orgID string, year int) ([]FundBalance, error) {
+	yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	return s.fundBalances(orgID, yearStart, yearStart.AddDate(1, 0, 0))
+}
+
+// fundBalances computes the fund balances of the period [yearStart, nextYear).
+func (s *Service) fundBalances(orgID string, yearStart, nextYear time.Time) ([]FundBalance, error) {
+	query := `
+		SELECT fund,
+			COALESCE(SUM(CASE WHEN day < $2 THEN amount ELSE 0 END), 0) as opening,
+			COALESCE(SUM(CASE WHEN day >= $2 AND amount > 0 THEN amount ELSE 0 END), 0) as inflows,
+			COALESCE(SUM(CASE WHEN day >= $2 AND amount < 0 THEN -amount ELSE 0 END), 0) as outflows
+		FROM (
+			SELECT type as fund, paid_at::date as day, amount - adjustment_total
+			FROM dues
+			WHERE organization_id = $1 AND status = 'paid' AND paid_at < $3
+			UNION ALL
+			SELECT fund, date as day, -amount
+			FROM expenses
+			WHERE organization_id = $1 AND status IN ('approved', 'paid') AND date < $3
+		) movements
+		GROUP BY fund`
+
+	rows, err := s.db.Query(query, orgID, yearStart, nextYear)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byFund := make(map[string]*FundBalance)
+	for rows.Next() {
+		var f FundBalance
+		if err := rows.Scan(&f.Fund, &f.Opening, &f.Inflows, &f.Outflows); err != nil {
+			return nil, err
+		}
+		byFund[f.Fund] = &f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	recRows, err := s.db.Query(`
+		SELECT type, COALESCE(SUM(amount - adjustment_total), 0)
+		FROM dues
+		WHERE organization_id = $1 AND status IN ('pending', 'overdue') AND due_date < $2
+		GROUP BY type`, orgID, nextYear)
+	if err != nil {
+		return nil, err
+	}
+	defer recRows.Close()
+
+	for recRows.Next() {
+		var fund string
+		var amount float64
+		if err := recRows.Scan(&fund, &amount); err != nil {
+			return nil, err
+		}
+		if byFund[fund] == nil {
+			byFund[fund] = &FundBalance{Fund: fund}
+		}
+		byFund[fund].Receivable = amount
+	}
+	if err := recRows.Err(); err != nil {
+		return nil, err
+	}
+
+	balances := make([]FundBalance, 0, len(dues.Types))
+	for _, t := range dues.Types {
+		f := FundBalance{Fund: t}
+		if b := byFund[t]; b != nil {
+			f = *b
+		}
+		f.Closing = f.Opening + f.Inflows - f.Outflows
+		balances = append(balances, f)
+	}
+	return balances, nil
+}
+
+// GetBlockReport breaks a month's dues and expenses down per block. Common
+// expenses are shared across blocks in proportion to their unit count.
+func (s *Service) GetBlockReport(orgID string, year, month int) (*BlockReport, error) {
+	report := &BlockReport{Month: month, Year: year}
+
+	rows, err := s.db.Query(`
+		SELECT b.id, COALESCE(b.name, ''),
+			COUNT(DISTINCT u.id) as units,
+			COALESCE(SUM(d.amount - d.adjustment_total), 0) as net_dues,
+			COALESCE(SUM(CASE WHEN d.status = 'paid' THEN d.amount - d.adjustment_total ELSE 0 END), 0) as collected,
+			COALESCE(SUM(CASE WHEN d.status = 'overdue' THEN d.amount - d.adjustment_total ELSE 0 END), 0) as overdue
+		FROM (SELECT id, name FROM blocks WHERE organization_id = $1) b
+		FULL JOIN (SELECT id, block_id FROM units WHERE organization_id = $1) u ON u.block_id = b.id
+		LEFT JOIN dues d ON d.unit_id = u.id AND d.status <> 'restructured'
+			AND EXTRACT(YEAR FROM d.due_date) = $2
+			AND EXTRACT(MONTH FROM d.due_date) = $3
+		GROUP BY b.id, b.name
+		ORDER BY b.name NULLS FIRST`, orgID, year, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block dues: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b BlockSummary
+		if err := rows.Scan(&b.BlockID, &b.BlockName, &b.Units, &b.NetDues, &b.Collected, &b.Overdue); err != nil {
+			return nil, err
+		}
+		report.Blocks = append(report.Blocks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	expRows, err := s.db.Query(`
+		SELECT block_id, COALESCE(SUM(amount), 0)
+		FROM expenses
+		WHERE organization_id = $1 AND status IN ('approved', 'paid')
+			AND EXTRACT(YEAR FROM date) = $2
+			AND EXTRACT(MONTH FROM date) = $3
+		GROUP BY block_id`, orgID, year, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block expenses: %w", err)
+	}
+	defer expRows.Close()
+
+	byBlock := make(map[string]float64)
+	for expRows.Next() {
+		var blockID *string
+		var amount float64
+		if err := expRows.Scan(&blockID, &amount); err != nil {
+			return nil, err
+		}
+		if blockID == nil {
+			report.CommonExpenses = amount
+			continue
+		}
+		byBlock[*blockID] = amount
+	}
+	if err := expRows.Err(); err != nil {
+		return nil, err
+	}
+
+	totalUnits := 0
+	for _, b := range report.Blocks {
+		totalUnits += b.Units
+	}
+	// Common expenses are split per unit in kuruş and each block takes the
+	// shares of its units, so the blocks add up to the site's balance.
+	var perUnit []int64
+	if totalUnits > 0 {
+		perUnit = dues.SplitKurus(dues.ToKurus(report.CommonExpenses), totalUnits)
+	}
+
+	site := &report.Site
+	for i := range report.Blocks {
+		b := &report.Blocks[i]
+		if b.BlockID != nil {
+			b.BlockExpenses = byBlock[*b.BlockID]
+		}
+		var shared int64
+		for _, k := range perUnit[:b.Units] {
+			shared += k
+		}
+		perUnit = perUnit[b.Units:]
+		b.SharedExpenses = float64(shared) / 100
+		b.Balance = b.Collected - b.BlockExpenses - b.SharedExpenses
+
+		site.Units += b.Units
+		site.NetDues += b.NetDues
+		site.Collected += b.Collected
+		site.Overdue += b.Overdue
+		site.BlockExpenses += b.BlockExpenses
+	}
+	site.SharedExpenses = report.CommonExpenses
+	site.Balance = site.Collected - site.BlockExpenses - site.SharedExpenses
+	return report, nil
+}
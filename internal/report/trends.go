@@ -0,0 +1,162 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MaxTrendMonths bounds the range of a trends request.
+const MaxTrendMonths = 60
+
+// Trend groupings.
+const (
+	GroupNone  = ""
+	GroupBlock = "block"
+	GroupType  = "type"
+)
+
+// TrendPoint is one month of a series, with the same meaning as the
+// fields of MonthlySummary: dues are counted in the month they fall due
+// and expenses in the month they were incurred.
+type TrendPoint struct {
+	Year           int     `json:"year"`
+	Month          int     `json:"month"`
+	NetDues        float64 `json:"net_dues"`
+	Collected      float64 `json:"collected"`
+	Overdue        float64 `json:"overdue"`
+	Expenses       float64 `json:"expenses"`
+	Net            float64 `json:"net"`             // collected minus expenses
+	CollectionRate float64 `json:"collection_rate"` // collected / net dues, 0-100
+}
+
+// TrendSeries is the monthly history of one group: the whole site, a block
+// (Key is the block ID, empty for units and expenses without a block) or a
+// dues type.
+type TrendSeries struct {
+	Key    string       `json:"key"`
+	Name   string       `json:"name"`
+	Points []TrendPoint `json:"points"`
+	Totals TrendPoint   `json:"totals"` // the whole range; Year and Month are zero
+}
+
+type Trends struct {
+	From    string        `json:"from"` // YYYY-MM
+	To      string        `json:"to"`   // YYYY-MM
+	GroupBy string        `json:"group_by,omitempty"`
+	Series  []TrendSeries `json:"series"`
+}
+
+// trendGroups holds the grouping key of dues and expenses for each
+// grouping, and how the key is named.
+var trendGroups = map[string]struct{ dues, expenses, name, join string }{
+	GroupNone: {dues: "''", expenses: "''", name: "''"},
+	GroupBlock: {dues: "COALESCE(u.block_id::text, '')", expenses: "COALESCE(block_id::text, '')", name: "COALESCE(b.name, '')",
+		join: "LEFT JOIN blocks b ON b.id::text = k.key"},
+	GroupType: {dues: "d.type", expenses: "fund", name: "k.key"},
+}
+
+// GetTrends returns monthly series from the month of from to the month of
+// to in one query, for the whole site or per block or dues type. Months
+// without activity are included with zeros so every series has a point
+// for each month.
+func (s *Service) GetTrends(orgID string, from, to time.Time, groupBy string) (*Trends, error) {
+	g, ok := trendGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group_by, use block or type")
+	}
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1; months > MaxTrendMonths {
+		return nil, fmt.Errorf("a trend can cover at most %d months", MaxTrendMonths)
+	}
+
+	// Without a grouping there is one series, even when nothing happened.
+	keys := "SELECT key FROM d UNION SELECT key FROM e"
+	if groupBy == GroupNone {
+		keys = "SELECT ''::text AS key"
+	}
+
+	query := `
+		WITH months AS (
+			SELECT generate_series($2::date, $3::date, INTERVAL '1 month')::date AS m
+		),
+		d AS (
+			SELECT date_trunc('month', d.due_date)::date AS m, ` + g.dues + ` AS key,
+				SUM(d.amount - d.adjustment_total) AS net_dues,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.status = 'paid'), 0) AS collected,
+				COALESCE(SUM(d.amount - d.adjustment_total) FILTER (WHERE d.status = 'overdue'), 0) AS overdue
+			FROM dues d
+			LEFT JOIN units u ON d.unit_id = u.id
+			WHERE d.organization_id = $1 AND d.status <> 'restructured'
+				AND d.due_date >= $2 AND d.due_date < $3::date + INTERVAL '1 month'
+			GROUP BY 1, 2
+		),
+		e AS (
+			SELECT date_trunc('month', date)::date AS m, ` + g.expenses + ` AS key, SUM(amount) AS expenses
+			FROM expenses
+			WHERE organization_id = $1 AND status IN ('approved', 'paid')
+				AND date >= $2 AND date < $3::date + INTERVAL '1 month'
+			GROUP BY 1, 2
+		),
+		k AS (` + keys + `)
+		SELECT k.key, ` + g.name + ` AS name,
+			EXTRACT(YEAR FROM months.m)::int, EXTRACT(MONTH FROM months.m)::int,
+			COALESCE(d.net_dues, 0), COALESCE(d.collected, 0), COALESCE(d.overdue, 0), COALESCE(e.expenses, 0)
+		FROM k
+		CROSS JOIN months
+		` + g.join + `
+		LEFT JOIN d ON d.m = months.m AND d.key = k.key
+		LEFT JOIN e ON e.m = months.m AND e.key = k.key
+		ORDER BY name, k.key, months.m`
+
+	rows, err := s.db.Query(query, orgID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trends: %w", err)
+	}
+	defer rows.Close()
+
+	trends := &Trends{From: from.Format("2006-01"), To: to.Format("2006-01"), GroupBy: groupBy, Series: []TrendSeries{}}
+	var series *TrendSeries
+	for rows.Next() {
+		var key, name string
+		var p TrendPoint
+		if err := rows.Scan(&key, &name, &p.Year, &p.Month, &p.NetDues, &p.Collected, &p.Overdue, &p.Expenses); err != nil {
+			return nil, err
+		}
+		if series == nil || series.Key != key {
+			trends.Series = append(trends.Series, TrendSeries{Key: key, Name: name})
+			series = &trends.Series[len(trends.Series)-1]
+		}
+		p.Net = p.Collected - p.Expenses
+		p.CollectionRate = CollectionRate(p.Collected, p.NetDues)
+		series.Points = append(series.Points, p)
+
+		t := &series.Totals
+		t.NetDues += p.NetDues
+		t.Collected += p.Collected
+		t.Overdue += p.Overdue
+		t.Expenses += p.Expenses
+		t.Net += p.Net
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range trends.Series {
+		t := &trends.Series[i].Totals
+		t.CollectionRate = CollectionRate(t.Collected, t.NetDues)
+	}
+	return trends, nil
+}
+
+// CollectionRate is the collected share of the dues as a percentage with
+// one decimal.
+func CollectionRate(collected, netDues float64) float64 {
+	if netDues <= 0 {
+		return 0
+	}
+	return math.Round(collected/netDues*1000) / 10
+}
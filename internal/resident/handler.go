@@ -2,9 +2,11 @@ package resident
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
 
@@ -34,13 +36,19 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	orgID := chi.URLParam(r, "orgId")
-	residents, err := h.service.ListByOrganization(orgID)
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	residents, res, err := h.service.List(orgID, p)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, residents)
+	response.WithMeta(w, http.StatusOK, residents, p.Meta(res, residents))
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -73,7 +81,10 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(id); errors.Is(err, ErrHasHistory) {
+		response.Error(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -17,6 +17,7 @@ type CreateRequest struct {
 	Phone    string `json:"phone"`
 	Email    string `json:"email,omitempty"`
 	UnitID   string `json:"unit_id,omitempty"`
+	Role     string `json:"role,omitempty"` // occupancy role in the unit, defaults to owner
 }
 
 type UpdateRequest struct {
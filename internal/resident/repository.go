@@ -3,6 +3,10 @@ package resident
 import (
 	"database/sql"
 	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/unit"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Repository struct {
@@ -13,15 +17,38 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(res *Resident) error {
+// Create inserts a resident and, when a unit is given, assigns them to it
+// in the same transaction.
+func (r *Repository) Create(res *Resident, role string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createTx(tx, res, role); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func createTx(tx *sql.Tx, res *Resident, role string) error {
 	query := `
-		INSERT INTO residents (full_name, phone, email, unit_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO residents (full_name, phone, email)
+		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(query,
-		res.FullName, res.Phone, res.Email, res.UnitID,
+	err := tx.QueryRow(query,
+		res.FullName, res.Phone, res.Email,
 	).Scan(&res.ID, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if res.UnitID != nil {
+		return unit.AssignTx(tx, *res.UnitID, res.ID, role)
+	}
+	return nil
 }
 
 func (r *Repository) GetByID(id string) (*Resident, error) {
@@ -42,14 +69,55 @@ func (r *Repository) GetByID(id string) (*Resident, error) {
 	return res, nil
 }
 
-func (r *Repository) ListByOrganization(orgID string) ([]Resident, error) {
-	query := `SELECT r.id, r.full_name, r.phone, r.email, r.unit_id, r.created_at, r.updated_at
+// selectResident lists residents with the unit they live in, including
+// those who are in a unit only as an occupant.
+const selectResident = `SELECT r.id, r.full_name, r.phone, r.email, u.id, r.created_at, r.updated_at
 		FROM residents r
-		JOIN units u ON r.unit_id = u.id
-		WHERE u.organization_id = $1
-		ORDER BY r.full_name`
+		JOIN units u ON u.id = ` + unit.ResidentUnit
 
-	rows, err := r.db.Query(query, orgID)
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanResident(row scanner, res *Resident) error {
+	return row.Scan(
+		&res.ID, &res.FullName, &res.Phone, &res.Email,
+		&res.UnitID, &res.CreatedAt, &res.UpdatedAt,
+	)
+}
+
+// listConfig is what the resident list can be sorted and searched by; ?q=
+// matches the name, phone, email and unit number.
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"full_name":  "r.full_name",
+		"created_at": "r.created_at",
+	},
+	Default: "full_name",
+	ID:      "r.id",
+	Search:  []string{"r.full_name", "r.phone", "r.email", "u.unit_number"},
+	Date:    "r.created_at",
+}
+
+// List returns a page of the organization's residents.
+func (r *Repository) List(orgID string, p *listing.Params) ([]Resident, listing.Result, error) {
+	query, args := p.Filter(selectResident+" WHERE u.organization_id = $1", []interface{}{orgID})
+	residents := []Resident{}
+	result, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var res Resident
+		if err := scanResident(rows, &res); err != nil {
+			return err
+		}
+		residents = append(residents, res)
+		return nil
+	})
+	return residents, result, err
+}
+
+// ListByOrganization returns every resident of the organization, for the
+// importer to match rows against.
+func (r *Repository) ListByOrganization(orgID string) ([]Resident, error) {
+	rows, err := r.db.Query(selectResident+" WHERE u.organization_id = $1 ORDER BY r.full_name", orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -58,10 +126,7 @@ func (r *Repository) ListByOrganization(orgID string) ([]Resident, error) {
 	var residents []Resident
 	for rows.Next() {
 		var res Resident
-		if err := rows.Scan(
-			&res.ID, &res.FullName, &res.Phone, &res.Email,
-			&res.UnitID, &res.CreatedAt, &res.UpdatedAt,
-		); err != nil {
+		if err := scanResident(rows, &res); err != nil {
 			return nil, err
 		}
 		residents = append(residents, res)
@@ -84,21 +149,55 @@ func (r *Repository) Update(id string, req UpdateRequest) (*Resident, error) {
 	if req.Email != nil {
 		res.Email = *req.Email
 	}
-	if req.UnitID != nil {
-		res.UnitID = req.UnitID
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	query := `UPDATE residents SET full_name=$1, phone=$2, email=$3, unit_id=$4, updated_at=NOW()
-		WHERE id=$5 RETURNING updated_at`
+	query := `UPDATE residents SET full_name=$1, phone=$2, email=$3, updated_at=NOW()
+		WHERE id=$4`
 
-	err = r.db.QueryRow(query, res.FullName, res.Phone, res.Email, res.UnitID, id).Scan(&res.UpdatedAt)
-	if err != nil {
+	if _, err := tx.Exec(query, res.FullName, res.Phone, res.Email, id); err != nil {
 		return nil, err
 	}
-	return res, nil
+
+	// The unit link is changed through the unit assignment so both sides
+	// stay in sync; an empty unit_id moves the resident out.
+	if req.UnitID != nil {
+		if *req.UnitID == "" {
+			err = unit.ReleaseResidentTx(tx, id)
+		} else {
+			err = unit.AssignTx(tx, *req.UnitID, id, "")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// HasHistory reports whether the resident has occupancies or dues billed
+// to them. Both are kept for the books, so such a resident is not deleted.
+func (r *Repository) HasHistory(id string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM unit_occupants WHERE resident_id = $1)
+		OR EXISTS (SELECT 1 FROM dues WHERE payer_resident_id = $1)`, id).Scan(&ok)
+	return ok, err
 }
 
 func (r *Repository) Delete(id string) error {
 	_, err := r.db.Exec("DELETE FROM residents WHERE id = $1", id)
+	// History written after HasHistory was checked still blocks the delete
+	// through its foreign key.
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" &&
+		(pqErr.Table == "unit_occupants" || pqErr.Table == "dues") {
+		return ErrHasHistory
+	}
 	return err
 }
@@ -1,6 +1,16 @@
 package resident
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/unit"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
 
 type Service struct {
 	repo *Repository
@@ -11,40 +21,143 @@ func NewService(repo *Repository) *Service {
 }
 
 func (s *Service) Create(req CreateRequest) (*Resident, error) {
+	res, err := newResident(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(res, req.Role); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateTx creates a resident as part of the caller's transaction, e.g. a
+// bulk import that commits all rows together.
+func (s *Service) CreateTx(tx *sql.Tx, req CreateRequest) (*Resident, error) {
+	res, err := newResident(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := createTx(tx, res, req.Role); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func newResident(req CreateRequest) (*Resident, error) {
+	req.FullName = strings.TrimSpace(req.FullName)
 	if req.FullName == "" || req.Phone == "" {
 		return nil, fmt.Errorf("full name and phone are required")
 	}
+	phone, err := NormalizePhone(req.Phone)
+	if err != nil {
+		return nil, err
+	}
+	email, err := NormalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if req.Role != "" && req.Role != unit.RoleOwner && req.Role != unit.RoleCoOwner &&
+		req.Role != unit.RoleTenant && req.Role != unit.RoleFamily {
+		return nil, fmt.Errorf("role must be owner, co_owner, tenant or family")
+	}
 
 	var unitID *string
 	if req.UnitID != "" {
 		unitID = &req.UnitID
 	}
 
-	res := &Resident{
+	return &Resident{
 		FullName: req.FullName,
-		Phone:    req.Phone,
-		Email:    req.Email,
+		Phone:    phone,
+		Email:    email,
 		UnitID:   unitID,
-	}
-
-	if err := s.repo.Create(res); err != nil {
-		return nil, err
-	}
-	return res, nil
+	}, nil
 }
 
 func (s *Service) GetByID(id string) (*Resident, error) {
 	return s.repo.GetByID(id)
 }
 
+func (s *Service) List(orgID string, p *listing.Params) ([]Resident, listing.Result, error) {
+	return s.repo.List(orgID, p)
+}
+
 func (s *Service) ListByOrganization(orgID string) ([]Resident, error) {
 	return s.repo.ListByOrganization(orgID)
 }
 
 func (s *Service) Update(id string, req UpdateRequest) (*Resident, error) {
+	if req.Phone != nil {
+		phone, err := NormalizePhone(*req.Phone)
+		if err != nil {
+			return nil, err
+		}
+		req.Phone = &phone
+	}
+	if req.Email != nil {
+		email, err := NormalizeEmail(*req.Email)
+		if err != nil {
+			return nil, err
+		}
+		req.Email = &email
+	}
 	return s.repo.Update(id, req)
 }
 
+// ErrHasHistory is returned when deleting a resident who has lived in a
+// unit or been billed dues.
+var ErrHasHistory = errors.New("resident has occupancy or dues history and cannot be deleted")
+
 func (s *Service) Delete(id string) error {
+	history, err := s.repo.HasHistory(id)
+	if err != nil {
+		return err
+	}
+	if history {
+		return ErrHasHistory
+	}
 	return s.repo.Delete(id)
 }
+
+// NormalizePhone accepts Turkish phone numbers in the usual spellings
+// ("0532 123 45 67", "+90 (532) 123-4567", "5321234567") and returns them
+// as 0XXXXXXXXXX, the form used for SMS and WhatsApp links.
+func NormalizePhone(phone string) (string, error) {
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.' || r == '+':
+		default:
+			return "", fmt.Errorf("invalid phone number %q", phone)
+		}
+	}
+
+	d := digits.String()
+	switch {
+	case len(d) == 12 && strings.HasPrefix(d, "90"):
+		d = d[2:]
+	case len(d) == 11 && d[0] == '0':
+		d = d[1:]
+	}
+	if len(d) != 10 || d[0] < '2' || d[0] > '5' {
+		return "", fmt.Errorf("invalid phone number %q", phone)
+	}
+	return "0" + d, nil
+}
+
+// NormalizeEmail trims and lower-cases an optional email address and
+// rejects anything that is not a bare address.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return "", fmt.Errorf("invalid email address %q", email)
+	}
+	return email, nil
+}
@@ -0,0 +1,36 @@
+package search
+
+import "time"
+
+// Result types.
+const (
+	TypeResident = "resident"
+	TypeUnit     = "unit"
+	TypeExpense  = "expense"
+	TypeDue      = "due"
+)
+
+var Types = []string{TypeResident, TypeUnit, TypeExpense, TypeDue}
+
+// Result is one match. Title is the text that matched (a name, unit
+// number or description) and Subtitle places it: the block and unit of a
+// resident, the vendor and category of an expense.
+type Result struct {
+	Type     string     `json:"type"`
+	ID       string     `json:"id"`
+	Title    string     `json:"title"`
+	Subtitle string     `json:"subtitle,omitempty"`
+	UnitID   *string    `json:"unit_id,omitempty"`
+	Date     *time.Time `json:"date,omitempty"`   // expense date or due date
+	Amount   *float64   `json:"amount,omitempty"` // expense amount or net due
+	Status   string     `json:"status,omitempty"`
+	Score    float64    `json:"score"`
+}
+
+// Results are the best matches across types, highest score first. Counts
+// holds how many matches each type has in all, beyond the limit.
+type Results struct {
+	Query   string         `json:"query"`
+	Counts  map[string]int `json:"counts"`
+	Results []Result       `json:"results"`
+}
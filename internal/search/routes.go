@@ -0,0 +1,7 @@
+package search
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/organizations/{orgId}/search", h.Search)
+}
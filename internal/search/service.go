@@ -0,0 +1,200 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/expense"
+	"github.com/mustafakemalcelik/sitetakip/internal/unit"
+)
+
+const (
+	MinQueryLength = 2
+	MaxWords       = 8
+	DefaultLimit   = 20
+	MaxLimit       = 100
+)
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Parameters of searchQuery: $1 organization, $2 the words as a tsquery,
+// $3 the query as typed, $4 and $5 expense category codes and their
+// labels, $6 the types asked for, $7 the limit. Both $2 and $3 are folded
+// with search_fold in SQL so they compare with the indexed expressions.
+// Beyond the limit, the best row of each type is still returned so the
+// totals per type are known.
+//
+// Each part finds its rows through the full-text and trigram indexes of
+// migration 019 on the table's own text, and ranks them by a document that
+// adds context, such as the block of a resident, so "ayşe b blok" puts
+// the Ayşe living in block B first.
+const searchQuery = `
+	SELECT type, id, title, subtitle, unit_id, date, amount, status, score, total, rank
+	FROM (
+		SELECT m.*,
+			COUNT(*) OVER (PARTITION BY type) AS total,
+			ROW_NUMBER() OVER (PARTITION BY type ORDER BY score DESC) AS type_rank,
+			ROW_NUMBER() OVER (ORDER BY score DESC, date DESC NULLS LAST, title) AS rank
+		FROM (
+			SELECT 'resident' AS type, r.id::text AS id, r.full_name AS title,
+				concat_ws(' · ', b.name, u.unit_number) AS subtitle, u.id::text AS unit_id,
+				NULL::date AS date, NULL::numeric AS amount, '' AS status,
+				ts_rank(setweight(to_tsvector('simple', search_fold(r.full_name)), 'A') ||
+					setweight(to_tsvector('simple', search_fold(concat_ws(' ', b.name, u.unit_number, r.phone, r.email))), 'B'),
+					to_tsquery('simple', search_fold($2)))
+					+ similarity(search_fold(r.full_name), search_fold($3)) AS score
+			FROM residents r
+			JOIN units u ON u.id = ` + unit.ResidentUnit + `
+			LEFT JOIN blocks b ON u.block_id = b.id
+			WHERE u.organization_id = $1
+				AND (to_tsvector('simple', search_fold(r.full_name)) @@ to_tsquery('simple', search_fold($2))
+					OR search_fold(r.full_name) % search_fold($3))
+
+			UNION ALL
+
+			SELECT 'unit', u.id::text, u.unit_number,
+				concat_ws(' · ', b.name, us.full_name), u.id::text,
+				NULL::date, NULL::numeric, '',
+				ts_rank(setweight(to_tsvector('simple', search_fold(u.unit_number)), 'A') ||
+					setweight(to_tsvector('simple', search_fold(concat_ws(' ', b.name, us.full_name))), 'B'),
+					to_tsquery('simple', search_fold($2)))
+					+ similarity(search_fold(u.unit_number), search_fold($3))
+			FROM units u
+			LEFT JOIN blocks b ON u.block_id = b.id
+			LEFT JOIN residents us ON u.resident_id = us.id
+			WHERE u.organization_id = $1
+				AND (to_tsvector('simple', search_fold(u.unit_number)) @@ to_tsquery('simple', search_fold($2))
+					OR search_fold(u.unit_number) % search_fold($3))
+
+			UNION ALL
+
+			SELECT 'expense', e.id::text, COALESCE(NULLIF(e.description, ''), c.label),
+				concat_ws(' · ', v.name, c.label, NULLIF(e.voucher_no, '')), NULL,
+				e.date, e.amount, e.status,
+				ts_rank(setweight(to_tsvector('simple', search_fold(e.description || ' ' || e.category)), 'A') ||
+					setweight(to_tsvector('simple', search_fold(concat_ws(' ', c.label, v.name, b.name, e.voucher_no))), 'B'),
+					to_tsquery('simple', search_fold($2)))
+					+ similarity(search_fold(e.description), search_fold($3))
+			FROM expenses e
+			LEFT JOIN vendors v ON e.vendor_id = v.id
+			LEFT JOIN blocks b ON e.block_id = b.id
+			CROSS JOIN LATERAL (
+				SELECT COALESCE((SELECT l.label FROM unnest($4::text[], $5::text[]) AS l(code, label)
+					WHERE l.code = e.category), e.category) AS label
+			) c
+			WHERE e.organization_id = $1
+				AND (to_tsvector('simple', search_fold(e.description || ' ' || e.category)) @@ to_tsquery('simple', search_fold($2))
+					OR search_fold(e.description) % search_fold($3))
+
+			UNION ALL
+
+			SELECT 'due', d.id::text, d.description,
+				concat_ws(' · ', b.name, u.unit_number), d.unit_id::text,
+				d.due_date, d.amount - d.adjustment_total, d.status,
+				ts_rank(setweight(to_tsvector('simple', search_fold(d.description)), 'A') ||
+					setweight(to_tsvector('simple', search_fold(concat_ws(' ', b.name, u.unit_number))), 'B'),
+					to_tsquery('simple', search_fold($2)))
+					+ similarity(search_fold(d.description), search_fold($3))
+			FROM dues d
+			LEFT JOIN units u ON d.unit_id = u.id
+			LEFT JOIN blocks b ON u.block_id = b.id
+			WHERE d.organization_id = $1 AND d.description <> ''
+				AND (to_tsvector('simple', search_fold(d.description)) @@ to_tsquery('simple', search_fold($2))
+					OR search_fold(d.description) % search_fold($3))
+		) m
+		WHERE type = ANY($6)
+	) ranked
+	WHERE rank <= $7 OR type_rank = 1
+	ORDER BY rank`
+
+// Search finds residents, units, expenses and dues of the organization
+// matching q. Every word of q matches as a word prefix, any of them is
+// enough and rows matching more rank higher; the whole of q also matches
+// names and descriptions by similarity, which catches typos. Case,
+// Turkish dotted and dotless i and accents are ignored. Without types all
+// types are searched.
+func (s *Service) Search(orgID, q string, types []string, limit int) (*Results, error) {
+	q = strings.TrimSpace(q)
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	if utf8.RuneCountInString(q) < MinQueryLength || len(words) == 0 {
+		return nil, fmt.Errorf("q must be at least %d characters", MinQueryLength)
+	}
+	if len(words) > MaxWords {
+		words = words[:MaxWords]
+	}
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+
+	if len(types) == 0 {
+		types = Types
+	}
+	for _, t := range types {
+		if !validType(t) {
+			return nil, fmt.Errorf("invalid type %q, use %s", t, strings.Join(Types, ", "))
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	codes := make([]string, 0, len(expense.CategoryLabels))
+	for code := range expense.CategoryLabels {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	labels := make([]string, len(codes))
+	for i, code := range codes {
+		labels[i] = expense.CategoryLabels[code]
+	}
+
+	rows, err := s.db.Query(searchQuery, orgID, strings.Join(words, " | "), q,
+		pq.Array(codes), pq.Array(labels), pq.Array(types), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	results := &Results{Query: q, Counts: map[string]int{}, Results: []Result{}}
+	for _, t := range types {
+		results.Counts[t] = 0
+	}
+	for rows.Next() {
+		var res Result
+		var total, rank int
+		if err := rows.Scan(&res.Type, &res.ID, &res.Title, &res.Subtitle, &res.UnitID,
+			&res.Date, &res.Amount, &res.Status, &res.Score, &total, &rank); err != nil {
+			return nil, err
+		}
+		results.Counts[res.Type] = total
+		if rank <= limit {
+			results.Results = append(results.Results, res)
+		}
+	}
+	return results, rows.Err()
+}
+
+func validType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package unit
+
+import (
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+)
+
+var lineLabels = map[string]string{
+	LineCharge:     "Tahakkuk",
+	LineAdjustment: "Düzeltme",
+	LinePayment:    "Tahsilat",
+}
+
+var statementColumns = []export.Column{
+	{Title: "Tarih", Width: 12},
+	{Title: "İşlem", Width: 12},
+	{Title: "Tür", Width: 14},
+	{Title: "Açıklama", Width: 36},
+	{Title: "Borç", Width: 12},
+	{Title: "Alacak", Width: 12},
+	{Title: "Bakiye", Width: 12},
+}
+
+func statementName(st *Statement) string {
+	unit := st.UnitNumber
+	if st.BlockName != "" {
+		unit = st.BlockName + "-" + unit
+	}
+	unit = strings.NewReplacer("/", "-", " ", "-").Replace(unit)
+	return "ekstre-" + unit + "-" + st.From.Format("2006-01-02") + "-" + st.To.Format("2006-01-02")
+}
+
+// exportStatement writes the statement between an opening (devir) and a
+// totals row.
+func exportStatement(add export.AddFunc, st *Statement) error {
+	if err := add(st.From, "Devir", nil, "Önceki dönemden devreden bakiye", nil, nil, st.OpeningBalance); err != nil {
+		return err
+	}
+	for _, l := range st.Lines {
+		err := add(l.Date, export.Label(lineLabels, l.Kind), export.Label(dues.TypeLabels, l.Type),
+			l.Description, l.Debit, l.Credit, l.Balance)
+		if err != nil {
+			return err
+		}
+	}
+	return add(st.To, "Toplam", nil, nil, st.TotalDebit, st.TotalCredit, st.ClosingBalance)
+}
@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxGeneratedUnits bounds a single layout so a typo in the floor count
+// cannot create thousands of units.
+const MaxGeneratedUnits = 5000
+
+const (
+	defaultPattern      = "{floor}{index:02}"
+	defaultBlockPattern = "{block}-{floor}{index:02}"
+	maxUnitNumberLength = 20 // units.unit_number is VARCHAR(20)
+)
+
+var placeholder = regexp.MustCompile(`\{(block|floor|index|seq)(?::(\d+))?\}`)
+
+// layout expands a generate request into the list of units it describes.
+// It checks the pattern and rejects layouts that number two units alike.
+func layout(req GenerateRequest) ([]GeneratedUnit, error) {
+	pattern := req.Pattern
+	if pattern == "" {
+		pattern = defaultPattern
+		if len(req.Blocks) > 0 {
+			pattern = defaultBlockPattern
+		}
+	}
+	if err := checkPattern(pattern, len(req.Blocks) > 0); err != nil {
+		return nil, err
+	}
+
+	firstFloor := 1
+	if req.FirstFloor != nil {
+		firstFloor = *req.FirstFloor
+	}
+
+	blocks := req.Blocks
+	if len(blocks) == 0 {
+		blocks = []GenerateBlock{{}}
+	}
+
+	var units []GeneratedUnit
+	seen := make(map[string]bool)
+	for _, b := range blocks {
+		name := strings.TrimSpace(b.Name)
+		if len(req.Blocks) > 0 && name == "" {
+			return nil, fmt.Errorf("every block needs a name")
+		}
+		floors, perFloor := b.Floors, b.UnitsPerFloor
+		if floors == 0 {
+			floors = req.Floors
+		}
+		if perFloor == 0 {
+			perFloor = req.UnitsPerFloor
+		}
+		if floors <= 0 || perFloor <= 0 {
+			return nil, fmt.Errorf("floors and units_per_floor must be positive")
+		}
+		if len(units)+floors*perFloor > MaxGeneratedUnits {
+			return nil, fmt.Errorf("a layout can create at most %d units", MaxGeneratedUnits)
+		}
+
+		seq := 0
+		for f := 0; f < floors; f++ {
+			floor := firstFloor + f
+			for i := 1; i <= perFloor; i++ {
+				seq++
+				number := expand(pattern, name, floor, i, seq)
+				if err := CheckUnitNumber(number); err != nil {
+					return nil, err
+				}
+				key := name + "\x00" + number
+				if seen[key] {
+					return nil, fmt.Errorf("pattern %q gives unit number %q twice", pattern, number)
+				}
+				seen[key] = true
+				units = append(units, GeneratedUnit{BlockName: name, UnitNumber: number, Floor: floor})
+			}
+		}
+	}
+	return units, nil
+}
+
+func checkPattern(pattern string, withBlocks bool) error {
+	rest := placeholder.ReplaceAllString(pattern, "")
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("pattern may only use {block}, {floor}, {index} and {seq}")
+	}
+	if !withBlocks && strings.Contains(pattern, "{block") {
+		return fmt.Errorf("pattern uses {block} but no blocks are given")
+	}
+	return nil
+}
+
+// expand fills the pattern's placeholders for one unit.
+func expand(pattern, block string, floor, index, seq int) string {
+	return placeholder.ReplaceAllStringFunc(pattern, func(m string) string {
+		parts := placeholder.FindStringSubmatch(m)
+		width, _ := strconv.Atoi(parts[2])
+		switch parts[1] {
+		case "block":
+			return block
+		case "floor":
+			return pad(floor, width)
+		case "index":
+			return pad(index, width)
+		default:
+			return pad(seq, width)
+		}
+	})
+}
+
+func pad(n, width int) string {
+	if n < 0 {
+		return "-" + pad(-n, width)
+	}
+	return fmt.Sprintf("%0*d", width, n)
+}
@@ -2,9 +2,14 @@ package unit
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
 	"github.com/mustafakemalcelik/sitetakip/pkg/response"
 )
 
@@ -33,15 +38,45 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusCreated, u)
 }
 
+func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "orgId")
+	var req GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		req.DryRun = true
+	}
+
+	result, err := h.service.Generate(orgID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	}
+	response.JSON(w, status, result)
+}
+
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	orgID := chi.URLParam(r, "orgId")
-	units, err := h.service.ListByOrganization(orgID)
+	p, err := listing.Parse(r, listConfig)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	units, res, err := h.service.List(orgID, r.URL.Query().Get("block_id"), p)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	response.JSON(w, http.StatusOK, units)
+	response.WithMeta(w, http.StatusOK, units, p.Meta(res, units))
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -74,10 +109,182 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := h.service.Delete(id); err != nil {
+	if err := h.service.Delete(id); errors.Is(err, ErrHasReceipts) {
+		response.Error(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 }
+
+func (h *Handler) AddOccupant(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req AddOccupantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	o, err := h.service.AddOccupant(id, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, o)
+}
+
+func (h *Handler) ListOccupants(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	all := r.URL.Query().Get("all") == "true"
+	occupants, err := h.service.ListOccupants(id, all)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, occupants)
+}
+
+func (h *Handler) UpdateOccupant(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	occupantID := chi.URLParam(r, "occupantId")
+	var req UpdateOccupantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	o, err := h.service.UpdateOccupant(id, occupantID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, o)
+}
+
+func (h *Handler) DeleteOccupant(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	occupantID := chi.URLParam(r, "occupantId")
+	if err := h.service.DeleteOccupant(id, occupantID); err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
+func (h *Handler) SetPayer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req SetPayerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	payers, err := h.service.SetPayer(id, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, payers)
+}
+
+func (h *Handler) MoveOut(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	occupantID := chi.URLParam(r, "occupantId")
+	var req MoveOutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	st, err := h.service.MoveOut(id, occupantID, req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, st)
+}
+
+func (h *Handler) Settlement(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	occupantID := chi.URLParam(r, "occupantId")
+	st, err := h.service.Settlement(id, occupantID)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, st)
+}
+
+func (h *Handler) Clearance(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	occupantID := chi.URLParam(r, "occupantId")
+	letter, err := h.service.Clearance(id, occupantID)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, letter)
+}
+
+// Statement returns a unit's account statement. from and to are
+// YYYY-MM-DD and default to the start of the year and today.
+func (h *Handler) Statement(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	from := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	to := today
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid from format, use YYYY-MM-DD")
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid to format, use YYYY-MM-DD")
+			return
+		}
+		to = t
+	}
+
+	st, err := h.service.Statement(id, from, to)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch format := export.Format(r); format {
+	case "":
+		response.JSON(w, http.StatusOK, st)
+	case export.PDF:
+		doc, err := h.service.StatementPDF(st)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := pdf.Serve(w, statementName(st), doc); err != nil {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+		}
+	default:
+		export.Stream(w, format, statementName(st), statementColumns, func(add export.AddFunc) error {
+			return exportStatement(add, st)
+		})
+	}
+}
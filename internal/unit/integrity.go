@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+)
+
+// Integrity mismatch kinds
+const (
+	MismatchUnitSide        = "unit_resident_mismatch"  // units.resident_id not mirrored by residents.unit_id
+	MismatchResidentSide    = "resident_unit_mismatch"  // residents.unit_id not mirrored by units.resident_id
+	MismatchMultipleUnits   = "resident_multiple_units" // one resident set on several units
+	MismatchMissingOccupant = "missing_occupancy"       // assigned resident without an open occupancy
+)
+
+// Mismatch is one inconsistency between units.resident_id,
+// residents.unit_id and unit_occupants.
+type Mismatch struct {
+	Kind           string `json:"kind"`
+	OrganizationID string `json:"organization_id"`
+	UnitID         string `json:"unit_id"`
+	UnitNumber     string `json:"unit_number"`
+	ResidentID     string `json:"resident_id"`
+	ResidentName   string `json:"resident_name"`
+}
+
+type IntegrityReport struct {
+	Mismatches []Mismatch `json:"mismatches"`
+	Repaired   int        `json:"repaired"` // units touched by the repair
+}
+
+const integrityQuery = `
+	SELECT 'unit_resident_mismatch', u.organization_id, u.id, u.unit_number, r.id, r.full_name
+	FROM units u
+	JOIN residents r ON r.id = u.resident_id
+	WHERE r.unit_id IS DISTINCT FROM u.id
+	UNION ALL
+	SELECT 'resident_unit_mismatch', u.organization_id, u.id, u.unit_number, r.id, r.full_name
+	FROM residents r
+	JOIN units u ON u.id = r.unit_id
+	WHERE u.resident_id IS DISTINCT FROM r.id
+	UNION ALL
+	SELECT 'resident_multiple_units', u.organization_id, u.id, u.unit_number, r.id, r.full_name
+	FROM units u
+	JOIN residents r ON r.id = u.resident_id
+	WHERE (SELECT COUNT(*) FROM units u2 WHERE u2.resident_id = u.resident_id) > 1
+	UNION ALL
+	SELECT 'missing_occupancy', u.organization_id, u.id, u.unit_number, r.id, r.full_name
+	FROM units u
+	JOIN residents r ON r.id = u.resident_id
+	WHERE NOT EXISTS (SELECT 1 FROM unit_occupants o WHERE o.unit_id = u.id AND o.resident_id = r.id
+		AND o.move_in_date <= CURRENT_DATE AND (o.move_out_date IS NULL OR o.move_out_date > CURRENT_DATE))
+	ORDER BY 2, 4`
+
+// Repair steps, run in order. units.resident_id is treated as the source
+// of truth; residents.unit_id only fills a unit that has no resident.
+var repairSteps = []string{
+	// A resident on several units keeps the unit their own row points at,
+	// otherwise the oldest one.
+	`WITH dup AS (
+		SELECT u.id, u.resident_id, ROW_NUMBER() OVER (PARTITION BY u.resident_id
+			ORDER BY COALESCE(u.id = r.unit_id, FALSE) DESC, u.created_at) AS rn
+		FROM units u
+		JOIN residents r ON r.id = u.resident_id
+	), cleared AS (
+		UPDATE units u SET resident_id = NULL, updated_at = NOW()
+		FROM dup WHERE u.id = dup.id AND dup.rn > 1
+		RETURNING u.id, dup.resident_id
+	), closed AS (
+		UPDATE unit_occupants o SET move_out_date = CURRENT_DATE, updated_at = NOW()
+		FROM cleared c
+		WHERE o.unit_id = c.id AND o.resident_id = c.resident_id
+			AND (o.move_out_date IS NULL OR o.move_out_date > CURRENT_DATE)
+	)
+	SELECT id FROM cleared`,
+
+	// An empty unit is given to the single resident pointing at it, as
+	// long as that resident is not on another unit.
+	`UPDATE units u SET resident_id = r.id, updated_at = NOW()
+	FROM residents r
+	WHERE r.unit_id = u.id AND u.resident_id IS NULL
+		AND (SELECT COUNT(*) FROM residents r2 WHERE r2.unit_id = u.id) = 1
+		AND NOT EXISTS (SELECT 1 FROM units u2 WHERE u2.resident_id = r.id)
+	RETURNING u.id`,
+
+	// Mirror units.resident_id onto the resident.
+	`UPDATE residents r SET unit_id = u.id, updated_at = NOW()
+	FROM units u
+	WHERE u.resident_id = r.id AND r.unit_id IS DISTINCT FROM u.id
+	RETURNING u.id`,
+
+	// Drop resident links the unit does not confirm.
+	`UPDATE residents r SET unit_id = NULL, updated_at = NOW()
+	FROM units u
+	WHERE r.unit_id = u.id AND u.resident_id IS DISTINCT FROM r.id
+	RETURNING u.id`,
+
+	// Every assigned resident has an open occupancy.
+	`INSERT INTO unit_occupants (unit_id, resident_id, role, move_in_date)
+	SELECT u.id, u.resident_id, 'owner', CURRENT_DATE
+	FROM units u
+	WHERE u.resident_id IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM unit_occupants o WHERE o.unit_id = u.id AND o.resident_id = u.resident_id
+			AND o.move_in_date <= CURRENT_DATE AND (o.move_out_date IS NULL OR o.move_out_date > CURRENT_DATE))
+	RETURNING unit_id`,
+}
+
+// CheckIntegrity reports mismatches between both sides of the
+// unit–resident link. With repair set it also fixes them in one
+// transaction and re-bills unpaid dues of the units it touched.
+func (r *Repository) CheckIntegrity(repair bool) (*IntegrityReport, error) {
+	report := &IntegrityReport{}
+	mismatches, err := listMismatches(r.db)
+	if err != nil {
+		return nil, err
+	}
+	report.Mismatches = mismatches
+	if !repair || len(mismatches) == 0 {
+		return report, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("LOCK TABLE units, residents IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return nil, err
+	}
+
+	touched := map[string]bool{}
+	for _, step := range repairSteps {
+		rows, err := tx.Query(step)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			touched[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for id := range touched {
+		if err := dues.ReassignPayers(tx, id, today); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.Repaired = len(touched)
+	return report, nil
+}
+
+func listMismatches(db *sql.DB) ([]Mismatch, error) {
+	rows, err := db.Query(integrityQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []Mismatch{}
+	for rows.Next() {
+		var m Mismatch
+		if err := rows.Scan(&m.Kind, &m.OrganizationID, &m.UnitID, &m.UnitNumber,
+			&m.ResidentID, &m.ResidentName); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	return mismatches, rows.Err()
+}
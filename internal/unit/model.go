@@ -5,21 +5,205 @@ import "time"
 type Unit struct {
 	ID             string    `json:"id"`
 	OrganizationID string    `json:"organization_id"`
+	BlockID        *string   `json:"block_id,omitempty"`
+	BlockName      string    `json:"block_name,omitempty"`
 	UnitNumber     string    `json:"unit_number"`
 	Floor          int       `json:"floor"`
+	Area           float64   `json:"area"`       // net m², used to allocate assessments
+	LandShare      float64   `json:"land_share"` // arsa payı, used to allocate assessments
 	ResidentID     *string   `json:"resident_id,omitempty"`
 	ResidentName   string    `json:"resident_name,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	Occupants []Occupant `json:"occupants,omitempty"`
+	Payers    []Payer    `json:"payers,omitempty"`
+}
+
+// Occupant roles
+const (
+	RoleOwner   = "owner" // kat maliki
+	RoleCoOwner = "co_owner"
+	RoleTenant  = "tenant" // kiracı
+	RoleFamily  = "family"
+)
+
+// Occupant links a resident to a unit in a role for the period they live
+// in or own it.
+type Occupant struct {
+	ID           string     `json:"id"`
+	UnitID       string     `json:"unit_id"`
+	ResidentID   string     `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	Phone        string     `json:"phone,omitempty"`
+	Role         string     `json:"role"` // owner, co_owner, tenant, family
+	MoveInDate   time.Time  `json:"move_in_date"`
+	MoveOutDate  *time.Time `json:"move_out_date,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type AddOccupantRequest struct {
+	ResidentID string `json:"resident_id"`
+	Role       string `json:"role"`
+	MoveInDate string `json:"move_in_date,omitempty"` // YYYY-MM-DD, defaults to today
+}
+
+type UpdateOccupantRequest struct {
+	Role        *string `json:"role,omitempty"`
+	MoveOutDate *string `json:"move_out_date,omitempty"` // YYYY-MM-DD
+}
+
+// Payer is the designated payer of a unit for one dues type. Without one,
+// aidat, heating and penalties go to the tenant if there is one, and
+// demirbaş and special assessments always go to the owner.
+type Payer struct {
+	DuesType     string `json:"dues_type"`
+	ResidentID   string `json:"resident_id"`
+	ResidentName string `json:"resident_name"`
+}
+
+type SetPayerRequest struct {
+	DuesType   string `json:"dues_type"`
+	ResidentID string `json:"resident_id"` // empty clears the designation
 }
 
 type CreateRequest struct {
+	BlockID    string  `json:"block_id,omitempty"`
+	UnitNumber string  `json:"unit_number"`
+	Floor      int     `json:"floor"`
+	Area       float64 `json:"area,omitempty"`
+	LandShare  float64 `json:"land_share,omitempty"`
+}
+
+type UpdateRequest struct {
+	BlockID    *string  `json:"block_id,omitempty"` // empty moves the unit out of its block
+	UnitNumber *string  `json:"unit_number,omitempty"`
+	Floor      *int     `json:"floor,omitempty"`
+	Area       *float64 `json:"area,omitempty"`
+	LandShare  *float64 `json:"land_share,omitempty"`
+	ResidentID *string  `json:"resident_id,omitempty"`
+	// ResidentRole is the occupancy role recorded when ResidentID changes;
+	// defaults to owner.
+	ResidentRole string `json:"resident_role,omitempty"`
+}
+
+// Settlement is what a departing occupant still owes: unpaid dues billed to
+// them for the period they lived in or owned the unit.
+type Settlement struct {
+	UnitID       string          `json:"unit_id"`
+	UnitNumber   string          `json:"unit_number"`
+	OccupantID   string          `json:"occupant_id"`
+	ResidentID   string          `json:"resident_id"`
+	ResidentName string          `json:"resident_name"`
+	Role         string          `json:"role"`
+	MoveInDate   time.Time       `json:"move_in_date"`
+	MoveOutDate  time.Time       `json:"move_out_date"`
+	Outstanding  float64         `json:"outstanding"`
+	Dues         []SettlementDue `json:"dues"`
+	Cleared      bool            `json:"cleared"` // nothing left to pay; a clearance letter can be issued
+}
+
+type SettlementDue struct {
+	DueID       string    `json:"due_id"`
+	Type        string    `json:"type"`
+	Description string    `json:"description,omitempty"`
+	DueDate     time.Time `json:"due_date"`
+	Amount      float64   `json:"amount"`
+	Status      string    `json:"status"`
+}
+
+type MoveOutRequest struct {
+	MoveOutDate string `json:"move_out_date,omitempty"` // YYYY-MM-DD, defaults to today
+}
+
+// ClearanceLetter (ilişiksizlik belgesi) certifies that a departing
+// occupant has no debt to the site management.
+type ClearanceLetter struct {
+	OrganizationName string    `json:"organization_name"`
+	Address          string    `json:"address"`
+	UnitNumber       string    `json:"unit_number"`
+	ResidentName     string    `json:"resident_name"`
+	Role             string    `json:"role"`
+	MoveInDate       time.Time `json:"move_in_date"`
+	MoveOutDate      time.Time `json:"move_out_date"`
+	IssuedAt         time.Time `json:"issued_at"`
+	Text             string    `json:"text"`
+}
+
+// Statement line kinds
+const (
+	LineCharge     = "charge"     // a due billed to the unit
+	LineAdjustment = "adjustment" // a void, discount or credit on a due
+	LinePayment    = "payment"
+)
+
+// Statement (hesap ekstresi) lists what was billed to a unit, what was
+// taken off and what was paid in a period, with a running balance.
+// Restructured dues are left out; their payment plan installments take
+// their place.
+type Statement struct {
+	OrganizationID string          `json:"organization_id"`
+	UnitID         string          `json:"unit_id"`
+	UnitNumber     string          `json:"unit_number"`
+	BlockName      string          `json:"block_name,omitempty"`
+	ResidentName   string          `json:"resident_name,omitempty"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance float64         `json:"opening_balance"` // owed before From; negative when paid ahead
+	Lines          []StatementLine `json:"lines"`
+	TotalDebit     float64         `json:"total_debit"`
+	TotalCredit    float64         `json:"total_credit"`
+	ClosingBalance float64         `json:"closing_balance"`
+}
+
+type StatementLine struct {
+	Date        time.Time `json:"date"`
+	Kind        string    `json:"kind"` // charge, adjustment, payment
+	DueID       string    `json:"due_id"`
+	Type        string    `json:"type"` // dues type of the line's due
+	Description string    `json:"description,omitempty"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Balance     float64   `json:"balance"`
+}
+
+// GenerateRequest lays out a whole site at once. Each block gets Floors
+// floors with UnitsPerFloor units on each; a block may override both.
+// Without blocks the units are created for a single building.
+//
+// Pattern builds the unit number from {block}, {floor}, {index} (position
+// on the floor, from 1) and {seq} (running number within the block). A
+// width pads with zeros: "{block}-{floor}{index:02}" gives "A-102".
+type GenerateRequest struct {
+	Blocks        []GenerateBlock `json:"blocks,omitempty"`
+	Floors        int             `json:"floors"`
+	UnitsPerFloor int             `json:"units_per_floor"`
+	FirstFloor    *int            `json:"first_floor,omitempty"` // defaults to 1; 0 for a ground floor (zemin kat)
+	Pattern       string          `json:"pattern,omitempty"`
+	Area          float64         `json:"area,omitempty"`
+	LandShare     float64         `json:"land_share,omitempty"`
+	DryRun        bool            `json:"dry_run,omitempty"`
+}
+
+type GenerateBlock struct {
+	Name          string `json:"name"`
+	Floors        int    `json:"floors,omitempty"`
+	UnitsPerFloor int    `json:"units_per_floor,omitempty"`
+}
+
+type GeneratedUnit struct {
+	BlockName  string `json:"block_name,omitempty"`
 	UnitNumber string `json:"unit_number"`
 	Floor      int    `json:"floor"`
 }
 
-type UpdateRequest struct {
-	UnitNumber *string `json:"unit_number,omitempty"`
-	Floor      *int    `json:"floor,omitempty"`
-	ResidentID *string `json:"resident_id,omitempty"`
+// GenerateResult lists the units a layout produces. On a dry run nothing is
+// written and Conflicts shows numbers that already exist.
+type GenerateResult struct {
+	DryRun        bool            `json:"dry_run"`
+	Units         []GeneratedUnit `json:"units"`
+	Conflicts     []GeneratedUnit `json:"conflicts,omitempty"`
+	BlocksCreated []string        `json:"blocks_created,omitempty"`
+	Created       int             `json:"created"`
 }
@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
+)
+
+// statementPDF prints the statement with the same opening and totals rows
+// as its spreadsheet export.
+func statementPDF(siteName, address string, st *Statement) *pdf.Document {
+	doc := pdf.New("Hesap Ekstresi " + st.UnitNumber)
+	doc.Header(siteName, address, "HESAP EKSTRESİ")
+
+	unit := st.UnitNumber
+	if st.BlockName != "" {
+		unit = st.BlockName + " / " + unit
+	}
+	doc.Field("Daire", unit)
+	if st.ResidentName != "" {
+		doc.Field("Sakin", st.ResidentName)
+	}
+	doc.Field("Dönem", st.From.Format("02.01.2006")+" - "+st.To.Format("02.01.2006"))
+	doc.Space(4)
+
+	rows := [][]string{{st.From.Format("02.01.2006"), "Devir", "Önceki dönemden devreden bakiye", "", "", export.Money(st.OpeningBalance)}}
+	for _, l := range st.Lines {
+		description := export.Label(dues.TypeLabels, l.Type)
+		if l.Description != "" {
+			description += " - " + l.Description
+		}
+		rows = append(rows, []string{
+			l.Date.Format("02.01.2006"),
+			export.Label(lineLabels, l.Kind),
+			description,
+			amount(l.Debit),
+			amount(l.Credit),
+			export.Money(l.Balance),
+		})
+	}
+	rows = append(rows, []string{st.To.Format("02.01.2006"), "Toplam", "",
+		export.Money(st.TotalDebit), export.Money(st.TotalCredit), export.Money(st.ClosingBalance)})
+
+	doc.Table([]pdf.Column{
+		{Title: "Tarih", Width: 22},
+		{Title: "İşlem", Width: 22},
+		{Title: "Açıklama"},
+		{Title: "Borç", Width: 24, Align: pdf.Right},
+		{Title: "Alacak", Width: 24, Align: pdf.Right},
+		{Title: "Bakiye", Width: 26, Align: pdf.Right},
+	}, rows, map[int]bool{0: true, len(rows) - 1: true})
+
+	doc.Space(3)
+	switch {
+	case st.ClosingBalance > 0:
+		doc.Text("Dönem sonu itibarıyla " + export.Money(st.ClosingBalance) + " TL borcunuz bulunmaktadır.")
+	case st.ClosingBalance < 0:
+		doc.Text("Dönem sonu itibarıyla " + export.Money(-st.ClosingBalance) + " TL alacağınız bulunmaktadır.")
+	default:
+		doc.Text("Dönem sonu itibarıyla borcunuz bulunmamaktadır.")
+	}
+	return doc
+}
+
+// amount leaves empty debit and credit cells blank instead of 0,00.
+func amount(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return export.Money(v)
+}
@@ -3,6 +3,14 @@ package unit
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustafakemalcelik/sitetakip/internal/block"
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/internal/organization"
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
 )
 
 type Repository struct {
@@ -14,28 +22,49 @@ func NewRepository(db *sql.DB) *Repository {
 }
 
 func (r *Repository) Create(u *Unit) error {
+	return createUnit(r.db, u)
+}
+
+// createUnit inserts a unit on its own or inside a caller's transaction.
+func createUnit(q database.Querier, u *Unit) error {
+	if u.BlockID != nil {
+		if err := block.Belongs(q, *u.BlockID, u.OrganizationID); err != nil {
+			return err
+		}
+	}
+
 	query := `
-		INSERT INTO units (organization_id, unit_number, floor, resident_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO units (organization_id, block_id, unit_number, floor, area, land_share, resident_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
 
-	return r.db.QueryRow(query,
-		u.OrganizationID, u.UnitNumber, u.Floor, u.ResidentID,
+	return q.QueryRow(query,
+		u.OrganizationID, u.BlockID, u.UnitNumber, u.Floor, u.Area, u.LandShare, u.ResidentID,
 	).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
 }
 
-func (r *Repository) GetByID(id string) (*Unit, error) {
-	u := &Unit{}
-	query := `SELECT u.id, u.organization_id, u.unit_number, u.floor, u.resident_id,
+const selectUnit = `SELECT u.id, u.organization_id, u.block_id, COALESCE(b.name, '') as block_name,
+		u.unit_number, u.floor, u.area, u.land_share, u.resident_id,
 		COALESCE(us.full_name, '') as resident_name, u.created_at, u.updated_at
-		FROM units u LEFT JOIN users us ON u.resident_id = us.id
-		WHERE u.id = $1`
+		FROM units u
+		LEFT JOIN blocks b ON u.block_id = b.id
+		LEFT JOIN residents us ON u.resident_id = us.id`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err := r.db.QueryRow(query, id).Scan(
-		&u.ID, &u.OrganizationID, &u.UnitNumber, &u.Floor,
+func scanUnit(row scanner, u *Unit) error {
+	return row.Scan(
+		&u.ID, &u.OrganizationID, &u.BlockID, &u.BlockName,
+		&u.UnitNumber, &u.Floor, &u.Area, &u.LandShare,
 		&u.ResidentID, &u.ResidentName, &u.CreatedAt, &u.UpdatedAt,
 	)
-	if err != nil {
+}
+
+func (r *Repository) GetByID(id string) (*Unit, error) {
+	u := &Unit{}
+	if err := scanUnit(r.db.QueryRow(selectUnit+" WHERE u.id = $1", id), u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("unit not found")
 		}
@@ -44,13 +73,51 @@ func (r *Repository) GetByID(id string) (*Unit, error) {
 	return u, nil
 }
 
-func (r *Repository) ListByOrganization(orgID string) ([]Unit, error) {
-	query := `SELECT u.id, u.organization_id, u.unit_number, u.floor, u.resident_id,
-		COALESCE(us.full_name, '') as resident_name, u.created_at, u.updated_at
-		FROM units u LEFT JOIN users us ON u.resident_id = us.id
-		WHERE u.organization_id = $1 ORDER BY u.floor, u.unit_number`
+// listConfig is what the unit list can be sorted and searched by; ?q=
+// matches the unit number, block and resident name.
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"unit_number": "u.unit_number",
+		"block_name":  "COALESCE(b.name, '')",
+		"floor":       "u.floor",
+		"area":        "u.area",
+		"land_share":  "u.land_share",
+		"created_at":  "u.created_at",
+	},
+	Default: "unit_number",
+	ID:      "u.id",
+	Search:  []string{"u.unit_number", "b.name", "us.full_name"},
+}
+
+// List returns a page of the organization's units, only those of a block
+// when blockID is set.
+func (r *Repository) List(orgID, blockID string, p *listing.Params) ([]Unit, listing.Result, error) {
+	query := selectUnit + " WHERE u.organization_id = $1 AND ($2 = '' OR u.block_id::text = $2)"
+	query, args := p.Filter(query, []interface{}{orgID, blockID})
+	units := []Unit{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var u Unit
+		if err := scanUnit(rows, &u); err != nil {
+			return err
+		}
+		units = append(units, u)
+		return nil
+	})
+	return units, res, err
+}
+
+// ListByOrganization returns every unit of the organization, or of one of
+// its blocks, grouped by block and floor.
+func (r *Repository) ListByOrganization(orgID, blockID string) ([]Unit, error) {
+	query := selectUnit + " WHERE u.organization_id = $1"
+	args := []interface{}{orgID}
+	if blockID != "" {
+		query += " AND u.block_id = $2"
+		args = append(args, blockID)
+	}
+	query += " ORDER BY b.name NULLS FIRST, u.floor, u.unit_number"
 
-	rows, err := r.db.Query(query, orgID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,10 +126,7 @@ func (r *Repository) ListByOrganization(orgID string) ([]Unit, error) {
 	var units []Unit
 	for rows.Next() {
 		var u Unit
-		if err := rows.Scan(
-			&u.ID, &u.OrganizationID, &u.UnitNumber, &u.Floor,
-			&u.ResidentID, &u.ResidentName, &u.CreatedAt, &u.UpdatedAt,
-		); err != nil {
+		if err := scanUnit(rows, &u); err != nil {
 			return nil, err
 		}
 		units = append(units, u)
@@ -70,33 +134,599 @@ func (r *Repository) ListByOrganization(orgID string) ([]Unit, error) {
 	return units, nil
 }
 
+// Update changes a unit. A resident_id change is an assignment (or a
+// release when empty), so history and both sides of the link stay intact.
 func (r *Repository) Update(id string, req UpdateRequest) (*Unit, error) {
 	u, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 
+	if req.BlockID != nil {
+		u.BlockID = nil
+		if *req.BlockID != "" {
+			if err := block.Belongs(r.db, *req.BlockID, u.OrganizationID); err != nil {
+				return nil, err
+			}
+			u.BlockID = req.BlockID
+		}
+	}
 	if req.UnitNumber != nil {
 		u.UnitNumber = *req.UnitNumber
 	}
 	if req.Floor != nil {
 		u.Floor = *req.Floor
 	}
-	if req.ResidentID != nil {
-		u.ResidentID = req.ResidentID
+	if req.Area != nil {
+		u.Area = *req.Area
+	}
+	if req.LandShare != nil {
+		u.LandShare = *req.LandShare
 	}
 
-	query := `UPDATE units SET unit_number=$1, floor=$2, resident_id=$3, updated_at=NOW()
-		WHERE id=$4 RETURNING updated_at`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE units SET block_id=$1, unit_number=$2, floor=$3, area=$4, land_share=$5, updated_at=NOW()
+		WHERE id=$6 RETURNING updated_at`
 
-	err = r.db.QueryRow(query, u.UnitNumber, u.Floor, u.ResidentID, id).Scan(&u.UpdatedAt)
+	err = tx.QueryRow(query, u.BlockID, u.UnitNumber, u.Floor, u.Area, u.LandShare, id).Scan(&u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
-	return u, nil
+
+	if req.ResidentID != nil {
+		if *req.ResidentID == "" {
+			err = ReleaseTx(tx, id)
+		} else {
+			err = AssignTx(tx, id, *req.ResidentID, req.ResidentRole)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// ResidentUnit is the unit of a resident aliased r in a query: the unit
+// they are linked to, or else the unit of their latest open occupancy, so
+// co-owners, tenants and family members are found as well.
+const ResidentUnit = `COALESCE(r.unit_id, (SELECT o.unit_id FROM unit_occupants o
+		WHERE o.resident_id = r.id AND (o.move_out_date IS NULL OR o.move_out_date > CURRENT_DATE)
+		ORDER BY o.move_in_date DESC LIMIT 1))`
+
+// AssignTx makes a resident the resident of a unit, writing both
+// units.resident_id and residents.unit_id and opening an occupancy. It
+// refuses to overwrite: the unit must be free and the resident must not
+// live in another unit. Every assignment goes through here.
+func AssignTx(tx *sql.Tx, unitID, residentID, role string) error {
+	var current *string
+	err := tx.QueryRow("SELECT resident_id FROM units WHERE id = $1 FOR UPDATE", unitID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unit not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	var residentUnit *string
+	err = tx.QueryRow("SELECT unit_id FROM residents WHERE id = $1 FOR UPDATE", residentID).Scan(&residentUnit)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("resident not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if current != nil && *current == residentID && residentUnit != nil && *residentUnit == unitID {
+		return nil
+	}
+	if current != nil && *current != residentID {
+		return fmt.Errorf("unit already has a resident, release it first")
+	}
+	if residentUnit != nil && *residentUnit != unitID {
+		return fmt.Errorf("resident is already assigned to another unit, release it first")
+	}
+
+	if _, err := tx.Exec("UPDATE units SET resident_id = $1, updated_at = NOW() WHERE id = $2", residentID, unitID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE residents SET unit_id = $1, updated_at = NOW() WHERE id = $2", unitID, residentID); err != nil {
+		return err
+	}
+
+	if role == "" {
+		role = RoleOwner
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if _, err := tx.Exec(`INSERT INTO unit_occupants (unit_id, resident_id, role, move_in_date)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM unit_occupants WHERE unit_id = $1 AND resident_id = $2
+			AND (move_out_date IS NULL OR move_out_date > $4))`,
+		unitID, residentID, role, today); err != nil {
+		return err
+	}
+	return dues.ReassignPayers(tx, unitID, today)
+}
+
+// ReleaseTx clears a unit's resident on both sides and closes their
+// occupancy today.
+func ReleaseTx(tx *sql.Tx, unitID string) error {
+	var current *string
+	err := tx.QueryRow("SELECT resident_id FROM units WHERE id = $1 FOR UPDATE", unitID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unit not found")
+	}
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE units SET resident_id = NULL, updated_at = NOW() WHERE id = $1", unitID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE residents SET unit_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND unit_id = $2`, *current, unitID); err != nil {
+		return err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if err := closeOccupancy(tx, unitID, *current, today); err != nil {
+		return err
+	}
+	return dues.ReassignPayers(tx, unitID, today)
+}
+
+// ReleaseResidentTx moves a resident out of whatever unit they are
+// assigned to.
+func ReleaseResidentTx(tx *sql.Tx, residentID string) error {
+	rows, err := tx.Query("SELECT id FROM units WHERE resident_id = $1", residentID)
+	if err != nil {
+		return err
+	}
+	var unitIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		unitIDs = append(unitIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range unitIDs {
+		if err := ReleaseTx(tx, id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("UPDATE residents SET unit_id = NULL, updated_at = NOW() WHERE id = $1", residentID)
+	return err
+}
+
+// closeOccupancy ends a resident's open occupancy of a unit on day and
+// drops them as designated payer.
+func closeOccupancy(tx *sql.Tx, unitID, residentID string, day time.Time) error {
+	if _, err := tx.Exec(`UPDATE unit_occupants SET move_out_date = $3, updated_at = NOW()
+		WHERE unit_id = $1 AND resident_id = $2 AND (move_out_date IS NULL OR move_out_date > $3)`,
+		unitID, residentID, day); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM unit_payers WHERE unit_id = $1 AND resident_id = $2", unitID, residentID)
+	return err
+}
+
+// existingLayout returns the organization's unit numbers keyed by block
+// name and number, and the names of its blocks.
+func (r *Repository) existingLayout(orgID string) (map[string]bool, map[string]bool, error) {
+	numbers := make(map[string]bool)
+	rows, err := r.db.Query(`SELECT COALESCE(b.name, ''), u.unit_number FROM units u
+		LEFT JOIN blocks b ON u.block_id = b.id
+		WHERE u.organization_id = $1`, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var block, number string
+		if err := rows.Scan(&block, &number); err != nil {
+			return nil, nil, err
+		}
+		numbers[block+"\x00"+number] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	blocks := make(map[string]bool)
+	blockRows, err := r.db.Query("SELECT name FROM blocks WHERE organization_id = $1", orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer blockRows.Close()
+	for blockRows.Next() {
+		var name string
+		if err := blockRows.Scan(&name); err != nil {
+			return nil, nil, err
+		}
+		blocks[name] = true
+	}
+	return numbers, blocks, blockRows.Err()
+}
+
+// Generate creates a whole layout of units in one transaction, adding the
+// blocks it names that do not exist yet. Any existing unit number makes
+// the whole layout fail.
+func (r *Repository) Generate(orgID string, units []GeneratedUnit, area, landShare float64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type batch struct {
+		numbers []string
+		floors  []int64
+		levels  map[int]bool
+	}
+	var order []string
+	batches := make(map[string]*batch)
+	for _, u := range units {
+		b := batches[u.BlockName]
+		if b == nil {
+			b = &batch{levels: make(map[int]bool)}
+			batches[u.BlockName] = b
+			order = append(order, u.BlockName)
+		}
+		b.numbers = append(b.numbers, u.UnitNumber)
+		b.floors = append(b.floors, int64(u.Floor))
+		b.levels[u.Floor] = true
+	}
+
+	for _, name := range order {
+		b := batches[name]
+		var blockID *string
+		if name != "" {
+			id, _, err := block.EnsureTx(tx, orgID, name, len(b.levels))
+			if err != nil {
+				return err
+			}
+			blockID = &id
+		}
+		if _, err := tx.Exec(`INSERT INTO units (organization_id, block_id, unit_number, floor, area, land_share)
+			SELECT $1, $2, n.number, n.floor, $5, $6
+			FROM unnest($3::text[], $4::int[]) AS n(number, floor)`,
+			orgID, blockID, pq.Array(b.numbers), pq.Array(b.floors), area, landShare); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// HasReceipts reports whether receipts were issued for dues of the unit.
+// Receipts are kept for the books, so such a unit is not deleted.
+func (r *Repository) HasReceipts(id string) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM receipts rc JOIN dues d ON rc.due_id = d.id
+		WHERE d.unit_id = $1)`, id).Scan(&ok)
+	return ok, err
 }
 
 func (r *Repository) Delete(id string) error {
 	_, err := r.db.Exec("DELETE FROM units WHERE id = $1", id)
+	// A receipt issued after HasReceipts was checked still blocks the
+	// delete through its foreign key.
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" && pqErr.Table == "receipts" {
+		return ErrHasReceipts
+	}
+	return err
+}
+
+const selectOccupant = `SELECT o.id, o.unit_id, o.resident_id, r.full_name, r.phone, o.role,
+		o.move_in_date, o.move_out_date, o.created_at, o.updated_at
+		FROM unit_occupants o
+		JOIN residents r ON o.resident_id = r.id`
+
+func scanOccupant(row scanner, o *Occupant) error {
+	return row.Scan(&o.ID, &o.UnitID, &o.ResidentID, &o.ResidentName, &o.Phone, &o.Role,
+		&o.MoveInDate, &o.MoveOutDate, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// AddOccupant records an occupant and re-bills the unit's unpaid dues
+// from their move-in date, so a new tenant pays the dues already issued
+// for their stay.
+func (r *Repository) AddOccupant(o *Occupant) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO unit_occupants (unit_id, resident_id, role, move_in_date)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+	if err := tx.QueryRow(query, o.UnitID, o.ResidentID, o.Role, o.MoveInDate).
+		Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return err
+	}
+	if err := dues.ReassignPayers(tx, o.UnitID, o.MoveInDate); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) GetOccupant(id string) (*Occupant, error) {
+	o := &Occupant{}
+	if err := scanOccupant(r.db.QueryRow(selectOccupant+" WHERE o.id = $1", id), o); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("occupant not found")
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListOccupants returns the occupants of a unit, current ones only unless
+// all is set.
+func (r *Repository) ListOccupants(unitID string, all bool) ([]Occupant, error) {
+	query := selectOccupant + " WHERE o.unit_id = $1"
+	if !all {
+		query += " AND (o.move_out_date IS NULL OR o.move_out_date > CURRENT_DATE)"
+	}
+	query += " ORDER BY o.move_in_date, r.full_name"
+
+	rows, err := r.db.Query(query, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var occupants []Occupant
+	for rows.Next() {
+		var o Occupant
+		if err := scanOccupant(rows, &o); err != nil {
+			return nil, err
+		}
+		occupants = append(occupants, o)
+	}
+	return occupants, nil
+}
+
+// UpdateOccupant saves an occupant. Setting a move-out date closes the
+// occupancy: unpaid dues from that date on are re-billed and, once the day
+// has come, the resident stops being a designated payer and is cleared as
+// the unit's resident. A move-out in the future is left to EndOccupancies.
+func (r *Repository) UpdateOccupant(o *Occupant) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE unit_occupants SET role=$1, move_out_date=$2, updated_at=NOW()
+		WHERE id=$3 RETURNING updated_at`
+	if err := tx.QueryRow(query, o.Role, o.MoveOutDate, o.ID).Scan(&o.UpdatedAt); err != nil {
+		return err
+	}
+
+	from := o.MoveInDate
+	if o.MoveOutDate != nil {
+		from = *o.MoveOutDate
+		if !from.After(time.Now().UTC()) {
+			if err := endOccupancyTx(tx, o.UnitID, o.ResidentID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := dues.ReassignPayers(tx, o.UnitID, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// endOccupancyTx drops a resident who has moved out as the unit's
+// designated payer and clears the links between them and the unit.
+func endOccupancyTx(tx *sql.Tx, unitID, residentID string) error {
+	if _, err := tx.Exec("DELETE FROM unit_payers WHERE unit_id = $1 AND resident_id = $2",
+		unitID, residentID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE units SET resident_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND resident_id = $2`, unitID, residentID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`UPDATE residents SET unit_id = NULL, updated_at = NOW()
+		WHERE id = $1 AND unit_id = $2`, residentID, unitID)
 	return err
 }
+
+// EndOccupancies finishes the move-outs whose day has come: residents
+// still linked to or paying for a unit they no longer live in are
+// released and the unit's dues from the move-out on are re-billed. It
+// returns how many occupancies were ended.
+func (r *Repository) EndOccupancies() (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT o.unit_id, o.resident_id, MAX(o.move_out_date)
+		FROM unit_occupants o
+		WHERE o.move_out_date <= CURRENT_DATE
+			AND (EXISTS (SELECT 1 FROM units u WHERE u.id = o.unit_id AND u.resident_id = o.resident_id)
+				OR EXISTS (SELECT 1 FROM residents r WHERE r.id = o.resident_id AND r.unit_id = o.unit_id)
+				OR EXISTS (SELECT 1 FROM unit_payers p WHERE p.unit_id = o.unit_id AND p.resident_id = o.resident_id))
+			AND NOT EXISTS (SELECT 1 FROM unit_occupants cur
+				WHERE cur.unit_id = o.unit_id AND cur.resident_id = o.resident_id
+					AND cur.move_in_date <= CURRENT_DATE
+					AND (cur.move_out_date IS NULL OR cur.move_out_date > CURRENT_DATE))
+		GROUP BY o.unit_id, o.resident_id`)
+	if err != nil {
+		return 0, err
+	}
+	type ended struct {
+		unitID, residentID string
+		day                time.Time
+	}
+	var all []ended
+	for rows.Next() {
+		var e ended
+		if err := rows.Scan(&e.unitID, &e.residentID, &e.day); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		all = append(all, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, e := range all {
+		if err := endOccupancyTx(tx, e.unitID, e.residentID); err != nil {
+			return 0, err
+		}
+		if err := dues.ReassignPayers(tx, e.unitID, e.day); err != nil {
+			return 0, err
+		}
+	}
+	return len(all), tx.Commit()
+}
+
+// DeleteOccupant removes an occupant recorded by mistake; the dues of their
+// stay go back to whoever else lived in the unit.
+func (r *Repository) DeleteOccupant(o *Occupant) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM unit_occupants WHERE id = $1", o.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM unit_payers WHERE unit_id = $1 AND resident_id = $2",
+		o.UnitID, o.ResidentID); err != nil {
+		return err
+	}
+	if err := dues.ReassignPayers(tx, o.UnitID, o.MoveInDate); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) ListPayers(unitID string) ([]Payer, error) {
+	rows, err := r.db.Query(`SELECT p.dues_type, p.resident_id, r.full_name
+		FROM unit_payers p
+		JOIN residents r ON p.resident_id = r.id
+		WHERE p.unit_id = $1 ORDER BY p.dues_type`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payers []Payer
+	for rows.Next() {
+		var p Payer
+		if err := rows.Scan(&p.DuesType, &p.ResidentID, &p.ResidentName); err != nil {
+			return nil, err
+		}
+		payers = append(payers, p)
+	}
+	return payers, nil
+}
+
+func (r *Repository) SetPayer(unitID, duesType, residentID string) error {
+	_, err := r.db.Exec(`INSERT INTO unit_payers (unit_id, dues_type, resident_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (unit_id, dues_type) DO UPDATE SET resident_id = EXCLUDED.resident_id`,
+		unitID, duesType, residentID)
+	return err
+}
+
+func (r *Repository) ClearPayer(unitID, duesType string) error {
+	_, err := r.db.Exec("DELETE FROM unit_payers WHERE unit_id = $1 AND dues_type = $2", unitID, duesType)
+	return err
+}
+
+// ListUnpaidFor returns the unpaid dues of a unit billed to a resident and
+// due within [from, to].
+func (r *Repository) ListUnpaidFor(unitID, residentID string, from, to time.Time) ([]SettlementDue, error) {
+	rows, err := r.db.Query(`SELECT id, type, COALESCE(description, ''), due_date, amount - adjustment_total, status
+		FROM dues
+		WHERE unit_id = $1 AND payer_resident_id = $2 AND due_date BETWEEN $3 AND $4
+			AND status IN ('pending', 'overdue')
+		ORDER BY due_date`, unitID, residentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SettlementDue
+	for rows.Next() {
+		var d SettlementDue
+		if err := rows.Scan(&d.DueID, &d.Type, &d.Description, &d.DueDate, &d.Amount, &d.Status); err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+// StatementLines returns every charge, adjustment and payment of a unit up
+// to and including to, oldest first. On the same day charges come before
+// the adjustments and payments that settle them.
+func (r *Repository) StatementLines(unitID string, to time.Time) ([]StatementLine, error) {
+	rows, err := r.db.Query(`
+		SELECT day, kind, due_id, type, description, debit, credit FROM (
+			SELECT d.due_date AS day, 'charge' AS kind, 1 AS step, d.id AS due_id, d.type,
+				COALESCE(d.description, '') AS description, d.amount AS debit, 0 AS credit
+			FROM dues d
+			WHERE d.unit_id = $1 AND d.status <> 'restructured' AND d.due_date <= $2
+			UNION ALL
+			SELECT a.created_at::date, a.kind, 2, d.id, d.type, a.reason, 0, a.amount
+			FROM due_adjustments a
+			JOIN dues d ON a.due_id = d.id
+			WHERE d.unit_id = $1 AND d.status <> 'restructured' AND a.created_at::date <= $2
+			UNION ALL
+			SELECT d.paid_at::date, 'payment', 3, d.id, d.type, COALESCE(d.payment_method, ''),
+				0, d.amount - d.adjustment_total
+			FROM dues d
+			WHERE d.unit_id = $1 AND d.status = 'paid' AND d.payment_method <> 'adjustment'
+				AND d.paid_at::date <= $2
+		) lines
+		ORDER BY day, step, due_id`, unitID, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []StatementLine
+	for rows.Next() {
+		var l StatementLine
+		if err := rows.Scan(&l.Date, &l.Kind, &l.DueID, &l.Type, &l.Description, &l.Debit, &l.Credit); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// OrganizationHeader returns the site name and address for documents.
+func (r *Repository) OrganizationHeader(orgID string) (string, string, error) {
+	return organization.Header(r.db, orgID)
+}
@@ -6,8 +6,18 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Route("/organizations/{orgId}/units", func(r chi.Router) {
 		r.Post("/", h.Create)
 		r.Get("/", h.List)
+		r.Post("/generate", h.Generate)
 		r.Get("/{id}", h.Get)
 		r.Put("/{id}", h.Update)
 		r.Delete("/{id}", h.Delete)
+		r.Post("/{id}/occupants", h.AddOccupant)
+		r.Get("/{id}/occupants", h.ListOccupants)
+		r.Put("/{id}/occupants/{occupantId}", h.UpdateOccupant)
+		r.Delete("/{id}/occupants/{occupantId}", h.DeleteOccupant)
+		r.Post("/{id}/occupants/{occupantId}/move-out", h.MoveOut)
+		r.Get("/{id}/occupants/{occupantId}/settlement", h.Settlement)
+		r.Get("/{id}/occupants/{occupantId}/clearance", h.Clearance)
+		r.Put("/{id}/payers", h.SetPayer)
+		r.Get("/{id}/statement", h.Statement)
 	})
 }
@@ -1,6 +1,20 @@
 package unit
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mustafakemalcelik/sitetakip/internal/dues"
+	"github.com/mustafakemalcelik/sitetakip/pkg/export"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+	"github.com/mustafakemalcelik/sitetakip/pkg/pdf"
+)
 
 type Service struct {
 	repo *Repository
@@ -11,34 +25,482 @@ func NewService(repo *Repository) *Service {
 }
 
 func (s *Service) Create(orgID string, req CreateRequest) (*Unit, error) {
-	if req.UnitNumber == "" {
-		return nil, fmt.Errorf("unit number is required")
+	u, err := newUnit(orgID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// CreateTx creates a unit as part of the caller's transaction, e.g. a bulk
+// import that commits all rows together.
+func (s *Service) CreateTx(tx *sql.Tx, orgID string, req CreateRequest) (*Unit, error) {
+	u, err := newUnit(orgID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := createUnit(tx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func newUnit(orgID string, req CreateRequest) (*Unit, error) {
+	req.UnitNumber = strings.TrimSpace(req.UnitNumber)
+	if err := CheckUnitNumber(req.UnitNumber); err != nil {
+		return nil, err
+	}
+	if req.Area < 0 || req.LandShare < 0 {
+		return nil, fmt.Errorf("area and land_share cannot be negative")
 	}
 
-	u := &Unit{
+	return &Unit{
 		OrganizationID: orgID,
+		BlockID:        dues.Optional(req.BlockID),
 		UnitNumber:     req.UnitNumber,
 		Floor:          req.Floor,
+		Area:           req.Area,
+		LandShare:      req.LandShare,
+	}, nil
+}
+
+// CheckUnitNumber accepts door numbers such as "5", "A-12", "3/B" or
+// "Dükkan 2" of at most 20 characters.
+func CheckUnitNumber(number string) error {
+	if number == "" {
+		return fmt.Errorf("unit number is required")
 	}
+	if utf8.RuneCountInString(number) > maxUnitNumberLength {
+		return fmt.Errorf("unit number %q is longer than %d characters", number, maxUnitNumberLength)
+	}
+	for _, r := range number {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -/.", r) {
+			return fmt.Errorf("unit number %q may only contain letters, digits, spaces and - / .", number)
+		}
+	}
+	return nil
+}
 
-	if err := s.repo.Create(u); err != nil {
+// Generate lays out many units at once. A dry run returns the units that
+// would be created, the blocks that would be added and any numbers that
+// already exist, without writing anything.
+func (s *Service) Generate(orgID string, req GenerateRequest) (*GenerateResult, error) {
+	units, err := layout(req)
+	if err != nil {
 		return nil, err
 	}
-	return u, nil
+	if req.Area < 0 || req.LandShare < 0 {
+		return nil, fmt.Errorf("area and land_share cannot be negative")
+	}
+
+	numbers, blocks, err := s.repo.existingLayout(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GenerateResult{DryRun: req.DryRun, Units: units}
+	for _, u := range units {
+		if numbers[u.BlockName+"\x00"+u.UnitNumber] {
+			result.Conflicts = append(result.Conflicts, u)
+		}
+	}
+	for _, b := range req.Blocks {
+		name := strings.TrimSpace(b.Name)
+		if !blocks[name] {
+			result.BlocksCreated = append(result.BlocksCreated, name)
+			blocks[name] = true
+		}
+	}
+	if req.DryRun {
+		return result, nil
+	}
+
+	if len(result.Conflicts) > 0 {
+		first := result.Conflicts[0]
+		return nil, fmt.Errorf("%d unit numbers already exist (first: %s)", len(result.Conflicts),
+			strings.TrimPrefix(first.BlockName+" "+first.UnitNumber, " "))
+	}
+	if err := s.repo.Generate(orgID, units, req.Area, req.LandShare); err != nil {
+		return nil, err
+	}
+	result.Created = len(units)
+	return result, nil
 }
 
+// GetByID returns the unit with its current occupants and designated payers.
 func (s *Service) GetByID(id string) (*Unit, error) {
-	return s.repo.GetByID(id)
+	u, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Occupants, err = s.repo.ListOccupants(id, false); err != nil {
+		return nil, err
+	}
+	if u.Payers, err = s.repo.ListPayers(id); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *Service) List(orgID, blockID string, p *listing.Params) ([]Unit, listing.Result, error) {
+	return s.repo.List(orgID, blockID, p)
 }
 
-func (s *Service) ListByOrganization(orgID string) ([]Unit, error) {
-	return s.repo.ListByOrganization(orgID)
+func (s *Service) ListByOrganization(orgID, blockID string) ([]Unit, error) {
+	return s.repo.ListByOrganization(orgID, blockID)
 }
 
 func (s *Service) Update(id string, req UpdateRequest) (*Unit, error) {
+	if req.UnitNumber != nil {
+		number := strings.TrimSpace(*req.UnitNumber)
+		if err := CheckUnitNumber(number); err != nil {
+			return nil, err
+		}
+		req.UnitNumber = &number
+	}
+	if req.ResidentRole != "" && !validRole(req.ResidentRole) {
+		return nil, fmt.Errorf("resident_role must be owner, co_owner, tenant or family")
+	}
 	return s.repo.Update(id, req)
 }
 
+// ErrHasReceipts is returned when deleting a unit whose dues have receipts.
+var ErrHasReceipts = errors.New("unit has issued receipts and cannot be deleted")
+
 func (s *Service) Delete(id string) error {
+	issued, err := s.repo.HasReceipts(id)
+	if err != nil {
+		return err
+	}
+	if issued {
+		return ErrHasReceipts
+	}
 	return s.repo.Delete(id)
 }
+
+func validRole(role string) bool {
+	return role == RoleOwner || role == RoleCoOwner || role == RoleTenant || role == RoleFamily
+}
+
+func (s *Service) AddOccupant(unitID string, req AddOccupantRequest) (*Occupant, error) {
+	if req.ResidentID == "" || req.Role == "" {
+		return nil, fmt.Errorf("resident_id and role are required")
+	}
+	if !validRole(req.Role) {
+		return nil, fmt.Errorf("role must be owner, co_owner, tenant or family")
+	}
+	if _, err := s.repo.GetByID(unitID); err != nil {
+		return nil, err
+	}
+
+	moveIn := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.MoveInDate != "" {
+		var err error
+		if moveIn, err = time.Parse("2006-01-02", req.MoveInDate); err != nil {
+			return nil, fmt.Errorf("invalid move_in_date format, use YYYY-MM-DD")
+		}
+	}
+
+	current, err := s.repo.ListOccupants(unitID, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range current {
+		if o.ResidentID == req.ResidentID {
+			return nil, fmt.Errorf("resident is already an occupant of this unit")
+		}
+	}
+
+	o := &Occupant{UnitID: unitID, ResidentID: req.ResidentID, Role: req.Role, MoveInDate: moveIn}
+	if err := s.repo.AddOccupant(o); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOccupant(o.ID)
+}
+
+func (s *Service) ListOccupants(unitID string, all bool) ([]Occupant, error) {
+	return s.repo.ListOccupants(unitID, all)
+}
+
+func (s *Service) UpdateOccupant(unitID, id string, req UpdateOccupantRequest) (*Occupant, error) {
+	o, err := s.repo.GetOccupant(id)
+	if err != nil {
+		return nil, err
+	}
+	if o.UnitID != unitID {
+		return nil, fmt.Errorf("occupant not found")
+	}
+
+	if req.Role != nil {
+		if !validRole(*req.Role) {
+			return nil, fmt.Errorf("role must be owner, co_owner, tenant or family")
+		}
+		o.Role = *req.Role
+	}
+	if req.MoveOutDate != nil {
+		if *req.MoveOutDate == "" {
+			o.MoveOutDate = nil
+		} else {
+			out, err := time.Parse("2006-01-02", *req.MoveOutDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid move_out_date format, use YYYY-MM-DD")
+			}
+			if out.Before(o.MoveInDate) {
+				return nil, fmt.Errorf("move_out_date cannot be before move_in_date")
+			}
+			o.MoveOutDate = &out
+		}
+	}
+
+	if err := s.repo.UpdateOccupant(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *Service) DeleteOccupant(unitID, id string) error {
+	o, err := s.repo.GetOccupant(id)
+	if err != nil {
+		return err
+	}
+	if o.UnitID != unitID {
+		return fmt.Errorf("occupant not found")
+	}
+	return s.repo.DeleteOccupant(o)
+}
+
+// SetPayer designates who pays a unit's dues of one type. The payer must be
+// a current occupant of the unit.
+func (s *Service) SetPayer(unitID string, req SetPayerRequest) ([]Payer, error) {
+	if !dues.ValidType(req.DuesType) {
+		return nil, fmt.Errorf("invalid dues_type %q", req.DuesType)
+	}
+
+	if req.ResidentID == "" {
+		if err := s.repo.ClearPayer(unitID, req.DuesType); err != nil {
+			return nil, err
+		}
+		return s.repo.ListPayers(unitID)
+	}
+
+	current, err := s.repo.ListOccupants(unitID, false)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, o := range current {
+		if o.ResidentID == req.ResidentID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("payer must be a current occupant of the unit")
+	}
+
+	if err := s.repo.SetPayer(unitID, req.DuesType, req.ResidentID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPayers(unitID)
+}
+
+func (s *Service) occupantOf(unitID, id string) (*Unit, *Occupant, error) {
+	u, err := s.repo.GetByID(unitID)
+	if err != nil {
+		return nil, nil, err
+	}
+	o, err := s.repo.GetOccupant(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.UnitID != unitID {
+		return nil, nil, fmt.Errorf("occupant not found")
+	}
+	return u, o, nil
+}
+
+// MoveOut closes an occupancy and returns the handover settlement.
+func (s *Service) MoveOut(unitID, id string, req MoveOutRequest) (*Settlement, error) {
+	out := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.MoveOutDate != "" {
+		var err error
+		if out, err = time.Parse("2006-01-02", req.MoveOutDate); err != nil {
+			return nil, fmt.Errorf("invalid move_out_date format, use YYYY-MM-DD")
+		}
+	}
+
+	_, o, err := s.occupantOf(unitID, id)
+	if err != nil {
+		return nil, err
+	}
+	if o.MoveOutDate != nil && !o.MoveOutDate.After(time.Now().UTC()) {
+		return nil, fmt.Errorf("occupant already moved out")
+	}
+	if out.Before(o.MoveInDate) {
+		return nil, fmt.Errorf("move_out_date cannot be before move_in_date")
+	}
+
+	o.MoveOutDate = &out
+	if err := s.repo.UpdateOccupant(o); err != nil {
+		return nil, err
+	}
+	return s.Settlement(unitID, id)
+}
+
+// EndOccupancies releases the residents whose move-out date, set ahead of
+// time, has come, and returns how many occupancies were ended. It is run
+// by the scheduler.
+func (s *Service) EndOccupancies() (int, error) {
+	return s.repo.EndOccupancies()
+}
+
+// Settlement computes what an occupant owes up to their move-out date (or
+// today if they still live there).
+func (s *Service) Settlement(unitID, id string) (*Settlement, error) {
+	u, o, err := s.occupantOf(unitID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now().UTC().Truncate(24 * time.Hour)
+	if o.MoveOutDate != nil {
+		until = *o.MoveOutDate
+	}
+
+	unpaid, err := s.repo.ListUnpaidFor(unitID, o.ResidentID, o.MoveInDate, until)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Settlement{
+		UnitID:       unitID,
+		UnitNumber:   u.UnitNumber,
+		OccupantID:   o.ID,
+		ResidentID:   o.ResidentID,
+		ResidentName: o.ResidentName,
+		Role:         o.Role,
+		MoveInDate:   o.MoveInDate,
+		MoveOutDate:  until,
+		Dues:         unpaid,
+	}
+	for _, d := range unpaid {
+		st.Outstanding += d.Amount
+	}
+	st.Cleared = len(unpaid) == 0
+	return st, nil
+}
+
+// Statement builds a unit's account statement for [from, to]. Everything
+// before from is carried in as the opening balance.
+func (s *Service) Statement(unitID string, from, to time.Time) (*Statement, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	u, err := s.repo.GetByID(unitID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.repo.StatementLines(unitID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{
+		OrganizationID: u.OrganizationID,
+		UnitID:         u.ID,
+		UnitNumber:     u.UnitNumber,
+		BlockName:      u.BlockName,
+		ResidentName:   u.ResidentName,
+		From:           from,
+		To:             to,
+		Lines:          []StatementLine{},
+	}
+	balance := 0.0
+	for _, l := range lines {
+		switch l.Kind {
+		case LineCharge:
+			// described by the due itself
+		case LinePayment:
+			l.Description = export.Label(dues.MethodLabels, l.Description)
+		default:
+			label := export.Label(dues.AdjustmentLabels, l.Kind)
+			if l.Description != "" {
+				label += ": " + l.Description
+			}
+			l.Kind, l.Description = LineAdjustment, label
+		}
+		balance = math.Round((balance+l.Debit-l.Credit)*100) / 100
+		l.Balance = balance
+
+		if l.Date.Before(from) {
+			st.OpeningBalance = balance
+			continue
+		}
+		st.TotalDebit += l.Debit
+		st.TotalCredit += l.Credit
+		st.Lines = append(st.Lines, l)
+	}
+	st.ClosingBalance = balance
+	return st, nil
+}
+
+// StatementPDF lays a statement out for print under the site's header.
+func (s *Service) StatementPDF(st *Statement) (*pdf.Document, error) {
+	name, address, err := s.repo.OrganizationHeader(st.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	return statementPDF(name, address, st), nil
+}
+
+var roleNames = map[string]string{
+	RoleOwner:   "kat maliki",
+	RoleCoOwner: "hissedar kat maliki",
+	RoleTenant:  "kiracı",
+	RoleFamily:  "aile bireyi",
+}
+
+// Clearance issues an ilişiksizlik belgesi once the occupant has no unpaid
+// dues left.
+func (s *Service) Clearance(unitID, id string) (*ClearanceLetter, error) {
+	st, err := s.Settlement(unitID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Cleared {
+		return nil, fmt.Errorf("resident still owes %.2f TL", st.Outstanding)
+	}
+
+	u, err := s.repo.GetByID(unitID)
+	if err != nil {
+		return nil, err
+	}
+	name, address, err := s.repo.OrganizationHeader(u.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	letter := &ClearanceLetter{
+		OrganizationName: name,
+		Address:          address,
+		UnitNumber:       st.UnitNumber,
+		ResidentName:     st.ResidentName,
+		Role:             st.Role,
+		MoveInDate:       st.MoveInDate,
+		MoveOutDate:      st.MoveOutDate,
+		IssuedAt:         now,
+	}
+	letter.Text = fmt.Sprintf(
+		"İLİŞİKSİZLİK BELGESİ\n\n%s\n%s\n\n%s numaralı bağımsız bölümde %s - %s tarihleri arasında %s olarak bulunan %s adlı kişinin "+
+			"site yönetimine aidat, demirbaş ve diğer giderlerden kaynaklanan herhangi bir borcu bulunmamaktadır.\n\n"+
+			"İşbu belge ilgilinin talebi üzerine %s tarihinde düzenlenmiştir.\n\nSite Yönetimi",
+		name, address, st.UnitNumber,
+		st.MoveInDate.Format("02.01.2006"), st.MoveOutDate.Format("02.01.2006"),
+		roleNames[st.Role], st.ResidentName, now.Format("02.01.2006"),
+	)
+	return letter, nil
+}
@@ -0,0 +1,37 @@
+package vendors
+
+import "time"
+
+type Vendor struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	TaxNumber      string    `json:"tax_number,omitempty"` // VKN or TCKN
+	IBAN           string    `json:"iban,omitempty"`
+	Category       string    `json:"category,omitempty"`
+	ContactName    string    `json:"contact_name,omitempty"`
+	Phone          string    `json:"phone,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name        string `json:"name"`
+	TaxNumber   string `json:"tax_number,omitempty"`
+	IBAN        string `json:"iban,omitempty"`
+	Category    string `json:"category,omitempty"`
+	ContactName string `json:"contact_name,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	Email       string `json:"email,omitempty"`
+}
+
+type UpdateRequest struct {
+	Name        *string `json:"name,omitempty"`
+	TaxNumber   *string `json:"tax_number,omitempty"`
+	IBAN        *string `json:"iban,omitempty"`
+	Category    *string `json:"category,omitempty"`
+	ContactName *string `json:"contact_name,omitempty"`
+	Phone       *string `json:"phone,omitempty"`
+	Email       *string `json:"email,omitempty"`
+}
@@ -0,0 +1,106 @@
+package vendors
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Belongs checks that a vendor exists in the organization. Packages that
+// link records to a vendor call it before writing vendor_id.
+func Belongs(db database.Querier, vendorID, orgID string) error {
+	var ok bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM vendors WHERE id = $1 AND organization_id = $2)",
+		vendorID, orgID).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("vendor not found")
+	}
+	return nil
+}
+
+func (r *Repository) Create(v *Vendor) error {
+	query := `
+		INSERT INTO vendors (organization_id, name, tax_number, iban, category, contact_name, phone, email)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query,
+		v.OrganizationID, v.Name, v.TaxNumber, v.IBAN, v.Category, v.ContactName, v.Phone, v.Email,
+	).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+}
+
+func (r *Repository) GetByID(id string) (*Vendor, error) {
+	v := &Vendor{}
+	query := `SELECT id, organization_id, name, tax_number, iban, category, contact_name, phone, email, created_at, updated_at
+		FROM vendors WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&v.ID, &v.OrganizationID, &v.Name, &v.TaxNumber, &v.IBAN, &v.Category,
+		&v.ContactName, &v.Phone, &v.Email, &v.CreatedAt, &v.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("vendor not found")
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+var listConfig = &listing.Config{
+	Sorts: map[string]string{
+		"name":       "name",
+		"category":   "category",
+		"created_at": "created_at",
+	},
+	Default: "name",
+	ID:      "id",
+	Search:  []string{"name", "tax_number", "contact_name", "phone"},
+}
+
+func (r *Repository) ListByOrganization(orgID string, p *listing.Params) ([]Vendor, listing.Result, error) {
+	query := `SELECT id, organization_id, name, tax_number, iban, category, contact_name, phone, email, created_at, updated_at
+		FROM vendors WHERE organization_id = $1`
+
+	query, args := p.Filter(query, []interface{}{orgID})
+	vendors := []Vendor{}
+	res, err := p.Page(r.db, query, args, func(rows *sql.Rows) error {
+		var v Vendor
+		if err := rows.Scan(
+			&v.ID, &v.OrganizationID, &v.Name, &v.TaxNumber, &v.IBAN, &v.Category,
+			&v.ContactName, &v.Phone, &v.Email, &v.CreatedAt, &v.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		vendors = append(vendors, v)
+		return nil
+	})
+	return vendors, res, err
+}
+
+func (r *Repository) Update(v *Vendor) error {
+	query := `UPDATE vendors SET name=$1, tax_number=$2, iban=$3, category=$4, contact_name=$5, phone=$6, email=$7, updated_at=NOW()
+		WHERE id=$8 RETURNING updated_at`
+
+	return r.db.QueryRow(query,
+		v.Name, v.TaxNumber, v.IBAN, v.Category, v.ContactName, v.Phone, v.Email, v.ID,
+	).Scan(&v.UpdatedAt)
+}
+
+func (r *Repository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM vendors WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,149 @@
+package vendors
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/listing"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(orgID string, req CreateRequest) (*Vendor, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	v := &Vendor{
+		OrganizationID: orgID,
+		Name:           req.Name,
+		TaxNumber:      strings.TrimSpace(req.TaxNumber),
+		IBAN:           normalizeIBAN(req.IBAN),
+		Category:       req.Category,
+		ContactName:    req.ContactName,
+		Phone:          req.Phone,
+		Email:          req.Email,
+	}
+	if err := validate(v); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Service) GetByID(id string) (*Vendor, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *Service) ListByOrganization(orgID string, p *listing.Params) ([]Vendor, listing.Result, error) {
+	return s.repo.ListByOrganization(orgID, p)
+}
+
+func (s *Service) Update(id string, req UpdateRequest) (*Vendor, error) {
+	v, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		v.Name = *req.Name
+	}
+	if req.TaxNumber != nil {
+		v.TaxNumber = strings.TrimSpace(*req.TaxNumber)
+	}
+	if req.IBAN != nil {
+		v.IBAN = normalizeIBAN(*req.IBAN)
+	}
+	if req.Category != nil {
+		v.Category = *req.Category
+	}
+	if req.ContactName != nil {
+		v.ContactName = *req.ContactName
+	}
+	if req.Phone != nil {
+		v.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		v.Email = *req.Email
+	}
+	if err := validate(v); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Service) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+func validate(v *Vendor) error {
+	if v.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if v.TaxNumber != "" && !isDigits(v.TaxNumber, 10) && !isDigits(v.TaxNumber, 11) {
+		return fmt.Errorf("tax_number must be a 10-digit VKN or 11-digit TCKN")
+	}
+	if v.IBAN != "" && !validIBAN(v.IBAN) {
+		return fmt.Errorf("invalid IBAN")
+	}
+	return nil
+}
+
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+}
+
+// validIBAN checks the ISO 13616 mod-97 checksum. Turkish IBANs are
+// additionally required to be 26 characters long.
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	if strings.HasPrefix(iban, "TR") && len(iban) != 26 {
+		return false
+	}
+
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
@@ -0,0 +1,25 @@
+-- Vendors / suppliers the building pays
+CREATE TABLE vendors (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    tax_number VARCHAR(11) NOT NULL DEFAULT '', -- VKN (10 digits) or TCKN (11 digits)
+    iban VARCHAR(34) NOT NULL DEFAULT '',
+    category VARCHAR(50) NOT NULL DEFAULT '', -- same values as expenses.category
+    contact_name VARCHAR(255) NOT NULL DEFAULT '',
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_vendors_organization ON vendors(organization_id);
+
+-- Accounts payable: who was paid, when the invoice is due, whether it is settled
+ALTER TABLE expenses ADD COLUMN vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN due_date DATE;
+ALTER TABLE expenses ADD COLUMN payment_status VARCHAR(20) NOT NULL DEFAULT 'paid'; -- paid, unpaid
+ALTER TABLE expenses ADD COLUMN paid_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_expenses_vendor ON expenses(vendor_id);
+CREATE INDEX idx_expenses_unpaid ON expenses(organization_id, due_date) WHERE payment_status = 'unpaid';
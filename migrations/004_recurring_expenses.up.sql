@@ -0,0 +1,49 @@
+-- Draft expenses are generated automatically and wait for the manager's confirmation
+ALTER TABLE expenses ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'confirmed'; -- draft, confirmed
+
+-- Service contracts (cleaning company, elevator maintenance, security, internet)
+CREATE TABLE contracts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0, -- agreed amount per period
+    renewal_notice_days INTEGER NOT NULL DEFAULT 30,
+    auto_renew BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT NOT NULL DEFAULT '',
+    reminder_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Templates the scheduler turns into draft expenses every period
+CREATE TABLE recurring_expenses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    vendor_id UUID REFERENCES vendors(id) ON DELETE SET NULL,
+    contract_id UUID REFERENCES contracts(id) ON DELETE SET NULL,
+    category VARCHAR(50) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    frequency VARCHAR(20) NOT NULL DEFAULT 'monthly', -- monthly, quarterly, yearly
+    day_of_month INTEGER NOT NULL DEFAULT 1,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    next_run_date DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE expenses ADD COLUMN recurring_expense_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_contracts_organization ON contracts(organization_id);
+CREATE INDEX idx_contracts_end_date ON contracts(end_date);
+CREATE INDEX idx_recurring_expenses_organization ON recurring_expenses(organization_id);
+CREATE INDEX idx_recurring_expenses_next_run ON recurring_expenses(next_run_date) WHERE active;
+CREATE INDEX idx_expenses_status ON expenses(status);
+-- One generated expense per template per period, so reruns are harmless
+CREATE UNIQUE INDEX idx_expenses_recurring_period ON expenses(recurring_expense_id, date)
+    WHERE recurring_expense_id IS NOT NULL;
@@ -0,0 +1,50 @@
+-- Expense workflow: draft -> submitted -> approved / rejected -> paid.
+-- The status column now also carries the payment state.
+UPDATE expenses SET status = CASE
+    WHEN status = 'draft' THEN 'draft'
+    WHEN payment_status = 'paid' THEN 'paid'
+    ELSE 'approved'
+END;
+ALTER TABLE expenses ALTER COLUMN status SET DEFAULT 'draft';
+
+DROP INDEX idx_expenses_unpaid;
+ALTER TABLE expenses DROP COLUMN payment_status;
+CREATE INDEX idx_expenses_unpaid ON expenses(organization_id, due_date) WHERE status = 'approved';
+
+ALTER TABLE expenses ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN submitted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE expenses ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0;
+
+-- Spending above the threshold needs required_approvals board members.
+-- category '' is the organization-wide default; a category rule overrides it.
+CREATE TABLE expense_approval_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    threshold DECIMAL(10,2) NOT NULL,
+    required_approvals INTEGER NOT NULL DEFAULT 2,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(organization_id, category)
+);
+
+CREATE TABLE expense_approvals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    decision VARCHAR(10) NOT NULL, -- approve, reject
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(expense_id, user_id)
+);
+
+CREATE TABLE expense_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_expense_approvals_expense ON expense_approvals(expense_id);
+CREATE INDEX idx_expense_comments_expense ON expense_comments(expense_id);
@@ -0,0 +1,81 @@
+-- Where the money is held: cash box (kasa), bank accounts, reserve fund
+CREATE TABLE accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL, -- cash, bank, reserve
+    bank_name VARCHAR(255) NOT NULL DEFAULT '',
+    iban VARCHAR(34) NOT NULL DEFAULT '',
+    opening_balance DECIMAL(12,2) NOT NULL DEFAULT 0,
+    opening_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE, -- default account of its type for postings
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Account journal: every movement of money, positive in, negative out
+CREATE TABLE account_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    amount DECIMAL(12,2) NOT NULL,
+    kind VARCHAR(20) NOT NULL, -- payment, expense, transfer, adjustment
+    source_type VARCHAR(20) NOT NULL DEFAULT '', -- due, expense
+    source_id UUID,
+    transfer_id UUID,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE account_reconciliations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    statement_date DATE NOT NULL,
+    statement_balance DECIMAL(12,2) NOT NULL,
+    book_balance DECIMAL(12,2) NOT NULL,
+    difference DECIMAL(12,2) NOT NULL,
+    adjustment_entry_id UUID REFERENCES account_entries(id) ON DELETE SET NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE dues ADD COLUMN account_id UUID REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN account_id UUID REFERENCES accounts(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_accounts_organization ON accounts(organization_id);
+CREATE INDEX idx_account_entries_account_date ON account_entries(account_id, date);
+CREATE INDEX idx_account_entries_organization_date ON account_entries(organization_id, date);
+CREATE INDEX idx_account_entries_source ON account_entries(source_type, source_id);
+CREATE INDEX idx_account_reconciliations_account ON account_reconciliations(account_id, statement_date);
+
+-- Backfill: every existing organization gets a default cash box and bank
+-- account, and past payments/expenses are posted to them.
+INSERT INTO accounts (organization_id, name, type, is_default, opening_date)
+SELECT o.id, 'Kasa', 'cash', TRUE, COALESCE(LEAST(
+    (SELECT MIN(paid_at)::date FROM dues WHERE organization_id = o.id),
+    (SELECT MIN(date) FROM expenses WHERE organization_id = o.id)), CURRENT_DATE)
+FROM organizations o;
+
+INSERT INTO accounts (organization_id, name, type, is_default, opening_date)
+SELECT o.id, 'Banka', 'bank', TRUE, a.opening_date
+FROM organizations o JOIN accounts a ON a.organization_id = o.id AND a.type = 'cash';
+
+UPDATE dues d SET account_id = a.id
+FROM accounts a
+WHERE d.status = 'paid' AND a.organization_id = d.organization_id AND a.is_default
+    AND a.type = CASE WHEN d.payment_method = 'cash' THEN 'cash' ELSE 'bank' END;
+
+UPDATE expenses e SET account_id = a.id
+FROM accounts a
+WHERE e.status = 'paid' AND a.organization_id = e.organization_id AND a.is_default AND a.type = 'cash';
+
+INSERT INTO account_entries (organization_id, account_id, date, amount, kind, source_type, source_id, description)
+SELECT organization_id, account_id, COALESCE(paid_at::date, due_date), amount, 'payment', 'due', id, COALESCE(description, '')
+FROM dues WHERE status = 'paid' AND account_id IS NOT NULL;
+
+INSERT INTO account_entries (organization_id, account_id, date, amount, kind, source_type, source_id, description)
+SELECT organization_id, account_id, COALESCE(paid_at::date, date), -amount, 'expense', 'expense', id, description
+FROM expenses WHERE status = 'paid' AND account_id IS NOT NULL;
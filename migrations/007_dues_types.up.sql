@@ -0,0 +1,11 @@
+-- Dues types, each collected into its own fund:
+-- aidat (monthly fee), demirbas (reserve fund / yedek akçe), special_assessment (ek bütçe),
+-- heating (yakıt), penalty (gecikme tazminatı)
+ALTER TABLE dues ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'aidat';
+
+-- The fund an expense is paid out of
+ALTER TABLE expenses ADD COLUMN fund VARCHAR(20) NOT NULL DEFAULT 'aidat';
+ALTER TABLE recurring_expenses ADD COLUMN fund VARCHAR(20) NOT NULL DEFAULT 'aidat';
+
+CREATE INDEX idx_dues_type ON dues(organization_id, type);
+CREATE INDEX idx_expenses_fund ON expenses(organization_id, fund);
@@ -0,0 +1,24 @@
+-- Allocation keys for splitting a total across units
+ALTER TABLE units ADD COLUMN area DECIMAL(10,2) NOT NULL DEFAULT 0; -- net m²
+ALTER TABLE units ADD COLUMN land_share DECIMAL(10,4) NOT NULL DEFAULT 0; -- arsa payı
+
+-- Special assessments (ek bütçe), e.g. roof renovation split into installments
+CREATE TABLE assessments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    total_amount DECIMAL(12,2) NOT NULL,
+    allocation_key VARCHAR(20) NOT NULL DEFAULT 'equal', -- equal, area, land_share
+    installments INTEGER NOT NULL,
+    first_due_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Installment dues generated for an assessment
+ALTER TABLE dues ADD COLUMN assessment_id UUID REFERENCES assessments(id) ON DELETE CASCADE;
+ALTER TABLE dues ADD COLUMN installment_no INTEGER;
+
+CREATE INDEX idx_assessments_organization ON assessments(organization_id);
+CREATE INDEX idx_dues_assessment ON dues(assessment_id, unit_id);
@@ -0,0 +1,34 @@
+-- Restructuring agreements (yapılandırma) for units with overdue dues
+CREATE TABLE payment_plans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, completed, broken, cancelled
+    original_amount DECIMAL(12,2) NOT NULL, -- sum of the consolidated dues
+    waived_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- penalty forgiven as part of the deal
+    total_amount DECIMAL(12,2) NOT NULL, -- original_amount - waived_amount
+    installments INTEGER NOT NULL,
+    first_due_date DATE NOT NULL,
+    grace_days INTEGER NOT NULL DEFAULT 15, -- days an installment may be late before the plan is broken
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id),
+    broken_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Original dues consolidated into a plan. They keep their rows and amounts
+-- and move to status 'restructured' while the plan is in force.
+CREATE TABLE payment_plan_dues (
+    plan_id UUID NOT NULL REFERENCES payment_plans(id) ON DELETE CASCADE,
+    due_id UUID NOT NULL REFERENCES dues(id) ON DELETE CASCADE,
+    PRIMARY KEY (plan_id, due_id)
+);
+
+-- New installment dues issued under a plan
+ALTER TABLE dues ADD COLUMN payment_plan_id UUID REFERENCES payment_plans(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_payment_plans_organization ON payment_plans(organization_id, status);
+CREATE INDEX idx_payment_plan_dues_due ON payment_plan_dues(due_id);
+CREATE INDEX idx_dues_payment_plan ON dues(payment_plan_id);
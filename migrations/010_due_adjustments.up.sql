@@ -0,0 +1,34 @@
+-- Sum of all adjustments on a due; amount - adjustment_total is what the
+-- unit actually owes (or paid). Kept in sync with due_adjustments.
+ALTER TABLE dues ADD COLUMN adjustment_total DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- Credit notes (alacak dekontu) owed to a unit, consumed by its future dues
+CREATE TABLE credit_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL,
+    remaining DECIMAL(10,2) NOT NULL,
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Append-only record of every change to what a due is worth
+CREATE TABLE due_adjustments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    due_id UUID NOT NULL REFERENCES dues(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL, -- void, discount, credit, credit_reversal
+    amount DECIMAL(10,2) NOT NULL, -- reduction of the due; negative for reversals
+    percent DECIMAL(5,2), -- set for percentage discounts
+    reason TEXT NOT NULL DEFAULT '',
+    credit_note_id UUID REFERENCES credit_notes(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_credit_notes_unit ON credit_notes(unit_id) WHERE remaining > 0;
+CREATE INDEX idx_due_adjustments_due ON due_adjustments(due_id);
+CREATE INDEX idx_due_adjustments_organization ON due_adjustments(organization_id, created_at);
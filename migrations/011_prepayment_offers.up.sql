@@ -0,0 +1,16 @@
+-- Organization-level prepayment offers: pay N months of dues upfront before
+-- valid_until and get discount_percent off each month
+CREATE TABLE prepayment_offers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'aidat', -- dues type the offer covers
+    months INTEGER NOT NULL,
+    discount_percent DECIMAL(5,2) NOT NULL,
+    valid_until DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_prepayment_offers_organization ON prepayment_offers(organization_id);
@@ -0,0 +1,36 @@
+-- People living in or owning a unit, with their role and stay
+CREATE TABLE unit_occupants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    resident_id UUID NOT NULL REFERENCES residents(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL, -- owner (kat maliki), co_owner, tenant (kiracı), family
+    move_in_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    move_out_date DATE, -- NULL while the person is still there
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Designated payer of a unit per dues type, overriding the default rules
+CREATE TABLE unit_payers (
+    unit_id UUID NOT NULL REFERENCES units(id) ON DELETE CASCADE,
+    dues_type VARCHAR(20) NOT NULL,
+    resident_id UUID NOT NULL REFERENCES residents(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (unit_id, dues_type)
+);
+
+-- The person a due is billed to and reminders are sent to
+ALTER TABLE dues ADD COLUMN payer_resident_id UUID REFERENCES residents(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_unit_occupants_unit ON unit_occupants(unit_id, move_in_date);
+CREATE INDEX idx_unit_occupants_resident ON unit_occupants(resident_id);
+CREATE INDEX idx_dues_payer ON dues(payer_resident_id);
+
+-- Existing single residents become the unit's owner; managers can correct
+-- the role afterwards
+INSERT INTO unit_occupants (unit_id, resident_id, role, move_in_date)
+SELECT u.id, u.resident_id, 'owner', u.created_at::date
+FROM units u WHERE u.resident_id IS NOT NULL;
+
+UPDATE dues d SET payer_resident_id = u.resident_id
+FROM units u WHERE d.unit_id = u.id AND u.resident_id IS NOT NULL;
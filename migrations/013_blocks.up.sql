@@ -0,0 +1,31 @@
+-- Blocks (A Blok, B Blok) of a multi-building site
+CREATE TABLE blocks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    floors INTEGER NOT NULL DEFAULT 0,
+    has_elevator BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(organization_id, name)
+);
+
+-- Units belong to a block; single-building sites leave it empty
+ALTER TABLE units ADD COLUMN block_id UUID REFERENCES blocks(id) ON DELETE RESTRICT;
+
+-- Unit numbers are unique within a block (A-5 and B-5 can coexist)
+ALTER TABLE units DROP CONSTRAINT units_organization_id_unit_number_key;
+CREATE UNIQUE INDEX idx_units_block_number ON units(organization_id, block_id, unit_number)
+    WHERE block_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_units_site_number ON units(organization_id, unit_number)
+    WHERE block_id IS NULL;
+
+-- Expenses, their recurring templates and assessments can be limited to a
+-- block; NULL means a common expense of the whole site
+ALTER TABLE expenses ADD COLUMN block_id UUID REFERENCES blocks(id) ON DELETE SET NULL;
+ALTER TABLE recurring_expenses ADD COLUMN block_id UUID REFERENCES blocks(id) ON DELETE SET NULL;
+ALTER TABLE assessments ADD COLUMN block_id UUID REFERENCES blocks(id) ON DELETE RESTRICT;
+
+CREATE INDEX idx_blocks_organization ON blocks(organization_id);
+CREATE INDEX idx_units_block ON units(block_id);
+CREATE INDEX idx_expenses_block ON expenses(block_id);
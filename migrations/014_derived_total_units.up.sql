@@ -0,0 +1,3 @@
+-- The unit count of a site is derived from its units instead of being typed
+-- in by hand
+ALTER TABLE organizations DROP COLUMN total_units;
@@ -0,0 +1,28 @@
+-- Payment receipts (tahsilat makbuzu), numbered 1, 2, 3... per organization
+ALTER TABLE organizations ADD COLUMN last_receipt_no INTEGER NOT NULL DEFAULT 0;
+
+-- Receipts stay in the books: the due of a receipt cannot be deleted, so
+-- neither can its unit (unit.Service.Delete refuses with a clear error).
+CREATE TABLE receipts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL,
+    due_id UUID NOT NULL UNIQUE REFERENCES dues(id),
+    amount DECIMAL(10,2) NOT NULL,
+    payment_method VARCHAR(20) NOT NULL DEFAULT '',
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(organization_id, number)
+);
+
+-- Dues paid before receipts existed get numbers in payment order; dues
+-- closed by credits alone took no money and get none
+INSERT INTO receipts (organization_id, number, due_id, amount, payment_method, issued_at)
+SELECT organization_id,
+    ROW_NUMBER() OVER (PARTITION BY organization_id ORDER BY paid_at, id),
+    id, amount - adjustment_total, COALESCE(payment_method, ''), paid_at
+FROM dues
+WHERE status = 'paid' AND COALESCE(payment_method, '') <> 'adjustment';
+
+UPDATE organizations o SET last_receipt_no = (
+    SELECT COUNT(*) FROM receipts r WHERE r.organization_id = o.id
+);
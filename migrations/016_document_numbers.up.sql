@@ -0,0 +1,87 @@
+-- Number format of each document kind (receipt, expense voucher, board
+-- decision) per organization; kinds without a row use the defaults
+CREATE TABLE document_series (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    prefix VARCHAR(10) NOT NULL,
+    digits INTEGER NOT NULL DEFAULT 6,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (organization_id, kind)
+);
+
+-- Last number issued per organization, kind and year. The row is locked by
+-- the transaction that takes a number, so numbers are issued one at a time
+-- and a rolled back transaction gives its number back.
+CREATE TABLE document_sequences (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    last_number INTEGER NOT NULL,
+    PRIMARY KEY (organization_id, kind, year)
+);
+
+-- Every number ever issued, for audits
+CREATE TABLE document_numbers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    seq INTEGER NOT NULL,
+    number VARCHAR(40) NOT NULL, -- formatted, e.g. MKB-2026-000123
+    source_id UUID, -- the due, expense or other record the number was issued for
+    note TEXT NOT NULL DEFAULT '',
+    issued_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (organization_id, kind, year, seq)
+);
+
+-- Receipts restart at 1 every year and carry their formatted number
+ALTER TABLE receipts ADD COLUMN year INTEGER;
+ALTER TABLE receipts ADD COLUMN document_no VARCHAR(40);
+ALTER TABLE receipts DROP CONSTRAINT receipts_organization_id_number_key;
+
+UPDATE receipts r SET year = EXTRACT(YEAR FROM r.issued_at)::int, number = n.seq
+FROM (
+    SELECT id, ROW_NUMBER() OVER (
+        PARTITION BY organization_id, EXTRACT(YEAR FROM issued_at) ORDER BY number
+    ) AS seq
+    FROM receipts
+) n
+WHERE r.id = n.id;
+UPDATE receipts SET document_no = 'MKB-' || year || '-' || LPAD(number::text, 6, '0');
+
+ALTER TABLE receipts ALTER COLUMN year SET NOT NULL;
+ALTER TABLE receipts ALTER COLUMN document_no SET NOT NULL;
+ALTER TABLE receipts ADD CONSTRAINT receipts_organization_year_number_key UNIQUE (organization_id, year, number);
+
+INSERT INTO document_numbers (organization_id, kind, year, seq, number, source_id, issued_at)
+SELECT organization_id, 'receipt', year, number, document_no, due_id, issued_at FROM receipts;
+INSERT INTO document_sequences (organization_id, kind, year, last_number)
+SELECT organization_id, 'receipt', year, MAX(number) FROM receipts GROUP BY organization_id, year;
+
+ALTER TABLE organizations DROP COLUMN last_receipt_no;
+
+-- Expense payment vouchers (tediye fişi)
+ALTER TABLE expenses ADD COLUMN voucher_no VARCHAR(40);
+
+WITH numbered AS (
+    SELECT id, organization_id, description, paid_at,
+        EXTRACT(YEAR FROM paid_at)::int AS year,
+        ROW_NUMBER() OVER (
+            PARTITION BY organization_id, EXTRACT(YEAR FROM paid_at) ORDER BY paid_at, created_at
+        ) AS seq
+    FROM expenses
+    WHERE status = 'paid' AND paid_at IS NOT NULL
+)
+INSERT INTO document_numbers (organization_id, kind, year, seq, number, source_id, note, issued_at)
+SELECT organization_id, 'expense_voucher', year, seq,
+    'TDY-' || year || '-' || LPAD(seq::text, 6, '0'), id, COALESCE(description, ''), paid_at
+FROM numbered;
+
+UPDATE expenses e SET voucher_no = n.number
+FROM document_numbers n
+WHERE n.kind = 'expense_voucher' AND n.source_id = e.id;
+
+INSERT INTO document_sequences (organization_id, kind, year, last_number)
+SELECT organization_id, 'expense_voucher', year, MAX(seq)
+FROM document_numbers WHERE kind = 'expense_voucher'
+GROUP BY organization_id, year;
@@ -0,0 +1,4 @@
+-- Month the organization's accounting year starts in; annual reports run
+-- from the first day of it for twelve months
+ALTER TABLE organizations ADD COLUMN fiscal_year_start INTEGER NOT NULL DEFAULT 1
+    CHECK (fiscal_year_start BETWEEN 1 AND 12);
@@ -0,0 +1,4 @@
+-- The portfolio view aggregates open dues and recent billing of every site
+-- a manager runs in one pass; these keep it to index scans per site
+CREATE INDEX idx_dues_open ON dues(organization_id, due_date) WHERE status IN ('pending', 'overdue');
+CREATE INDEX idx_dues_organization_due_date ON dues(organization_id, due_date);
@@ -0,0 +1,32 @@
+-- Site-wide search (arama): full-text for whole words and word prefixes,
+-- trigrams for typos and partial names.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- search_fold folds text for matching: Turkish İ, I and ı all become i
+-- (lower() would turn I into i and leave İ alone), then accents are
+-- dropped so "Ayşe", "AYSE" and "ayse" are the same word. unaccent() is
+-- only STABLE because its dictionary could change; naming the dictionary
+-- makes the wrapper safe to declare IMMUTABLE and use in indexes.
+CREATE OR REPLACE FUNCTION search_fold(s TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary, translate(COALESCE(s, ''), 'İIı', 'iii')))
+$$;
+
+-- Each searched table gets a full-text and a trigram index on the folded
+-- text the search matches on; the expressions must stay identical to the
+-- ones in internal/search.
+CREATE INDEX idx_residents_search ON residents USING GIN (to_tsvector('simple', search_fold(full_name)));
+CREATE INDEX idx_residents_search_trgm ON residents USING GIN (search_fold(full_name) gin_trgm_ops);
+
+CREATE INDEX idx_units_search ON units USING GIN (to_tsvector('simple', search_fold(unit_number)));
+CREATE INDEX idx_units_search_trgm ON units USING GIN (search_fold(unit_number) gin_trgm_ops);
+
+CREATE INDEX idx_expenses_search ON expenses
+    USING GIN (to_tsvector('simple', search_fold(description || ' ' || category)));
+CREATE INDEX idx_expenses_search_trgm ON expenses USING GIN (search_fold(description) gin_trgm_ops);
+
+CREATE INDEX idx_dues_search ON dues USING GIN (to_tsvector('simple', search_fold(description)))
+    WHERE description <> '';
+CREATE INDEX idx_dues_search_trgm ON dues USING GIN (search_fold(description) gin_trgm_ops)
+    WHERE description <> '';
@@ -0,0 +1,8 @@
+-- Board members (yönetim kurulu) of a site besides its manager; expense
+-- approvals are voted on by the manager and the board.
+CREATE TABLE organization_board_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
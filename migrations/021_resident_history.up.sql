@@ -0,0 +1,9 @@
+-- A resident's stays and the dues billed to them are history: deleting the
+-- resident is refused while either exists instead of erasing them
+ALTER TABLE unit_occupants DROP CONSTRAINT unit_occupants_resident_id_fkey;
+ALTER TABLE unit_occupants ADD CONSTRAINT unit_occupants_resident_id_fkey
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE RESTRICT;
+
+ALTER TABLE dues DROP CONSTRAINT dues_payer_resident_id_fkey;
+ALTER TABLE dues ADD CONSTRAINT dues_payer_resident_id_fkey
+    FOREIGN KEY (payer_resident_id) REFERENCES residents(id) ON DELETE RESTRICT;
@@ -22,3 +22,11 @@ func Connect(databaseURL string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// Querier is satisfied by both *sql.DB and *sql.Tx, so a helper can run on
+// its own or as part of a caller's transaction.
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
@@ -0,0 +1,250 @@
+// Package export streams lists and reports as CSV or XLSX downloads. CSV
+// follows what Excel expects in a Turkish locale (UTF-8 with BOM, ';'
+// separators, 1.234,56 and 31.01.2026); XLSX stores real numbers and dates
+// that Excel formats in the reader's locale.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/logger"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+	"github.com/mustafakemalcelik/sitetakip/pkg/xlsx"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+	// PDF is recognised by Format for the documents that have a printed
+	// form; those handlers render it themselves, Stream does not.
+	PDF = "pdf"
+
+	csvType  = "text/csv"
+	xlsxType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// Column is a column of an export. Width is in characters and only used by
+// XLSX; zero keeps the default.
+type Column struct {
+	Title string
+	Width float64
+}
+
+// AddFunc appends a row. Values may be string, float64 (amounts), int,
+// time.Time, *time.Time or nil.
+type AddFunc func(values ...interface{}) error
+
+// Format returns the export format asked for with ?format=csv|xlsx|pdf
+// or, when that is missing, the Accept header. An empty result means JSON.
+func Format(r *http.Request) string {
+	if f := strings.ToLower(r.URL.Query().Get("format")); f != "" {
+		if f == "json" {
+			return ""
+		}
+		return f
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case csvType:
+			return CSV
+		case xlsxType:
+			return XLSX
+		case "application/pdf":
+			return PDF
+		case "application/json":
+			return ""
+		}
+	}
+	return ""
+}
+
+// Stream writes a download named name.<format>. fill produces the rows
+// through add, typically straight from a database cursor, so large
+// organizations are never held in memory. Nothing is sent until the first
+// row, so an error before it is still answered as JSON; an error after it
+// can only cut the download short.
+func Stream(w http.ResponseWriter, format, name string, columns []Column, fill func(add AddFunc) error) {
+	if format != CSV && format != XLSX {
+		response.Error(w, http.StatusBadRequest, "unsupported format, use csv or xlsx")
+		return
+	}
+
+	s := &stream{w: w, format: format, name: name, columns: columns}
+	err := fill(s.add)
+	if err == nil {
+		err = s.start()
+	}
+	if err != nil {
+		if !s.started {
+			response.Error(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logger.Error("export failed", map[string]string{"name": name, "error": err.Error()})
+		return
+	}
+	if err := s.close(); err != nil {
+		logger.Error("export failed", map[string]string{"name": name, "error": err.Error()})
+	}
+}
+
+type stream struct {
+	w       http.ResponseWriter
+	format  string
+	name    string
+	columns []Column
+	started bool
+
+	buf   *bufio.Writer
+	csv   *csv.Writer
+	sheet *xlsx.Writer
+}
+
+// start sends the headers and the header row.
+func (s *stream) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	contentType := csvType + "; charset=utf-8"
+	if s.format == XLSX {
+		contentType = xlsxType
+	}
+	s.w.Header().Set("Content-Type", contentType)
+	s.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": s.name + "." + s.format}))
+	s.w.WriteHeader(http.StatusOK)
+
+	titles := make([]interface{}, len(s.columns))
+	if s.format == XLSX {
+		widths := make([]float64, len(s.columns))
+		for i, c := range s.columns {
+			titles[i] = xlsx.Cell{Value: c.Title, Style: xlsx.StyleHeader}
+			widths[i] = c.Width
+		}
+		var err error
+		if s.sheet, err = xlsx.NewWriter(s.w, s.name, widths); err != nil {
+			return err
+		}
+		return s.sheet.WriteRow(titles...)
+	}
+
+	s.buf = bufio.NewWriter(s.w)
+	if _, err := s.buf.WriteString("\xEF\xBB\xBF"); err != nil {
+		return err
+	}
+	s.csv = csv.NewWriter(s.buf)
+	s.csv.Comma = ';'
+	s.csv.UseCRLF = true
+	for i, c := range s.columns {
+		titles[i] = c.Title
+	}
+	return s.add(titles...)
+}
+
+func (s *stream) add(values ...interface{}) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if s.sheet != nil {
+		return s.sheet.WriteRow(values...)
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = Text(v)
+	}
+	return s.csv.Write(record)
+}
+
+func (s *stream) close() error {
+	if s.sheet != nil {
+		return s.sheet.Close()
+	}
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		return err
+	}
+	return s.buf.Flush()
+}
+
+// Text formats a value the way it is written to CSV. Text starting with
+// =, +, - or @ gets a leading ' so spreadsheets do not run it as a
+// formula; amounts are left as they are.
+func Text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case float64:
+		return Money(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format("02.01.2006")
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format("02.01.2006")
+	case xlsx.Cell:
+		return Text(v.Value)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Money formats an amount the Turkish way: 1.234.567,89.
+func Money(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-2:]
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if sign != "" && strings.Trim(whole+frac, "0") == "" {
+		sign = ""
+	}
+	return sign + b.String() + "," + frac
+}
+
+// Label returns the display name of a stored code, or the code itself when
+// it has none.
+func Label(labels map[string]string, code string) string {
+	if l, ok := labels[code]; ok {
+		return l
+	}
+	return code
+}
+
+var monthNames = [...]string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran",
+	"Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"}
+
+// MonthName returns the Turkish name of a month (1-12).
+func MonthName(month int) string {
+	if month < 1 || month > 12 {
+		return strconv.Itoa(month)
+	}
+	return monthNames[month-1]
+}
@@ -0,0 +1,339 @@
+// Package listing pages, sorts and filters the list endpoints the same way
+// everywhere. A list declares what it can be sorted and searched by in a
+// Config; Parse reads the request against it:
+//
+//	?limit=50&page=2      page by number (per_page and offset work too)
+//	?limit=50&cursor=...  keyset paging: the rows after next_cursor of the
+//	                      previous page, stable while rows are added
+//	?sort=-due_date       sort key, '-' for descending (or &order=desc)
+//	?q=ayşe               case-insensitive search, e.g. resident name or unit
+//	?from=2026-01-01&to=2026-03-31
+//	                      date range, both ends included
+//
+// Repositories pass their query through Filter and then Page (or Order),
+// and handlers answer with Meta so every list reports the same fields.
+package listing
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/database"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Config describes a list. Sort keys are the JSON names of the fields they
+// sort by, so the next cursor can be read off the last row; their SQL
+// expressions must not be NULL, as cursors compare them.
+type Config struct {
+	Sorts   map[string]string // sort key -> SQL expression
+	Default string            // default sort key, '-' for descending
+	ID      string            // unique column breaking ties, e.g. "d.id"
+	Search  []string          // text columns ?q= looks in
+	Date    string            // date column ?from= and ?to= filter on
+}
+
+// Params is a parsed list request.
+type Params struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+	Query  string
+	From   *time.Time
+	To     *time.Time
+
+	after  *cursor
+	config *Config
+}
+
+// Result is what Page found besides the rows: how many rows the filters
+// match and whether rows follow the page.
+type Result struct {
+	Total int
+	More  bool
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+// Parse reads the paging, sort and filter parameters of a request.
+func Parse(r *http.Request, c *Config) (*Params, error) {
+	q := r.URL.Query()
+	p := Defaults(c)
+
+	limit := q.Get("limit")
+	if limit == "" {
+		limit = q.Get("per_page")
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid limit")
+		}
+		if n > MaxLimit {
+			n = MaxLimit
+		}
+		p.Limit = n
+	}
+
+	if s := q.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid page")
+		}
+		p.Offset = (n - 1) * p.Limit
+	} else if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset")
+		}
+		p.Offset = n
+	}
+
+	if s := q.Get("sort"); s != "" {
+		p.Sort, p.Desc = strings.TrimPrefix(s, "-"), strings.HasPrefix(s, "-")
+		if _, ok := c.Sorts[p.Sort]; !ok {
+			return nil, fmt.Errorf("invalid sort, use one of %s", strings.Join(c.keys(), ", "))
+		}
+	}
+	switch q.Get("order") {
+	case "":
+	case "asc":
+		p.Desc = false
+	case "desc":
+		p.Desc = true
+	default:
+		return nil, fmt.Errorf("invalid order, use asc or desc")
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		cur, err := decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := c.Sorts[cur.Sort]; !ok {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		if q.Get("sort") == "" && q.Get("order") == "" {
+			p.Sort, p.Desc = cur.Sort, cur.Desc
+		} else if cur.Sort != p.Sort || cur.Desc != p.Desc {
+			return nil, fmt.Errorf("cursor belongs to another sort")
+		}
+		p.after = cur
+		p.Offset = 0
+	}
+
+	if len(c.Search) > 0 {
+		p.Query = strings.TrimSpace(q.Get("q"))
+	}
+	if c.Date != "" {
+		var err error
+		if p.From, err = date(q.Get("from"), "from"); err != nil {
+			return nil, err
+		}
+		if p.To, err = date(q.Get("to"), "to"); err != nil {
+			return nil, err
+		}
+		if p.From != nil && p.To != nil && p.To.Before(*p.From) {
+			return nil, fmt.Errorf("to must not be before from")
+		}
+	}
+	return p, nil
+}
+
+// Defaults returns the first page in the default order with no filters, for
+// callers that list without a request.
+func Defaults(c *Config) *Params {
+	return &Params{
+		Limit:  DefaultLimit,
+		Sort:   strings.TrimPrefix(c.Default, "-"),
+		Desc:   strings.HasPrefix(c.Default, "-"),
+		config: c,
+	}
+}
+
+// Filter appends the search and date conditions to a query that already
+// has a WHERE clause, numbering its parameters after args.
+func (p *Params) Filter(query string, args []interface{}) (string, []interface{}) {
+	c := p.config
+	if p.Query != "" {
+		args = append(args, "%"+escape(p.Query)+"%")
+		conds := make([]string, len(c.Search))
+		for i, col := range c.Search {
+			conds[i] = fmt.Sprintf("%s ILIKE $%d", col, len(args))
+		}
+		query += " AND (" + strings.Join(conds, " OR ") + ")"
+	}
+	if p.From != nil {
+		args = append(args, *p.From)
+		query += fmt.Sprintf(" AND %s >= $%d", c.Date, len(args))
+	}
+	if p.To != nil {
+		args = append(args, p.To.AddDate(0, 0, 1))
+		query += fmt.Sprintf(" AND %s < $%d", c.Date, len(args))
+	}
+	return query, args
+}
+
+// Order returns the ORDER BY clause, the ID breaking ties so rows keep
+// their place from one page to the next.
+func (p *Params) Order() string {
+	dir := ""
+	if p.Desc {
+		dir = " DESC"
+	}
+	return " ORDER BY " + p.config.Sorts[p.Sort] + dir + ", " + p.config.ID + dir
+}
+
+// Page runs a filtered query for the requested page, calling scan for each
+// of its rows, and counts the rows the filters match.
+func (p *Params) Page(db database.Querier, query string, args []interface{}, scan func(*sql.Rows) error) (Result, error) {
+	var res Result
+	if err := db.QueryRow("SELECT COUNT(*) FROM ("+query+") counted", args...).Scan(&res.Total); err != nil {
+		return res, err
+	}
+
+	query, args = p.seek(query, args)
+	// One row more than asked tells whether another page follows.
+	query += p.Order() + fmt.Sprintf(" LIMIT %d OFFSET %d", p.Limit+1, p.Offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		if n == p.Limit {
+			res.More = true
+			break
+		}
+		if err := scan(rows); err != nil {
+			return res, err
+		}
+		n++
+	}
+	return res, rows.Err()
+}
+
+// seek appends the keyset condition of a cursor: the rows after the
+// cursor's row in the sort order.
+func (p *Params) seek(query string, args []interface{}) (string, []interface{}) {
+	if p.after == nil {
+		return query, args
+	}
+	op := ">"
+	if p.Desc {
+		op = "<"
+	}
+	args = append(args, p.after.Value, p.after.ID)
+	query += fmt.Sprintf(" AND (%s, %s) %s ($%d, $%d)",
+		p.config.Sorts[p.Sort], p.config.ID, op, len(args)-1, len(args))
+	return query, args
+}
+
+// Meta describes a page of items (a slice of structs) for the response.
+// next_cursor is set when more rows follow and continues after the last
+// item.
+func (p *Params) Meta(res Result, items interface{}) response.Meta {
+	m := response.Meta{PerPage: p.Limit, Total: res.Total, HasMore: res.More}
+	m.TotalPages = (res.Total + p.Limit - 1) / p.Limit
+	if p.after == nil {
+		m.Page = p.Offset/p.Limit + 1
+	}
+	if res.More {
+		v := reflect.ValueOf(items)
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			last := reflect.Indirect(v.Index(v.Len() - 1))
+			m.NextCursor = encode(&cursor{Sort: p.Sort, Desc: p.Desc,
+				Value: field(last, p.Sort), ID: field(last, "id")})
+		}
+	}
+	return m
+}
+
+func (c *Config) keys() []string {
+	keys := make([]string, 0, len(c.Sorts))
+	for k := range c.Sorts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// field returns the value of the struct field with the given JSON name as
+// the database reads it back.
+func field(v reflect.Value, name string) string {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag != name {
+			continue
+		}
+		f := reflect.Indirect(v.Field(i))
+		if !f.IsValid() {
+			return ""
+		}
+		switch f := f.Interface().(type) {
+		case time.Time:
+			return f.Format(time.RFC3339Nano)
+		case float64:
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		default:
+			return fmt.Sprint(f)
+		}
+	}
+	return ""
+}
+
+func encode(c *cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	c := &cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+func date(s, name string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, use YYYY-MM-DD", name)
+	}
+	return &t, nil
+}
+
+// escape makes the LIKE wildcards in a search match themselves.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,182 @@
+package listing
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testConfig = &Config{
+	Sorts: map[string]string{
+		"due_date": "d.due_date",
+		"amount":   "d.amount",
+		"name":     "r.full_name",
+	},
+	Default: "-due_date",
+	ID:      "d.id",
+	Search:  []string{"r.full_name", "u.unit_number"},
+	Date:    "d.due_date",
+}
+
+type row struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name,omitempty"`
+	Amount  float64    `json:"amount"`
+	DueDate time.Time  `json:"due_date"`
+	PaidAt  *time.Time `json:"paid_at,omitempty"`
+}
+
+func parse(t *testing.T, query string) *Params {
+	t.Helper()
+	p, err := Parse(httptest.NewRequest("GET", "/?"+query, nil), testConfig)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", query, err)
+	}
+	return p
+}
+
+func TestParseDefaults(t *testing.T) {
+	p := parse(t, "")
+	if p.Limit != DefaultLimit || p.Offset != 0 || p.Sort != "due_date" || !p.Desc {
+		t.Fatalf("defaults = %+v", p)
+	}
+}
+
+func TestParsePaging(t *testing.T) {
+	tests := []struct {
+		query         string
+		limit, offset int
+		sort          string
+		desc          bool
+	}{
+		{"limit=20&page=3", 20, 40, "due_date", true},
+		{"per_page=10&offset=5", 10, 5, "due_date", true},
+		{"limit=100000", MaxLimit, 0, "due_date", true},
+		{"sort=amount", DefaultLimit, 0, "amount", false},
+		{"sort=-amount", DefaultLimit, 0, "amount", true},
+		{"sort=name&order=desc", DefaultLimit, 0, "name", true},
+		{"order=asc", DefaultLimit, 0, "due_date", false},
+	}
+	for _, tt := range tests {
+		p := parse(t, tt.query)
+		if p.Limit != tt.limit || p.Offset != tt.offset || p.Sort != tt.sort || p.Desc != tt.desc {
+			t.Errorf("Parse(%q) = limit %d offset %d sort %s desc %v", tt.query, p.Limit, p.Offset, p.Sort, p.Desc)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for query, want := range map[string]string{
+		"limit=0":                       "invalid limit",
+		"limit=x":                       "invalid limit",
+		"page=0":                        "invalid page",
+		"offset=-1":                     "invalid offset",
+		"sort=password":                 "invalid sort, use one of amount, due_date, name",
+		"order=up":                      "invalid order",
+		"cursor=!!!":                    "invalid cursor",
+		"cursor=bm90IGpzb24":            "invalid cursor",
+		"from=01.02.2026":               "invalid from",
+		"from=2026-03-01&to=2026-02-01": "to must not be before from",
+	} {
+		_, err := Parse(httptest.NewRequest("GET", "/?"+query, nil), testConfig)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	p := parse(t, "q=50%25_off&from=2026-01-01&to=2026-01-31")
+	query, args := p.Filter("SELECT * FROM dues d WHERE d.organization_id = $1", []interface{}{"org"})
+
+	wantQuery := "SELECT * FROM dues d WHERE d.organization_id = $1" +
+		" AND (r.full_name ILIKE $2 OR u.unit_number ILIKE $2)" +
+		" AND d.due_date >= $3 AND d.due_date < $4"
+	if query != wantQuery {
+		t.Fatalf("query = %q\nwant    %q", query, wantQuery)
+	}
+	wantArgs := []interface{}{"org", `%50\%\_off%`,
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	if got := parse(t, "").Order(); got != " ORDER BY d.due_date DESC, d.id DESC" {
+		t.Errorf("Order() = %q", got)
+	}
+	if got := parse(t, "sort=name").Order(); got != " ORDER BY r.full_name, d.id" {
+		t.Errorf("Order() = %q", got)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	items := []row{
+		{ID: "a", Amount: 100, DueDate: due.AddDate(0, 1, 0)},
+		{ID: "b", Amount: 1e6, DueDate: due},
+	}
+
+	first := parse(t, "limit=2&sort=-amount")
+	meta := first.Meta(Result{Total: 5, More: true}, items)
+	if meta.Page != 1 || meta.TotalPages != 3 || !meta.HasMore || meta.NextCursor == "" {
+		t.Fatalf("meta = %+v", meta)
+	}
+
+	// The cursor carries the sort, so the next request needs only the cursor.
+	next := parse(t, "limit=2&cursor="+meta.NextCursor)
+	if next.Sort != "amount" || !next.Desc || next.Offset != 0 {
+		t.Fatalf("next = %+v", next)
+	}
+	query, args := next.seek("SELECT * FROM dues d WHERE d.organization_id = $1", []interface{}{"org"})
+	if want := "SELECT * FROM dues d WHERE d.organization_id = $1 AND (d.amount, d.id) < ($2, $3)"; query != want {
+		t.Fatalf("query = %q\nwant    %q", query, want)
+	}
+	if want := []interface{}{"org", "1000000", "b"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	if m := next.Meta(Result{Total: 5}, items); m.Page != 0 || m.NextCursor != "" || m.HasMore {
+		t.Fatalf("last page meta = %+v", m)
+	}
+
+	asc := parse(t, "sort=due_date")
+	cur := asc.Meta(Result{Total: 3, More: true}, []*row{&items[1]}).NextCursor
+	_, args = parse(t, "cursor="+cur).seek("", nil)
+	if want := []interface{}{"2026-03-01T00:00:00Z", "b"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+
+	if _, err := Parse(httptest.NewRequest("GET", "/?sort=name&cursor="+meta.NextCursor, nil), testConfig); err == nil ||
+		err.Error() != "cursor belongs to another sort" {
+		t.Fatalf("mismatched sort error = %v", err)
+	}
+}
+
+func TestSeekWithoutCursor(t *testing.T) {
+	query, args := parse(t, "page=2").seek("SELECT 1", []interface{}{"org"})
+	if query != "SELECT 1" || len(args) != 1 {
+		t.Fatalf("seek without cursor changed the query: %q %v", query, args)
+	}
+}
+
+func TestField(t *testing.T) {
+	paid := time.Date(2026, 3, 5, 10, 30, 0, 0, time.UTC)
+	v := reflect.ValueOf(row{ID: "x", Name: "Ayşe", Amount: 1250.5, PaidAt: &paid})
+	for name, want := range map[string]string{
+		"id":      "x",
+		"name":    "Ayşe",
+		"amount":  "1250.5",
+		"paid_at": "2026-03-05T10:30:00Z",
+		"missing": "",
+	} {
+		if got := field(v, name); got != want {
+			t.Errorf("field(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := field(reflect.ValueOf(row{}), "paid_at"); got != "" {
+		t.Errorf("nil pointer field = %q, want empty", got)
+	}
+}
@@ -0,0 +1,256 @@
+// Package pdf renders the printable documents of the application:
+// receipts, account statements and reports. DejaVu Sans is embedded so
+// Turkish letters print the same on every device; only the glyphs a
+// document uses end up in the file.
+package pdf
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	ContentType = "application/pdf"
+
+	family     = "DejaVu"
+	lineHeight = 6.0
+)
+
+var (
+	//go:embed fonts/DejaVuSans.ttf
+	regular []byte
+	//go:embed fonts/DejaVuSans-Bold.ttf
+	bold []byte
+)
+
+// Align is the horizontal alignment of a table column.
+type Align string
+
+const (
+	Left   Align = "L"
+	Center Align = "C"
+	Right  Align = "R"
+)
+
+// Column is a table column. Width is in millimetres; columns with zero
+// width share what is left of the line.
+type Column struct {
+	Title string
+	Width float64
+	Align Align
+}
+
+// Document is an A4 portrait document with a page footer. Build it with
+// the methods below and send it with Serve.
+type Document struct {
+	f *fpdf.Fpdf
+}
+
+// New starts a document. title appears in the file's metadata and the
+// page footer.
+func New(title string) *Document {
+	f := fpdf.New("P", "mm", "A4", "")
+	f.AddUTF8FontFromBytes(family, "", regular)
+	f.AddUTF8FontFromBytes(family, "B", bold)
+	f.SetTitle(title, true)
+	f.SetCreator("SiteTakip", true)
+	f.SetMargins(15, 15, 15)
+	f.SetAutoPageBreak(true, 20)
+	f.AliasNbPages("{nb}")
+
+	d := &Document{f: f}
+	printed := time.Now().Format("02.01.2006 15:04")
+	f.SetFooterFunc(func() {
+		f.SetY(-14)
+		f.SetFont(family, "", 7)
+		f.SetTextColor(120, 120, 120)
+		left, _, _, _ := f.GetMargins()
+		f.CellFormat(0, 4, title+" · "+printed, "", 0, "L", false, 0, "")
+		f.SetX(left)
+		f.CellFormat(0, 4, fmt.Sprintf("Sayfa %d/{nb}", f.PageNo()), "", 0, "R", false, 0, "")
+		f.SetTextColor(0, 0, 0)
+	})
+	f.AddPage()
+	return d
+}
+
+// Header prints the site's name and address followed by the document
+// title.
+func (d *Document) Header(siteName, address, title string) {
+	f := d.f
+	f.SetFont(family, "B", 14)
+	f.MultiCell(0, 7, siteName, "", "L", false)
+	if address != "" {
+		f.SetFont(family, "", 9)
+		f.MultiCell(0, 5, address, "", "L", false)
+	}
+	left, _, right, _ := f.GetMargins()
+	width, _ := f.GetPageSize()
+	f.Ln(2)
+	f.Line(left, f.GetY(), width-right, f.GetY())
+	f.Ln(4)
+	f.SetFont(family, "B", 12)
+	f.CellFormat(0, 8, title, "", 1, "C", false, 0, "")
+	f.Ln(2)
+}
+
+// Field prints a label and its value on one line.
+func (d *Document) Field(label, value string) {
+	f := d.f
+	f.SetFont(family, "B", 10)
+	f.CellFormat(50, lineHeight, label, "", 0, "L", false, 0, "")
+	f.SetFont(family, "", 10)
+	f.MultiCell(0, lineHeight, value, "", "L", false)
+}
+
+// Text prints a paragraph.
+func (d *Document) Text(s string) {
+	d.f.SetFont(family, "", 10)
+	d.f.MultiCell(0, lineHeight, s, "", "L", false)
+}
+
+// Heading prints a small section title.
+func (d *Document) Heading(s string) {
+	d.f.Ln(3)
+	d.f.SetFont(family, "B", 11)
+	d.f.CellFormat(0, 7, s, "", 1, "L", false, 0, "")
+}
+
+// Space adds vertical space in millimetres.
+func (d *Document) Space(mm float64) {
+	d.f.Ln(mm)
+}
+
+// Table prints rows under a shaded header row, repeating the header on
+// every page the table runs onto. Cells that do not fit their column are
+// shortened. Rows in bold are printed in the bold face, e.g. totals.
+func (d *Document) Table(columns []Column, rows [][]string, bold map[int]bool) {
+	f := d.f
+	widths := d.widths(columns)
+
+	header := func() {
+		f.SetFont(family, "B", 9)
+		f.SetFillColor(230, 230, 230)
+		for i, c := range columns {
+			f.CellFormat(widths[i], 7, fit(f, c.Title, widths[i]), "1", 0, string(align(c.Align)), true, 0, "")
+		}
+		f.Ln(-1)
+	}
+	header()
+
+	_, pageHeight := f.GetPageSize()
+	_, _, _, bottom := f.GetMargins()
+	for r, row := range rows {
+		if f.GetY()+lineHeight > pageHeight-bottom {
+			f.AddPage()
+			header()
+		}
+		style := ""
+		if bold[r] {
+			style = "B"
+		}
+		f.SetFont(family, style, 9)
+		for i, c := range columns {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			f.CellFormat(widths[i], lineHeight, fit(f, text, widths[i]), "1", 0, string(align(c.Align)), false, 0, "")
+		}
+		f.Ln(-1)
+	}
+}
+
+// Signatures prints labelled signature lines side by side.
+func (d *Document) Signatures(labels ...string) {
+	if len(labels) == 0 {
+		return
+	}
+	f := d.f
+	f.Ln(18)
+	width := d.lineWidth() / float64(len(labels))
+	f.SetFont(family, "", 9)
+	for range labels {
+		f.CellFormat(width, 5, "..............................", "", 0, "C", false, 0, "")
+	}
+	f.Ln(-1)
+	for _, l := range labels {
+		f.CellFormat(width, 5, l, "", 0, "C", false, 0, "")
+	}
+	f.Ln(-1)
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.f.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Serve renders the document and sends it inline as name.pdf, so browsers
+// show it and phones can share it.
+func Serve(w http.ResponseWriter, name string, d *Document) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name + ".pdf"}))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	return err
+}
+
+func (d *Document) lineWidth() float64 {
+	left, _, right, _ := d.f.GetMargins()
+	width, _ := d.f.GetPageSize()
+	return width - left - right
+}
+
+// widths gives columns without a width an equal part of the free space.
+func (d *Document) widths(columns []Column) []float64 {
+	free, flexible := d.lineWidth(), 0
+	for _, c := range columns {
+		free -= c.Width
+		if c.Width == 0 {
+			flexible++
+		}
+	}
+	widths := make([]float64, len(columns))
+	for i, c := range columns {
+		widths[i] = c.Width
+		if c.Width == 0 && flexible > 0 && free > 0 {
+			widths[i] = free / float64(flexible)
+		}
+	}
+	return widths
+}
+
+func align(a Align) Align {
+	if a == "" {
+		return Left
+	}
+	return a
+}
+
+// fit shortens s with an ellipsis until it fits a cell of the given width.
+func fit(f *fpdf.Fpdf, s string, width float64) string {
+	max := width - 2*f.GetCellMargin()
+	if f.GetStringWidth(s) <= max {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && f.GetStringWidth(string(r)+"…") > max {
+		r = r[:len(r)-1]
+	}
+	return strings.TrimSpace(string(r)) + "…"
+}
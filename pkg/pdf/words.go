@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"math"
+	"strings"
+)
+
+var (
+	ones = []string{"", "bir", "iki", "üç", "dört", "beş", "altı", "yedi", "sekiz", "dokuz"}
+	tens = []string{"", "on", "yirmi", "otuz", "kırk", "elli", "altmış", "yetmiş", "seksen", "doksan"}
+)
+
+// AmountInWords spells an amount out the way receipts do ("yalnız"),
+// e.g. 1250.5 -> "bin iki yüz elli Türk lirası elli kuruş".
+func AmountInWords(amount float64) string {
+	kurus := int64(math.Round(math.Abs(amount) * 100))
+	lira, rest := kurus/100, kurus%100
+
+	words := ""
+	if lira > 0 || rest == 0 {
+		words = spell(lira) + " Türk lirası"
+	}
+	if rest > 0 {
+		if words != "" {
+			words += " "
+		}
+		words += spell(rest) + " kuruş"
+	}
+	if amount < 0 {
+		words = "eksi " + words
+	}
+	return words
+}
+
+// spell writes out a whole number. Turkish drops "bir" before "yüz" and
+// "bin": 1100 is "bin yüz".
+func spell(n int64) string {
+	if n == 0 {
+		return "sıfır"
+	}
+	scales := []string{"", "bin", "milyon", "milyar", "trilyon"}
+	var groups []string
+	for i := 0; n > 0 && i < len(scales); i++ {
+		g := n % 1000
+		n /= 1000
+		if g == 0 {
+			continue
+		}
+		words := hundreds(g)
+		if i == 1 && g == 1 {
+			words = ""
+		}
+		if scales[i] != "" {
+			words = strings.TrimSpace(words + " " + scales[i])
+		}
+		groups = append([]string{words}, groups...)
+	}
+	return strings.Join(groups, " ")
+}
+
+func hundreds(n int64) string {
+	var parts []string
+	if h := n / 100; h > 0 {
+		if h > 1 {
+			parts = append(parts, ones[h])
+		}
+		parts = append(parts, "yüz")
+	}
+	if t := n % 100 / 10; t > 0 {
+		parts = append(parts, tens[t])
+	}
+	if o := n % 10; o > 0 {
+		parts = append(parts, ones[o])
+	}
+	return strings.Join(parts, " ")
+}
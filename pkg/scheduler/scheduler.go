@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mustafakemalcelik/sitetakip/pkg/logger"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler runs background jobs at fixed intervals. Each job runs once
+// at startup and then on its own ticker; a failing run is logged and
+// retried on the next tick. Jobs must be safe to run on several instances
+// at once (they rely on database constraints and row locks for that).
+type Scheduler struct {
+	jobs []job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, run func() error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(j)
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(j job) {
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Error("job_panic", map[string]interface{}{"job": j.name, "panic": rec})
+		}
+	}()
+
+	if err := j.run(); err != nil {
+		logger.Error("job_failed", map[string]interface{}{"job": j.name, "error": err.Error()})
+		return
+	}
+	logger.Info("job_done", map[string]interface{}{"job": j.name, "duration_ms": time.Since(start).Milliseconds()})
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustafakemalcelik/sitetakip/pkg/response"
+)
+
+// Local stores files on the server's filesystem and serves them through
+// HMAC-signed download URLs.
+type Local struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocal(root, baseURL, secret string) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &Local{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+	}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	p := filepath.Join(l.root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(l.root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid storage key")
+	}
+	return p, nil
+}
+
+func (l *Local) Put(key string, r io.Reader, size int64, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(p)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return f.Close()
+}
+
+func (l *Local) Open(key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) SignedURL(key string, ttl time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", l.sign(key, expires))
+	return fmt.Sprintf("%s/%s?%s", l.baseURL, key, q.Encode()), nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) verify(key, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expiry")
+	}
+	if time.Now().Unix() > exp {
+		return fmt.Errorf("link expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, expires))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// Handler serves signed download URLs. It must be mounted at the path used
+// as baseURL, e.g. r.Get("/files/*", local.Handler()).
+func (l *Local) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, "*")
+		q := r.URL.Query()
+		if err := l.verify(key, q.Get("expires"), q.Get("signature")); err != nil {
+			response.Error(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		p, err := l.path(key)
+		if err != nil {
+			response.Error(w, http.StatusNotFound, "file not found")
+			return
+		}
+		if _, err := os.Stat(p); err != nil {
+			response.Error(w, http.StatusNotFound, "file not found")
+			return
+		}
+		http.ServeFile(w, r, p)
+	}
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestLocal(t *testing.T) (*Local, *httptest.Server) {
+	t.Helper()
+	r := chi.NewRouter()
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	l, err := NewLocal(t.TempDir(), srv.URL+"/api/v1/files/", "test-secret")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	r.Get("/api/v1/files/*", l.Handler())
+	return l, srv
+}
+
+func get(t *testing.T, rawURL string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	l, _ := newTestLocal(t)
+	const key, content = "org/expense/e1/fatura.pdf", "%PDF-1.4 test"
+
+	if err := l.Put(key, strings.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	f, err := l.Open(key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != content {
+		t.Fatalf("Open read %q, want %q", data, content)
+	}
+
+	signed, err := l.SignedURL(key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if status, body := get(t, signed); status != http.StatusOK || body != content {
+		t.Fatalf("signed GET = %d %q", status, body)
+	}
+
+	if err := l.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Open(key); err == nil {
+		t.Fatal("Open after Delete succeeded")
+	}
+	if err := l.Delete(key); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+	if status, _ := get(t, signed); status != http.StatusNotFound {
+		t.Fatalf("GET after Delete = %d, want 404", status)
+	}
+}
+
+func TestLocalSignedURLRejected(t *testing.T) {
+	l, _ := newTestLocal(t)
+	const key = "org/dues/d1/dekont.png"
+	if err := l.Put(key, strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	signed, err := l.SignedURL(key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, _ := url.Parse(signed)
+	q := u.Query()
+
+	tampered := *u
+	tq := u.Query()
+	tq.Set("signature", strings.Repeat("0", len(q.Get("signature"))))
+	tampered.RawQuery = tq.Encode()
+
+	other := *u
+	other.Path = strings.Replace(u.Path, "d1", "d2", 1)
+
+	expired := *u
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	eq := u.Query()
+	eq.Set("expires", past)
+	eq.Set("signature", l.sign(key, past))
+	expired.RawQuery = eq.Encode()
+
+	for name, tt := range map[string]struct {
+		url, want string
+	}{
+		"tampered signature": {tampered.String(), "invalid signature"},
+		"other key":          {other.String(), "invalid signature"},
+		"expired":            {expired.String(), "link expired"},
+		"no query":           {u.Scheme + "://" + u.Host + u.Path, "invalid expiry"},
+	} {
+		status, body := get(t, tt.url)
+		if status != http.StatusForbidden || !strings.Contains(body, tt.want) {
+			t.Errorf("%s: GET = %d %s, want 403 %q", name, status, body, tt.want)
+		}
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	l, _ := newTestLocal(t)
+	for _, key := range []string{"../outside.txt", "org/../../outside.txt", ""} {
+		if err := l.Put(key, strings.NewReader("x"), 1, "text/plain"); err == nil || err.Error() != "invalid storage key" {
+			t.Errorf("Put(%q) error = %v, want invalid storage key", key, err)
+		}
+	}
+}
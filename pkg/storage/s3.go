@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 talks to any S3-compatible object store using path-style requests
+// signed with AWS Signature Version 4, so it works against AWS, MinIO,
+// Cloudflare R2 and similar services without an SDK.
+type S3 struct {
+	cfg    S3Config
+	host   string
+	scheme string
+	client *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+
+	return &S3{
+		cfg:    cfg,
+		host:   u.Host,
+		scheme: u.Scheme,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (s *S3) objectPath(key string) string {
+	return "/" + s.cfg.Bucket + "/" + key
+}
+
+func (s *S3) Put(key string, r io.Reader, size int64, contentType string) error {
+	req, err := http.NewRequest(http.MethodPut, s.scheme+"://"+s.host+uriEncode(s.objectPath(key), false), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req, http.StatusOK)
+}
+
+func (s *S3) Open(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.scheme+"://"+s.host+uriEncode(s.objectPath(key), false), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.signHeaders(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 get %s: status %d", key, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.scheme+"://"+s.host+uriEncode(s.objectPath(key), false), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, http.StatusNoContent, http.StatusOK)
+}
+
+func (s *S3) do(req *http.Request, okStatuses ...int) error {
+	s.signHeaders(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, st := range okStatuses {
+		if resp.StatusCode == st {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// SignedURL returns a presigned GET URL (query-string authentication).
+func (s *S3) SignedURL(key string, ttl time.Duration) (string, error) {
+	return s.presign(uriEncode(s.objectPath(key), false), ttl, time.Now().UTC()), nil
+}
+
+// presign signs a GET of the already encoded path at the given time.
+func (s *S3) presign(path string, ttl time.Duration, now time.Time) string {
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	q := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    s.cfg.AccessKey + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(ttl.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	query := canonicalQuery(q)
+
+	canonical := strings.Join([]string{
+		http.MethodGet, path, query, "host:" + s.host + "\n", "host", unsignedPayload,
+	}, "\n")
+	sig := s.signature(now, amzDate, scope, canonical)
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", s.scheme, s.host, path, query, sig)
+}
+
+func (s *S3) signHeaders(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 s.host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers["content-type"] = ct
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), canonicalQuery(nil), canonHeaders.String(), signed, unsignedPayload,
+	}, "\n")
+	sig := s.signature(now, amzDate, scope, canonical)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signed, sig,
+	))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, uriEncode(k, true)+"="+uriEncode(params[k], true))
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode implements the SigV4 URI encoding: every byte except the
+// RFC 3986 unreserved characters is percent-encoded, and "/" is kept as-is
+// in paths.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
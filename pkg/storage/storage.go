@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Storage abstracts where uploaded files (receipts, payment proofs) live.
+// Keys are slash-separated paths such as "orgID/expense/expenseID/file.pdf".
+type Storage interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// SignedURL returns a short-lived URL that allows downloading the file
+	// without an Authorization header.
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// NewFromEnv builds the storage backend selected by STORAGE_DRIVER
+// ("local" by default, or "s3" for any S3-compatible service such as MinIO).
+func NewFromEnv() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		root := os.Getenv("STORAGE_PATH")
+		if root == "" {
+			root = "uploads"
+		}
+		baseURL := os.Getenv("PUBLIC_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080"
+		}
+		secret := os.Getenv("STORAGE_SIGNING_KEY")
+		if secret == "" {
+			secret = os.Getenv("JWT_SECRET")
+		}
+		if secret == "" {
+			secret = "dev-secret-change-in-production"
+		}
+		return NewLocal(root, baseURL+"/api/v1/files", secret)
+	case "s3":
+		cfg := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -0,0 +1,270 @@
+// Package xlsx reads and writes the small subset of Office Open XML
+// spreadsheets the application exchanges with managers: one sheet of plain
+// cells, no formulas or formatting beyond number styles.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Limits on what a sheet may hold, checked before anything is allocated so
+// a small crafted upload cannot claim a huge sheet. MaxColumns is Excel's own
+// limit (XFD); MaxPartSize bounds each part of the file once decompressed.
+const (
+	MaxColumns  = 16384
+	MaxPartSize = 64 << 20
+)
+
+// ReadRows returns the cells of the first worksheet as text, row by row.
+// Missing cells are returned as empty strings and trailing empty rows are
+// dropped. A sheet with a row past maxRows is rejected.
+func ReadRows(r io.ReaderAt, size int64, maxRows int) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if f := files["xl/sharedStrings.xml"]; f != nil {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	f := files[sheetPath]
+	if f == nil {
+		return nil, fmt.Errorf("xlsx file has no worksheet")
+	}
+	return readSheet(f, shared, maxRows)
+}
+
+// openPart opens a part of the file, reading at most MaxPartSize bytes of
+// it; a zip bomb ends in a truncated, invalid XML document.
+func openPart(f *zip.File) (io.Reader, io.Closer, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.LimitReader(rc, MaxPartSize), rc, nil
+}
+
+func decodeFile(f *zip.File, v interface{}) error {
+	r, c, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// firstSheet resolves the workbook's first sheet to its part name.
+func firstSheet(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	wb, rels := files["xl/workbook.xml"], files["xl/_rels/workbook.xml.rels"]
+	if wb == nil || rels == nil {
+		return fallback, nil
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeFile(wb, &workbook); err != nil {
+		return "", fmt.Errorf("invalid workbook: %w", err)
+	}
+	var relationships struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeFile(rels, &relationships); err != nil {
+		return "", fmt.Errorf("invalid workbook relationships: %w", err)
+	}
+	if len(workbook.Sheets) == 0 {
+		return fallback, nil
+	}
+
+	for _, rel := range relationships.Items {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// richText is a shared or inline string, either plain or split into runs.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := decodeFile(f, &sst); err != nil {
+		return nil, fmt.Errorf("invalid shared strings: %w", err)
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.String()
+	}
+	return shared, nil
+}
+
+type cell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+// readSheet streams the worksheet so large sheets are not held twice.
+func readSheet(f *zip.File, shared []string, maxRows int) ([][]string, error) {
+	r, c, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var rows [][]string
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid worksheet: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row struct {
+			Index int    `xml:"r,attr"`
+			Cells []cell `xml:"c"`
+		}
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("invalid worksheet row: %w", err)
+		}
+		index := row.Index
+		if index == 0 {
+			index = len(rows) + 1
+		}
+		if index < 0 || index > maxRows {
+			return nil, fmt.Errorf("the sheet has rows past row %d", maxRows)
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			if col < 0 || col >= MaxColumns {
+				return nil, fmt.Errorf("invalid worksheet cell %q", c.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = cellText(c, shared)
+		}
+		rows[index-1] = values
+	}
+
+	for len(rows) > 0 && isEmpty(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+func cellText(c cell, shared []string) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "inlineStr":
+		return c.Inline.String()
+	case "b":
+		if c.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "n", "":
+		// Whole numbers stored as floats ("5321234567.0", "1E+3") are
+		// returned without the fraction so phone and unit numbers survive.
+		if f, err := strconv.ParseFloat(c.Value, 64); err == nil && f == float64(int64(f)) {
+			return strconv.FormatInt(int64(f), 10)
+		}
+		return c.Value
+	default:
+		return c.Value
+	}
+}
+
+// columnIndex converts the letters of a cell reference ("C7") to a
+// zero-based column index, or -1 past MaxColumns.
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A') + 1
+		if n > MaxColumns {
+			return -1
+		}
+	}
+	return n - 1
+}
+
+func isEmpty(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
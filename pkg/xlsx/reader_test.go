@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sheetFile builds an xlsx holding only a first worksheet with the given
+// sheetData body.
+func sheetFile(t *testing.T, sheetData string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, `<worksheet><sheetData>`+sheetData+`</sheetData></worksheet>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestReadRowsRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Daireler", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteRow("Blok", "Daire", "Telefon")
+	w.WriteRow("A", 5, "05321234567")
+	w.WriteRow(nil, "12", 3.5)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ReadRows(bytes.NewReader(buf.Bytes()), int64(buf.Len()), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Blok", "Daire", "Telefon"},
+		{"A", "5", "05321234567"},
+		{"", "12", "3.5"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestReadRowsSparse(t *testing.T) {
+	f := sheetFile(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>Ad</t></is></c></row>`+
+		`<row r="3"><c r="C3"><v>7.0</v></c></row><row r="4"></row>`)
+	rows, err := ReadRows(f, f.Size(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"Ad"}, nil, {"", "", "7"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestReadRowsLimits(t *testing.T) {
+	tests := []struct {
+		name, sheetData, err string
+	}{
+		{"row index past the limit", `<row r="2000000000"><c r="A2000000000"><v>1</v></c></row>`, "past row 10"},
+		{"negative row index", `<row r="-5"><c><v>1</v></c></row>`, "past row 10"},
+		{"too many rows", strings.Repeat(`<row><c><v>1</v></c></row>`, 11), "past row 10"},
+		{"column past XFD", `<row r="1"><c r="XFE1"><v>1</v></c></row>`, "invalid worksheet cell"},
+		{"long column letters", `<row r="1"><c r="` + strings.Repeat("Z", 40) + `1"><v>1</v></c></row>`, "invalid worksheet cell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sheetFile(t, tt.sheetData)
+			_, err := ReadRows(f, f.Size(), 10)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	f := sheetFile(t, `<row r="1"><c r="XFD1"><v>1</v></c></row>`)
+	rows, err := ReadRows(f, f.Size(), 10)
+	if err != nil {
+		t.Fatalf("last column: %v", err)
+	}
+	if len(rows[0]) != MaxColumns {
+		t.Fatalf("last column: got %d cells, want %d", len(rows[0]), MaxColumns)
+	}
+}
+
+func TestReadRowsDecompressedSize(t *testing.T) {
+	// A sheet padded past MaxPartSize compresses to a few hundred KB.
+	padding := strings.Repeat(" ", MaxPartSize)
+	f := sheetFile(t, `<row r="1"><c><v>1</v></c></row>`+padding)
+	if f.Size() > 1<<20 {
+		t.Fatalf("test file is %d bytes, expected it to compress", f.Size())
+	}
+	if _, err := ReadRows(f, f.Size(), 10); err == nil {
+		t.Fatal("expected an error for a sheet past MaxPartSize")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "XFD1": MaxColumns - 1, "XFE1": -1} {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cell styles of the written workbook, indexes into cellXfs of styles.xml.
+const (
+	StyleDefault = iota
+	StyleHeader  // bold
+	StyleMoney   // #,##0.00, shown with the reader's decimal separators
+	StyleDate    // the reader's short date format
+	StyleInteger // #,##0
+)
+
+// Writer streams a single-sheet workbook. Rows are written to the zip
+// archive as they come, so the sheet never has to fit in memory; the
+// archive is only valid after Close.
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+	err   error
+}
+
+// NewWriter starts a workbook with one sheet whose first row is frozen as
+// a header. widths are optional column widths in characters; zero leaves a
+// column at the default width.
+func NewWriter(w io.Writer, sheetName string, widths []float64) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetTitle(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, xml.Header+p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sw := &Writer{zw: zw, sheet: bufio.NewWriter(f)}
+	sw.write(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sw.write(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(widths) > 0 {
+		sw.write("<cols>")
+		for i, width := range widths {
+			if width > 0 {
+				sw.write(fmt.Sprintf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width))
+			}
+		}
+		sw.write("</cols>")
+	}
+	sw.write("<sheetData>")
+	return sw, sw.err
+}
+
+// Cell is a value with an explicit style. Plain values passed to WriteRow
+// get a style from their type: strings are text, floats money, ints
+// integers and times dates.
+type Cell struct {
+	Value interface{}
+	Style int
+}
+
+// WriteRow appends a row. Supported values are string, float64, int,
+// int64, time.Time, *time.Time, Cell and nil for an empty cell.
+func (w *Writer) WriteRow(values ...interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.rows)
+	for i, v := range values {
+		style := -1
+		if c, ok := v.(Cell); ok {
+			v, style = c.Value, c.Style
+		}
+		writeCell(&b, cellRef(i, w.rows), v, style)
+	}
+	b.WriteString("</row>")
+	w.write(b.String())
+	return w.err
+}
+
+// Close ends the sheet and writes the archive's central directory.
+func (w *Writer) Close() error {
+	w.write("</sheetData></worksheet>")
+	if w.err == nil {
+		w.err = w.sheet.Flush()
+	}
+	if err := w.zw.Close(); w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *Writer) write(s string) {
+	if w.err == nil {
+		_, w.err = w.sheet.WriteString(s)
+	}
+}
+
+func writeCell(b *strings.Builder, ref string, v interface{}, style int) {
+	pick := func(def int) int {
+		if style >= 0 {
+			return style
+		}
+		return def
+	}
+	number := func(s string, def int) {
+		fmt.Fprintf(b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, pick(def), s)
+	}
+
+	switch v := v.(type) {
+	case nil:
+		if style > 0 {
+			fmt.Fprintf(b, `<c r="%s" s="%d"/>`, ref, style)
+		}
+	case string:
+		if v == "" && style <= 0 {
+			return
+		}
+		fmt.Fprintf(b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, pick(StyleDefault), escape(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		number(strconv.FormatFloat(v, 'f', -1, 64), StyleMoney)
+	case int:
+		number(strconv.Itoa(v), StyleInteger)
+	case int64:
+		number(strconv.FormatInt(v, 10), StyleInteger)
+	case time.Time:
+		number(strconv.FormatFloat(serial(v), 'f', -1, 64), StyleDate)
+	case *time.Time:
+		if v != nil {
+			writeCell(b, ref, *v, style)
+		}
+	default:
+		writeCell(b, ref, fmt.Sprint(v), style)
+	}
+}
+
+// serial converts a date to Excel's day count since 1899-12-30, ignoring
+// the time of day.
+func serial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return math.Round(day.Sub(epoch).Hours() / 24)
+}
+
+// cellRef builds a reference like "C7" from a zero-based column and a
+// one-based row.
+func cellRef(col, row int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name + strconv.Itoa(row)
+}
+
+// sheetTitle trims a name to what Excel accepts as a sheet title.
+func sheetTitle(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		// XML 1.0 has no way to carry most control characters.
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			continue
+		}
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+const contentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const workbookRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles uses the built-in number formats 4 (#,##0.00), 14 (short date)
+// and 3 (#,##0), which Excel renders with the reader's locale.
+const styles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="3" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`